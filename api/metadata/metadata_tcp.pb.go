@@ -22,14 +22,14 @@ import (
 // MetadataTCPServer is the server API for Metadata service.
 type MetadataTCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 	ListServices(context.Context, *ListServicesRequest) (*ListServicesReply, error)
 	GetServiceDesc(context.Context, *GetServiceDescRequest) (*GetServiceDescReply, error)
 }
 
 func RegisterMetadataTCPServer(s *tcp.Server, srv MetadataTCPServer) {
-	chanList := s.RegisterService(&Metadata_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&Metadata_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Metadata_ListServices_TCP_Handler(srv interface{}, ctx context.Context, data []byte, interceptor tcp.UnaryServerInterceptor) ([]byte, error) {
@@ -9,6 +9,7 @@ require (
 	github.com/spf13/cobra v1.4.0
 	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4
 	golang.org/x/text v0.4.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
@@ -36,13 +36,19 @@ func (p *Project) Add(ctx context.Context, dir string, layout string, branch str
 		os.RemoveAll(to)
 	}
 
-	fmt.Printf("🚀 Add service %s, layout repo is %s, please wait a moment.\n\n", p.Name, layout)
-
 	pkgPath = fmt.Sprintf("%s/%s", mod, pkgPath)
-	repo := base.NewRepo(layout, branch)
-	err := repo.CopyToV2(ctx, to, pkgPath, repoAddIgnores, []string{filepath.Join(p.Path, "api"), "api"})
-	if err != nil {
-		return err
+	if p.Template != "" {
+		fmt.Printf("🚀 Add service %s, template is %s, please wait a moment.\n\n", p.Name, p.Template)
+		if err := copyTemplate(p.Template, to, pkgPath, p.Name, templateAddIgnores); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("🚀 Add service %s, layout repo is %s, please wait a moment.\n\n", p.Name, layout)
+		repo := base.NewRepo(layout, branch)
+		err := repo.CopyToV2(ctx, to, pkgPath, repoAddIgnores, []string{filepath.Join(p.Path, "api"), "api"})
+		if err != nil {
+			return err
+		}
 	}
 
 	e := os.Rename(
@@ -59,6 +65,9 @@ func (p *Project) Add(ctx context.Context, dir string, layout string, branch str
 	fmt.Print("💻 Use the following command to add a project 👇:\n\n")
 
 	fmt.Println(color.WhiteString("$ cd %s", p.Name))
+	if p.Template != "" {
+		fmt.Println(color.WhiteString("$ go mod tidy"))
+	}
 	fmt.Println(color.WhiteString("$ go generate ./..."))
 	fmt.Println(color.WhiteString("$ go build -o ./bin/ ./... "))
 	fmt.Println(color.WhiteString("$ ./bin/%s -conf ./configs\n", p.Name))
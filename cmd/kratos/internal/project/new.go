@@ -16,6 +16,8 @@ import (
 type Project struct {
 	Name string
 	Path string
+	// Template is the name of a built-in template used instead of the layout repo.
+	Template string
 }
 
 // New new a project from remote repo.
@@ -37,10 +39,17 @@ func (p *Project) New(ctx context.Context, dir string, layout string, branch str
 		}
 		os.RemoveAll(to)
 	}
-	fmt.Printf("🚀 Creating service %s, layout repo is %s, please wait a moment.\n\n", p.Name, layout)
-	repo := base.NewRepo(layout, branch)
-	if err := repo.CopyTo(ctx, to, p.Name, []string{".git", ".github"}); err != nil {
-		return err
+	if p.Template != "" {
+		fmt.Printf("🚀 Creating service %s, template is %s, please wait a moment.\n\n", p.Name, p.Template)
+		if err := copyTemplate(p.Template, to, p.Name, p.Name, nil); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("🚀 Creating service %s, layout repo is %s, please wait a moment.\n\n", p.Name, layout)
+		repo := base.NewRepo(layout, branch)
+		if err := repo.CopyTo(ctx, to, p.Name, []string{".git", ".github"}); err != nil {
+			return err
+		}
 	}
 	e := os.Rename(
 		filepath.Join(to, "cmd", "server"),
@@ -55,6 +64,9 @@ func (p *Project) New(ctx context.Context, dir string, layout string, branch str
 	fmt.Print("💻 Use the following command to start the project 👇:\n\n")
 
 	fmt.Println(color.WhiteString("$ cd %s", p.Name))
+	if p.Template != "" {
+		fmt.Println(color.WhiteString("$ go mod tidy"))
+	}
 	fmt.Println(color.WhiteString("$ go generate ./..."))
 	fmt.Println(color.WhiteString("$ go build -o ./bin/ ./... "))
 	fmt.Println(color.WhiteString("$ ./bin/%s -conf ./configs\n", p.Name))
@@ -19,15 +19,16 @@ import (
 var CmdNew = &cobra.Command{
 	Use:   "new",
 	Short: "Create a service template",
-	Long:  "Create a service project using the repository template. Example: kratos new helloworld",
+	Long:  "Create a service project using the repository template. Example: kratos new helloworld, kratos new --template game mygame",
 	Run:   run,
 }
 
 var (
-	repoURL string
-	branch  string
-	timeout string
-	nomod   bool
+	repoURL  string
+	branch   string
+	timeout  string
+	nomod    bool
+	template string
 )
 
 func init() {
@@ -39,6 +40,7 @@ func init() {
 	CmdNew.Flags().StringVarP(&branch, "branch", "b", branch, "repo branch")
 	CmdNew.Flags().StringVarP(&timeout, "timeout", "t", timeout, "time out")
 	CmdNew.Flags().BoolVarP(&nomod, "nomod", "", nomod, "retain go mod")
+	CmdNew.Flags().StringVarP(&template, "template", "", template, "built-in template, one of: "+strings.Join(templateNames(), ", "))
 }
 
 func run(_ *cobra.Command, args []string) {
@@ -66,7 +68,7 @@ func run(_ *cobra.Command, args []string) {
 		name = args[0]
 	}
 	projectName, workingDir := processProjectParams(name, wd)
-	p := &Project{Name: projectName}
+	p := &Project{Name: projectName, Template: template}
 	done := make(chan error, 1)
 	go func() {
 		if !nomod {
@@ -5,7 +5,9 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/yola1107/kratos/cmd/kratos/v2/internal/base"
@@ -72,6 +74,69 @@ func TestCmdNewNoMod(t *testing.T) {
 	assertImportsInclude(t, filepath.Join(cwd, "project/app/user/cmd/user/wire.go"), `"project/app/user/internal/biz"`)
 }
 
+// TestCmdNewTemplate tests the `kratos new` command with a built-in template.
+func TestCmdNewTemplate(t *testing.T) {
+	// the kratos module of this repository, the template needs its unreleased transports
+	kratos, err := filepath.Abs("../../../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd := changeCurrentDir(t)
+	projectName := "mygame"
+
+	// flags are package state shared with the other tests
+	nomod = false
+	CmdNew.SetArgs([]string{"--template", "game", projectName})
+	t.Cleanup(func() { template = "" })
+	if err := CmdNew.Execute(); err != nil {
+		t.Fatalf("executing command: %v", err)
+	}
+
+	for _, file := range []string{
+		"go.mod",
+		"Makefile",
+		"cmd/mygame/main.go",
+		"cmd/mygame/wire_gen.go",
+		"internal/biz/table/table.go",
+		"tools/press/runner.go",
+	} {
+		if _, err := os.Stat(filepath.Join(cwd, projectName, file)); err != nil {
+			t.Errorf("expected file %s to exist", file)
+		}
+	}
+	err = filepath.WalkDir(filepath.Join(cwd, projectName), func(path string, _ os.DirEntry, err error) error {
+		if filepath.Ext(path) == ".tmpl" {
+			t.Errorf("unexpected template file %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walking project: %v", err)
+	}
+
+	assertGoMod(t, filepath.Join(cwd, projectName, "go.mod"), projectName)
+
+	assertImportsInclude(t, filepath.Join(cwd, projectName, "cmd", projectName, "wire.go"), fmt.Sprintf(`"%s/internal/biz"`, projectName))
+
+	if testing.Short() {
+		t.Skip("skipping the build of the generated module in short mode")
+	}
+	dir := filepath.Join(cwd, projectName)
+	goCmd(t, dir, "mod", "edit", "-replace", "github.com/yola1107/kratos/v2="+kratos)
+	goCmd(t, dir, "build", "-mod=mod", "./...")
+}
+
+// goCmd runs the go command with args in dir.
+func goCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
 // assertImportsInclude checks that the file at path contains the expected import.
 func assertImportsInclude(t *testing.T, path, expected string) {
 	t.Helper()
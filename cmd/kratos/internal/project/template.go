@@ -0,0 +1,86 @@
+package project
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// templates are the built-in project templates, kratos new --template <name>.
+// The go files are suffixed with .tmpl so they are not built with kratos.
+//
+//go:embed all:templates
+var templates embed.FS
+
+// templateName is the name of the project in the built-in templates.
+const templateName = "game-layout"
+
+// templateAddIgnores lists the files skipped when adding a built-in template
+// to an existing go module.
+var templateAddIgnores = []string{"go.mod", ".gitignore"}
+
+// templateNames returns the names of the built-in templates.
+func templateNames() []string {
+	fds, _ := templates.ReadDir("templates")
+	names := make([]string, 0, len(fds))
+	for _, fd := range fds {
+		if fd.IsDir() {
+			names = append(names, fd.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyTemplate copies the built-in template tmpl to the directory to, with
+// the module path of the template replaced by modPath and its project name
+// replaced by name.
+func copyTemplate(tmpl, to, modPath, name string, ignores []string) error {
+	root := path.Join("templates", tmpl)
+	if _, err := fs.Stat(templates, root); err != nil {
+		return fmt.Errorf("unknown template %q, available templates: %s", tmpl, strings.Join(templateNames(), ", "))
+	}
+	modBytes, err := templates.ReadFile(path.Join(root, "go.mod.tmpl"))
+	if err != nil {
+		return err
+	}
+	replaces := []string{modfile.ModulePath(modBytes), modPath, templateName, name}
+	return fs.WalkDir(templates, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		dst := filepath.Join(to, filepath.FromSlash(strings.TrimSuffix(rel, ".tmpl")))
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		if hasSets(path.Base(strings.TrimSuffix(rel, ".tmpl")), ignores) {
+			return nil
+		}
+		buf, err := templates.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(replaces); i += 2 {
+			buf = bytes.ReplaceAll(buf, []byte(replaces[i]), []byte(replaces[i+1]))
+		}
+		return os.WriteFile(dst, buf, 0o644)
+	})
+}
+
+func hasSets(name string, sets []string) bool {
+	for _, s := range sets {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
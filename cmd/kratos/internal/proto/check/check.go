@@ -0,0 +1,52 @@
+// Package check holds the helpers shared by the proto checks, lint and diff.
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// Files returns the proto files of args, walking the directories.
+func Files(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".proto" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Write writes results to w, one per line in the text format or as a json array.
+func Write[T fmt.Stringer](w io.Writer, format string, results []T) error {
+	switch format {
+	case "json":
+		if results == nil {
+			results = []T{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "text":
+		for _, r := range results {
+			if _, err := fmt.Fprintln(w, r.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected text or json", format)
+	}
+}
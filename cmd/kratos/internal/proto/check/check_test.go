@@ -0,0 +1,62 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type result struct {
+	Name string `json:"name"`
+}
+
+func (r result) String() string { return "result " + r.Name }
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.proto", "sub/b.proto", "sub/c.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := Files([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.proto"), filepath.Join(dir, "sub", "b.proto")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if _, err = Files([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("Files want error for a missing path")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	results := []result{{Name: "a"}, {Name: "b"}}
+	buf := new(bytes.Buffer)
+	if err := Write(buf, "text", results); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "result a\nresult b\n"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+	buf.Reset()
+	if err := Write[result](buf, "json", nil); err != nil {
+		t.Fatal(err)
+	}
+	var got []result
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("json = %s, want []", buf)
+	}
+	if err := Write(buf, "xml", results); err == nil {
+		t.Error("Write want error for an unknown format")
+	}
+}
@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of the breaking changes, the Kind of a Change.
+const (
+	KindMessageRemoved      = "message-removed"
+	KindFieldRemoved        = "field-removed"
+	KindFieldRenumbered     = "field-renumbered"
+	KindFieldType           = "field-type"
+	KindEnumRemoved         = "enum-removed"
+	KindEnumValueRemoved    = "enum-value-removed"
+	KindEnumValueRenumbered = "enum-value-renumbered"
+	KindServiceRemoved      = "service-removed"
+	KindRPCRemoved          = "rpc-removed"
+	KindRPCType             = "rpc-type"
+	KindCommandChanged      = "command-changed"
+)
+
+// Change is a wire-incompatible change between two schemas.
+type Change struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (c Change) String() string {
+	if c.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", c.File, c.Kind, c.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", c.File, c.Line, c.Kind, c.Message)
+}
+
+type comparer struct {
+	changes []Change
+}
+
+func (c *comparer) report(pos position, kind, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{File: pos.file, Line: pos.line, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// compare returns the changes breaking the clients built from old, sorted by
+// position. Only the packages of cur are compared, so the imports of a
+// descriptor set are not reported as removed.
+func compare(old, cur *schema) []Change {
+	c := &comparer{}
+	for _, name := range sortedKeys(old.messages) {
+		om := old.messages[name]
+		if !cur.packages[om.pkg] {
+			continue
+		}
+		cm, ok := cur.messages[name]
+		if !ok {
+			c.report(om.pos, KindMessageRemoved, "message %s was removed", name)
+			continue
+		}
+		c.compareMessage(name, om, cm)
+	}
+	for _, name := range sortedKeys(old.enums) {
+		oe := old.enums[name]
+		if !cur.packages[oe.pkg] {
+			continue
+		}
+		ce, ok := cur.enums[name]
+		if !ok {
+			c.report(oe.pos, KindEnumRemoved, "enum %s was removed", name)
+			continue
+		}
+		c.compareEnum(name, oe, ce)
+	}
+	for _, name := range sortedKeys(old.services) {
+		oldSvc := old.services[name]
+		if !cur.packages[oldSvc.pkg] {
+			continue
+		}
+		curSvc, ok := cur.services[name]
+		if !ok {
+			c.report(oldSvc.pos, KindServiceRemoved, "service %s was removed", name)
+			continue
+		}
+		c.compareService(name, oldSvc, curSvc)
+	}
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		a, b := c.changes[i], c.changes[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return c.changes
+}
+
+func (c *comparer) compareMessage(name string, om, cm *message) {
+	numbers := make([]int32, 0, len(om.fields))
+	for n := range om.fields {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	for _, n := range numbers {
+		of := om.fields[n]
+		cf, ok := cm.fields[n]
+		if !ok {
+			switch moved := cm.fieldByName(of.name); {
+			case moved != nil:
+				c.report(moved.pos, KindFieldRenumbered, "field %s.%s was renumbered from %d to %d", name, of.name, n, moved.number)
+			case cm.isReserved(n):
+				// removed the right way, the number can't be reused
+			default:
+				c.report(cm.pos, KindFieldRemoved, "field %s.%s = %d was removed, reserve its number instead", name, of.name, n)
+			}
+			continue
+		}
+		if of.typ != cf.typ || of.label != cf.label {
+			c.report(cf.pos, KindFieldType, "field %s.%s = %d changed type from %s to %s", name, of.name, n, fieldType(of), fieldType(cf))
+		}
+	}
+}
+
+func fieldType(f *field) string {
+	if f.label == "" {
+		return f.typ
+	}
+	return f.label + " " + f.typ
+}
+
+func (c *comparer) compareEnum(name string, oe, ce *enum) {
+	numbers := make(map[int32]bool, len(ce.values))
+	for _, n := range ce.values {
+		numbers[n] = true
+	}
+	for _, v := range sortedKeys(oe.values) {
+		on := oe.values[v]
+		cn, ok := ce.values[v]
+		switch {
+		case ok && cn != on:
+			c.report(ce.pos, KindEnumValueRenumbered, "enum value %s.%s was renumbered from %d to %d", name, v, on, cn)
+		case !ok && !numbers[on] && !isReserved(ce.reserved, on):
+			c.report(ce.pos, KindEnumValueRemoved, "enum value %s.%s = %d was removed, reserve its number instead", name, v, on)
+		}
+	}
+}
+
+func isReserved(ranges []reservedRange, n int32) bool {
+	for _, r := range ranges {
+		if n >= r.start && n <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *comparer) compareService(name string, oldSvc, curSvc *service) {
+	for _, m := range sortedKeys(oldSvc.rpcs) {
+		or := oldSvc.rpcs[m]
+		cr, ok := curSvc.rpcs[m]
+		if !ok {
+			c.report(curSvc.pos, KindRPCRemoved, "rpc %s.%s was removed", name, m)
+			continue
+		}
+		if or.signature() != cr.signature() {
+			c.report(cr.pos, KindRPCType, "rpc %s.%s changed from %s to %s", name, m, or.signature(), cr.signature())
+		}
+		switch {
+		case or.hasCmd && !cr.hasCmd:
+			c.report(cr.pos, KindCommandChanged, "rpc %s.%s lost its command id %d", name, m, or.cmd)
+		case or.hasCmd && or.cmd != cr.cmd:
+			c.report(cr.pos, KindCommandChanged, "command id of rpc %s.%s changed from %d to %d", name, m, or.cmd, cr.cmd)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/check"
+)
+
+// CmdDiff represents the diff command.
+var CmdDiff = &cobra.Command{
+	Use:   "diff",
+	Short: "Report the wire-incompatible changes of the proto files",
+	Long: "Compare the proto files with a descriptor set or a git revision and report the changes breaking the released clients, " +
+		"it exits 1 when there are any. Example: kratos proto diff api --git v1.2.0, " +
+		"kratos proto diff api --descriptor-set release.binpb (protoc --descriptor_set_out=release.binpb)",
+	Run: run,
+}
+
+var (
+	descriptorSet string
+	gitRevision   string
+	format        string
+)
+
+func init() {
+	CmdDiff.Flags().StringVarP(&descriptorSet, "descriptor-set", "d", "", "FileDescriptorSet of the released proto files")
+	CmdDiff.Flags().StringVarP(&gitRevision, "git", "g", "", "git revision of the released proto files")
+	CmdDiff.Flags().StringVarP(&format, "format", "f", "text", "output format: text or json")
+}
+
+func run(_ *cobra.Command, args []string) {
+	changes, err := diff(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err = check.Write(os.Stdout, format, changes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func diff(args []string) ([]Change, error) {
+	if len(args) == 0 {
+		return nil, errors.New("please specify the proto files or directories. Example: kratos proto diff api --git main")
+	}
+	if (descriptorSet == "") == (gitRevision == "") {
+		return nil, errors.New("please specify one of --descriptor-set and --git")
+	}
+	files, err := check.Files(args)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := loadSources(files, func(path string) (io.ReadCloser, error) { return os.Open(path) })
+	if err != nil {
+		return nil, err
+	}
+	var old *schema
+	if descriptorSet != "" {
+		old, err = readDescriptorSet(descriptorSet)
+	} else {
+		old, err = readGit(gitRevision, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return compare(old, cur), nil
+}
+
+func readDescriptorSet(path string) (*schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err = proto.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("%s is not a FileDescriptorSet: %v", path, err)
+	}
+	return loadDescriptorSet(set), nil
+}
+
+// readGit parses the proto files of args at the git revision rev, including
+// the files removed since.
+func readGit(rev string, args []string) (*schema, error) {
+	out, err := git(append([]string{"ls-tree", "-r", "--name-only", rev, "--"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, path := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if filepath.Ext(path) == ".proto" {
+			files = append(files, path)
+		}
+	}
+	return loadSources(files, func(path string) (io.ReadCloser, error) {
+		b, err := git("show", rev+":./"+path)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	})
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
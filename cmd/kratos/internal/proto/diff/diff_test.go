@@ -0,0 +1,227 @@
+package diff
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const oldProto = `syntax = "proto3";
+package game.v1;
+
+enum GameCommand {
+  Nothing = 0;
+  OnLoginReq = 1001;
+  OnChatReq = 1002;
+}
+
+service Game {
+  rpc OnLoginReq(LoginReq) returns (LoginRsp);
+  rpc OnChatReq(ChatReq) returns (ChatRsp);
+  rpc OnQuitReq(QuitReq) returns (QuitRsp) {
+    option (kratos.socket.cmd) = 1003;
+  }
+}
+
+message Player {
+  int64 id = 1;
+  string name = 2;
+  double money = 3;
+  int32 chair = 4;
+  string avatar = 5;
+  map<string, int32> items = 6;
+}
+message LoginReq { int64 id = 1; }
+message LoginRsp { Player player = 1; }
+message ChatReq { string msg = 1; }
+message ChatRsp {}
+message QuitReq {}
+message QuitRsp {}
+`
+
+const curProto = `syntax = "proto3";
+package game.v1;
+
+enum GameCommand {
+  Nothing = 0;
+  OnLoginReq = 1001;
+  OnChatReq = 1012;
+}
+
+service Game {
+  rpc OnLoginReq(LoginReq) returns (LoginRsp);
+  rpc OnChatReq(ChatReq) returns (ChatRsp);
+}
+
+message Player {
+  reserved 5;
+  int64 id = 1;
+  string nick = 2;
+  int64 money = 3;
+  int32 chair = 14;
+  map<string, int64> items = 6;
+}
+message LoginReq { int64 id = 1; }
+message LoginRsp { Player player = 1; }
+message ChatReq { string msg = 1; }
+message ChatRsp {}
+`
+
+func parse(t *testing.T, src string) *schema {
+	t.Helper()
+	s, err := loadSources([]string{"game.proto"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(src)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func kinds(changes []Change) map[string]int {
+	m := make(map[string]int)
+	for _, c := range changes {
+		m[c.Kind]++
+	}
+	return m
+}
+
+func TestCompare(t *testing.T) {
+	if changes := compare(parse(t, oldProto), parse(t, oldProto)); len(changes) != 0 {
+		t.Fatalf("compare(old, old) = %v, want none", changes)
+	}
+	got := kinds(compare(parse(t, oldProto), parse(t, curProto)))
+	want := map[string]int{
+		KindFieldType:           2, // money double to int64, items map value
+		KindFieldRenumbered:     1, // chair 4 to 14
+		KindEnumValueRenumbered: 1, // OnChatReq
+		KindCommandChanged:      1, // OnChatReq 1002 to 1012
+		KindRPCRemoved:          1, // OnQuitReq
+		KindMessageRemoved:      2, // QuitReq, QuitRsp
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("%s changes = %d, want %d", k, got[k], n)
+		}
+	}
+	for k, n := range got {
+		if _, ok := want[k]; !ok {
+			t.Errorf("unexpected %d %s changes", n, k)
+		}
+	}
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	opts := new(descriptorpb.MethodOptions)
+	b := protowire.AppendTag(nil, cmdOption, protowire.VarintType)
+	opts.ProtoReflect().SetUnknown(protowire.AppendVarint(b, 1003))
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("game/v1/game.proto"),
+		Package: proto.String("game.v1"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("GameCommand"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("Nothing"), Number: proto.Int32(0)},
+				{Name: proto.String("OnLoginReq"), Number: proto.Int32(1001)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Player"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()},
+				{
+					Name: proto.String("items"), Number: proto.Int32(6), TypeName: proto.String(".game.v1.Player.ItemsEntry"),
+					Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				},
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("ItemsEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("key"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("value"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Game"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("OnLoginReq"), InputType: proto.String(".game.v1.LoginReq"), OutputType: proto.String(".game.v1.LoginRsp")},
+				{Name: proto.String("OnQuitReq"), InputType: proto.String(".game.v1.QuitReq"), OutputType: proto.String(".game.v1.QuitRsp"), Options: opts},
+			},
+		}},
+	}}}
+	s := loadDescriptorSet(set)
+	items := s.messages["game.v1.Player"].fields[6]
+	if items == nil || items.typ != "map<string,int32>" || items.label != "" {
+		t.Errorf("items = %+v, want map<string,int32>", items)
+	}
+	if _, ok := s.messages["game.v1.Player.ItemsEntry"]; ok {
+		t.Error("map entry want skipped")
+	}
+	rpcs := s.services["game.v1.Game"].rpcs
+	if r := rpcs["OnLoginReq"]; !r.hasCmd || r.cmd != 1001 {
+		t.Errorf("OnLoginReq cmd = %d, want 1001 of GameCommand", r.cmd)
+	}
+	if r := rpcs["OnQuitReq"]; !r.hasCmd || r.cmd != 1003 {
+		t.Errorf("OnQuitReq cmd = %d, want 1003 of (kratos.socket.cmd)", r.cmd)
+	}
+	if r := rpcs["OnQuitReq"]; r.input != "game.v1.QuitReq" {
+		t.Errorf("OnQuitReq input = %s, want game.v1.QuitReq", r.input)
+	}
+}
+
+func TestDiffGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	oldCWD, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldCWD) })
+
+	path := filepath.Join("api", "game.proto")
+	if err = os.MkdirAll("api", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, []byte(oldProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=kratos", "-c", "user.email=kratos@example.com", "commit", "-q", "-m", "release"},
+	} {
+		if _, err = git(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.WriteFile(path, []byte(curProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gitRevision, descriptorSet = "HEAD", ""
+	t.Cleanup(func() { gitRevision = "" })
+	changes, err := diff([]string{"api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kinds(changes)[KindCommandChanged]; got != 1 {
+		t.Errorf("command changes = %d, want 1: %v", got, changes)
+	}
+	if _, err = diff(nil); err == nil {
+		t.Error("diff() want error without proto files")
+	}
+}
@@ -0,0 +1,488 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// gameCommandEnum is the enum whose values name the command ids of the
+	// methods without the (kratos.socket.cmd) option.
+	gameCommandEnum = "GameCommand"
+	// cmdOption is the field number of the (kratos.socket.cmd) method option.
+	cmdOption = 1110
+)
+
+// position is where a definition is declared, line is 0 for the
+// definitions read from a descriptor set.
+type position struct {
+	file string
+	line int
+}
+
+// schema is the wire-relevant part of a set of proto files.
+type schema struct {
+	packages map[string]bool
+	messages map[string]*message // by full name
+	enums    map[string]*enum
+	services map[string]*service
+}
+
+func newSchema() *schema {
+	return &schema{
+		packages: make(map[string]bool),
+		messages: make(map[string]*message),
+		enums:    make(map[string]*enum),
+		services: make(map[string]*service),
+	}
+}
+
+type message struct {
+	pkg      string
+	pos      position
+	fields   map[int32]*field
+	reserved []reservedRange
+}
+
+// reservedRange is a reserved field number range, end inclusive.
+type reservedRange struct {
+	start, end int32
+}
+
+func (m *message) isReserved(n int32) bool {
+	return isReserved(m.reserved, n)
+}
+
+func (m *message) fieldByName(name string) *field {
+	for _, f := range m.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+type field struct {
+	name   string
+	number int32
+	typ    string // int32, game.v1.Player, map<string,int32>
+	label  string // repeated or empty
+	pos    position
+}
+
+type enum struct {
+	pkg      string
+	pos      position
+	values   map[string]int32
+	reserved []reservedRange
+}
+
+type service struct {
+	pkg  string
+	pos  position
+	rpcs map[string]*rpc
+}
+
+type rpc struct {
+	input, output string
+	streams       string // client, server, bidi or empty
+	cmd           int32
+	hasCmd        bool
+	pos           position
+}
+
+// signature is the wire signature of the rpc, input, output and streaming.
+func (r *rpc) signature() string {
+	s := fmt.Sprintf("(%s) returns (%s)", r.input, r.output)
+	if r.streams != "" {
+		s += " " + r.streams + " streaming"
+	}
+	return s
+}
+
+func streams(client, server bool) string {
+	switch {
+	case client && server:
+		return "bidi"
+	case client:
+		return "client"
+	case server:
+		return "server"
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fullName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+var scalars = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// sourceFile is a parsed proto file waiting for its type names to be resolved.
+type sourceFile struct {
+	path       string
+	definition *proto.Proto
+	pkg        string
+}
+
+// loadSources parses the proto files read by open into a schema.
+func loadSources(paths []string, open func(path string) (io.ReadCloser, error)) (*schema, error) {
+	var files []*sourceFile
+	for _, path := range paths {
+		r, err := open(path)
+		if err != nil {
+			return nil, err
+		}
+		parser := proto.NewParser(r)
+		parser.Filename(path)
+		definition, err := parser.Parse()
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		f := &sourceFile{path: path, definition: definition}
+		proto.Walk(definition, proto.WithPackage(func(p *proto.Package) { f.pkg = p.Name }))
+		files = append(files, f)
+	}
+	s := newSchema()
+	// declare the types first, the fields may refer to the types of any file
+	for _, f := range files {
+		s.packages[f.pkg] = true
+		proto.Walk(f.definition,
+			proto.WithMessage(func(m *proto.Message) {
+				s.messages[fullName(f.pkg, scopeName(m))] = &message{
+					pkg:    f.pkg,
+					pos:    position{file: f.path, line: m.Position.Line},
+					fields: make(map[int32]*field),
+				}
+			}),
+			proto.WithEnum(func(e *proto.Enum) {
+				s.enums[fullName(f.pkg, scopeName(e))] = &enum{
+					pkg:    f.pkg,
+					pos:    position{file: f.path, line: e.Position.Line},
+					values: make(map[string]int32),
+				}
+			}),
+		)
+	}
+	for _, f := range files {
+		s.loadSource(f)
+	}
+	return s, nil
+}
+
+func (s *schema) loadSource(f *sourceFile) {
+	commands := make(map[string]int32)
+	proto.Walk(f.definition,
+		proto.WithMessage(func(m *proto.Message) {
+			name := fullName(f.pkg, scopeName(m))
+			msg := s.messages[name]
+			add := func(fd *proto.Field, typ, label string) {
+				msg.fields[int32(fd.Sequence)] = &field{
+					name:   fd.Name,
+					number: int32(fd.Sequence),
+					typ:    typ,
+					label:  label,
+					pos:    position{file: f.path, line: fd.Position.Line},
+				}
+			}
+			for _, e := range m.Elements {
+				switch v := e.(type) {
+				case *proto.NormalField:
+					label := ""
+					if v.Repeated {
+						label = "repeated"
+					}
+					add(v.Field, s.resolve(f.pkg, name, v.Type), label)
+				case *proto.MapField:
+					add(v.Field, fmt.Sprintf("map<%s,%s>", s.resolve(f.pkg, name, v.KeyType), s.resolve(f.pkg, name, v.Type)), "")
+				case *proto.Oneof:
+					for _, oe := range v.Elements {
+						if of, ok := oe.(*proto.OneOfField); ok {
+							add(of.Field, s.resolve(f.pkg, name, of.Type), "")
+						}
+					}
+				case *proto.Reserved:
+					msg.reserved = append(msg.reserved, sourceRanges(v.Ranges)...)
+				}
+			}
+		}),
+		proto.WithEnum(func(e *proto.Enum) {
+			en := s.enums[fullName(f.pkg, scopeName(e))]
+			for _, v := range e.Elements {
+				switch v := v.(type) {
+				case *proto.EnumField:
+					en.values[v.Name] = int32(v.Integer)
+					if _, ok := e.Parent.(*proto.Proto); ok && e.Name == gameCommandEnum {
+						commands[v.Name] = int32(v.Integer)
+					}
+				case *proto.Reserved:
+					en.reserved = append(en.reserved, sourceRanges(v.Ranges)...)
+				}
+			}
+		}),
+	)
+	proto.Walk(f.definition, proto.WithService(func(sv *proto.Service) {
+		svc := &service{pkg: f.pkg, pos: position{file: f.path, line: sv.Position.Line}, rpcs: make(map[string]*rpc)}
+		for _, e := range sv.Elements {
+			r, ok := e.(*proto.RPC)
+			if !ok {
+				continue
+			}
+			m := &rpc{
+				input:   s.resolve(f.pkg, f.pkg, r.RequestType),
+				output:  s.resolve(f.pkg, f.pkg, r.ReturnsType),
+				streams: streams(r.StreamsRequest, r.StreamsReturns),
+				pos:     position{file: f.path, line: r.Position.Line},
+			}
+			for _, re := range r.Elements {
+				if o, ok := re.(*proto.Option); ok && o.Name == "(kratos.socket.cmd)" {
+					var id int32
+					if _, err := fmt.Sscan(o.Constant.Source, &id); err == nil {
+						m.cmd, m.hasCmd = id, true
+					}
+				}
+			}
+			if !m.hasCmd {
+				m.cmd, m.hasCmd = commands[r.Name]
+			}
+			svc.rpcs[r.Name] = m
+		}
+		s.services[fullName(f.pkg, sv.Name)] = svc
+	}))
+}
+
+// scopeName returns the name of a message or enum relative to its package,
+// Outer.Inner.
+func scopeName(v proto.Visitee) string {
+	var names []string
+	for v != nil {
+		switch t := v.(type) {
+		case *proto.Message:
+			names = append([]string{t.Name}, names...)
+			v = t.Parent
+		case *proto.Enum:
+			names = append([]string{t.Name}, names...)
+			v = t.Parent
+		default:
+			v = nil
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+// resolve returns the full name of the type typ referred to in scope, the
+// full name of a message in package pkg or pkg itself, following the proto
+// scoping rules. A type declared by none of the files is assumed to be in pkg
+// unless qualified.
+func (s *schema) resolve(pkg, scope, typ string) string {
+	if scalars[typ] {
+		return typ
+	}
+	if strings.HasPrefix(typ, ".") {
+		return typ[1:]
+	}
+	for scope != "" {
+		if name := scope + "." + typ; s.declares(name) {
+			return name
+		}
+		i := strings.LastIndex(scope, ".")
+		if i < 0 {
+			break
+		}
+		scope = scope[:i]
+	}
+	if s.declares(typ) || strings.Contains(typ, ".") {
+		return typ
+	}
+	return fullName(pkg, typ)
+}
+
+func (s *schema) declares(name string) bool {
+	_, isMessage := s.messages[name]
+	_, isEnum := s.enums[name]
+	return isMessage || isEnum
+}
+
+func sourceRanges(ranges []proto.Range) []reservedRange {
+	res := make([]reservedRange, 0, len(ranges))
+	for _, r := range ranges {
+		end := r.To
+		if r.Max {
+			end = 536870911
+		} else if end == 0 {
+			end = r.From
+		}
+		res = append(res, reservedRange{start: int32(r.From), end: int32(end)})
+	}
+	return res
+}
+
+var descriptorScalars = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:   "double",
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:    "float",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  "fixed64",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  "fixed32",
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     "bool",
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   "string",
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:    "bytes",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: "sfixed32",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: "sfixed64",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   "sint32",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   "sint64",
+}
+
+// loadDescriptorSet reads a schema from a FileDescriptorSet, as written by
+// protoc --descriptor_set_out.
+func loadDescriptorSet(set *descriptorpb.FileDescriptorSet) *schema {
+	s := newSchema()
+	entries := make(map[string]*descriptorpb.DescriptorProto) // map entries by full name
+	for _, f := range set.GetFile() {
+		var walk func(prefix string, msgs []*descriptorpb.DescriptorProto)
+		walk = func(prefix string, msgs []*descriptorpb.DescriptorProto) {
+			for _, m := range msgs {
+				name := fullName(prefix, m.GetName())
+				if m.GetOptions().GetMapEntry() {
+					entries[name] = m
+				}
+				walk(name, m.GetNestedType())
+			}
+		}
+		walk(f.GetPackage(), f.GetMessageType())
+	}
+	for _, f := range set.GetFile() {
+		s.loadDescriptor(f, entries)
+	}
+	return s
+}
+
+func (s *schema) loadDescriptor(f *descriptorpb.FileDescriptorProto, entries map[string]*descriptorpb.DescriptorProto) {
+	pkg := f.GetPackage()
+	pos := position{file: f.GetName()}
+	s.packages[pkg] = true
+	typeName := func(fd *descriptorpb.FieldDescriptorProto) string {
+		if t, ok := descriptorScalars[fd.GetType()]; ok {
+			return t
+		}
+		return strings.TrimPrefix(fd.GetTypeName(), ".")
+	}
+	commands := make(map[string]int32)
+	addEnum := func(name string, e *descriptorpb.EnumDescriptorProto) {
+		en := &enum{pkg: pkg, pos: pos, values: make(map[string]int32)}
+		for _, v := range e.GetValue() {
+			en.values[v.GetName()] = v.GetNumber()
+		}
+		for _, r := range e.GetReservedRange() {
+			en.reserved = append(en.reserved, reservedRange{start: r.GetStart(), end: r.GetEnd()})
+		}
+		s.enums[name] = en
+	}
+	var walk func(prefix string, msgs []*descriptorpb.DescriptorProto)
+	walk = func(prefix string, msgs []*descriptorpb.DescriptorProto) {
+		for _, m := range msgs {
+			name := fullName(prefix, m.GetName())
+			if m.GetOptions().GetMapEntry() {
+				continue
+			}
+			msg := &message{pkg: pkg, pos: pos, fields: make(map[int32]*field)}
+			for _, fd := range m.GetField() {
+				fl := &field{name: fd.GetName(), number: fd.GetNumber(), typ: typeName(fd), pos: pos}
+				if fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+					fl.label = "repeated"
+					if entry, ok := entries[fl.typ]; ok {
+						kv := entry.GetField()
+						fl.typ, fl.label = fmt.Sprintf("map<%s,%s>", typeName(kv[0]), typeName(kv[1])), ""
+					}
+				}
+				msg.fields[fl.number] = fl
+			}
+			// descriptor ranges are end exclusive
+			for _, r := range m.GetReservedRange() {
+				msg.reserved = append(msg.reserved, reservedRange{start: r.GetStart(), end: r.GetEnd() - 1})
+			}
+			s.messages[name] = msg
+			for _, e := range m.GetEnumType() {
+				addEnum(fullName(name, e.GetName()), e)
+			}
+			walk(name, m.GetNestedType())
+		}
+	}
+	walk(pkg, f.GetMessageType())
+	for _, e := range f.GetEnumType() {
+		addEnum(fullName(pkg, e.GetName()), e)
+		if e.GetName() == gameCommandEnum {
+			for _, v := range e.GetValue() {
+				commands[v.GetName()] = v.GetNumber()
+			}
+		}
+	}
+	for _, sv := range f.GetService() {
+		svc := &service{pkg: pkg, pos: pos, rpcs: make(map[string]*rpc)}
+		for _, m := range sv.GetMethod() {
+			r := &rpc{
+				input:   strings.TrimPrefix(m.GetInputType(), "."),
+				output:  strings.TrimPrefix(m.GetOutputType(), "."),
+				streams: streams(m.GetClientStreaming(), m.GetServerStreaming()),
+				pos:     pos,
+			}
+			r.cmd, r.hasCmd = descriptorCommand(m.GetOptions())
+			if !r.hasCmd {
+				r.cmd, r.hasCmd = commands[m.GetName()]
+			}
+			svc.rpcs[m.GetName()] = r
+		}
+		s.services[fullName(pkg, sv.GetName())] = svc
+	}
+}
+
+// descriptorCommand returns the (kratos.socket.cmd) option, an unknown field
+// of opts as the socket options are not linked into kratos.
+func descriptorCommand(opts *descriptorpb.MethodOptions) (int32, bool) {
+	b := opts.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+		if num == cmdOption && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, false
+			}
+			return int32(v), true
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+	}
+	return 0, false
+}
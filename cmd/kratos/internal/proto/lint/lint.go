@@ -0,0 +1,90 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/check"
+)
+
+// CmdLint represents the lint command.
+var CmdLint = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the socket conventions of the proto files",
+	Long: "Check the command ids, message names and pushes the socket plugins depend on. " +
+		"Example: kratos proto lint api --range Game=1000-1999 --format json",
+	Run: run,
+}
+
+var (
+	format string
+	ranges []string
+	block  int32
+)
+
+func init() {
+	CmdLint.Flags().StringVarP(&format, "format", "f", "text", "output format: text or json")
+	CmdLint.Flags().StringArrayVarP(&ranges, "range", "r", nil, "command id range of a service, Service=lo-hi, repeatable")
+	CmdLint.Flags().Int32VarP(&block, "block", "", 1000, "size of the command id block of a service without --range")
+}
+
+func run(_ *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Please specify the proto files or directories. Example: kratos proto lint api")
+		os.Exit(2)
+	}
+	l, err := newLinter(ranges, block)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	files, err := check.Files(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, f := range files {
+		if err = l.lintFile(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	diags := l.finish()
+	if err = check.Write(os.Stdout, format, diags); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			os.Exit(1)
+		}
+	}
+}
+
+// parseRange parses a --range value, Service=lo-hi.
+func parseRange(s string) (string, idRange, error) {
+	name, bounds, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", idRange{}, fmt.Errorf("invalid range %q, expected Service=lo-hi", s)
+	}
+	lo, hi, ok := strings.Cut(bounds, "-")
+	if !ok {
+		return "", idRange{}, fmt.Errorf("invalid range %q, expected Service=lo-hi", s)
+	}
+	l, err := strconv.ParseInt(lo, 10, 32)
+	if err != nil {
+		return "", idRange{}, fmt.Errorf("invalid range %q: %v", s, err)
+	}
+	h, err := strconv.ParseInt(hi, 10, 32)
+	if err != nil {
+		return "", idRange{}, fmt.Errorf("invalid range %q: %v", s, err)
+	}
+	if l > h {
+		return "", idRange{}, fmt.Errorf("invalid range %q, lo is greater than hi", s)
+	}
+	return name, idRange{lo: int32(l), hi: int32(h)}, nil
+}
@@ -0,0 +1,132 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProto = `syntax = "proto3";
+
+package game.v1;
+
+import "google/protobuf/empty.proto";
+import "kratos/socket/socket.proto";
+
+enum GameCommand {
+  Nothing = 0;
+  OnLoginReq = 1001;
+  OnReadyReq = 1002;
+  OnChatPush = 2001;
+  OnQuitPush = 2002;
+}
+
+service Game {
+  rpc OnLoginReq(LoginReq) returns (LoginRsp);
+  rpc OnReadyReq(ReadyReq) returns (LoginRsp);
+  rpc OnSceneReq(SceneReq) returns (SceneRsp);
+  rpc OnPing(PingReq) returns (PingRsp) {
+    option (kratos.socket.cmd) = 1;
+  }
+  rpc OnAction(ActionReq) returns (ActionRsp) {
+    option (kratos.socket.cmd) = 1001;
+  }
+  rpc OnMove(MoveReq) returns (MoveRsp) {
+    option (kratos.socket.cmd) = 3001;
+  }
+}
+
+service GamePush {
+  option (kratos.socket.push) = true;
+  rpc Chat(ChatPush) returns (google.protobuf.Empty) {
+    option (kratos.socket.cmd) = 2001;
+  }
+  rpc Gift(GiftPush) returns (google.protobuf.Empty) {
+    option (kratos.socket.cmd) = 2003;
+  }
+}
+
+message LoginReq {}
+message LoginRsp {}
+message ReadyReq {}
+message SceneReq {}
+message SceneRsp {}
+message PingReq {}
+message PingRsp {}
+message ActionReq {}
+message ActionRsp {}
+message MoveReq {}
+message MoveRsp {}
+message ChatPush {}
+message QuitPush {}
+`
+
+func lintTestProto(t *testing.T, ranges ...string) []Diagnostic {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "game.proto")
+	if err := os.WriteFile(path, []byte(testProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := newLinter(ranges, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.lintFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return l.finish()
+}
+
+func TestLint(t *testing.T) {
+	type key struct {
+		line int
+		rule string
+	}
+	want := map[key]string{
+		{18, RuleMessagePair}:      SeverityError,   // OnReadyReq(ReadyReq) returns (LoginRsp)
+		{19, RuleCommandMissing}:   SeverityError,   // OnSceneReq
+		{20, RuleCommandReserved}:  SeverityError,   // cmd 1, ping
+		{23, RuleCommandDuplicate}: SeverityError,   // cmd 1001 of OnLoginReq
+		{26, RuleCommandRange}:     SeverityError,   // 3001 outside 1000-1999
+		{13, RulePushUndeclared}:   SeverityWarning, // OnQuitPush
+		{36, RulePushUndeclared}:   SeverityError,   // GiftPush is not declared
+		{53, RulePushUndeclared}:   SeverityWarning, // QuitPush is not sent
+	}
+	got := lintTestProto(t)
+	for _, d := range got {
+		k := key{d.Line, d.Rule}
+		severity, ok := want[k]
+		if !ok {
+			t.Errorf("unexpected diagnostic %s", d)
+			continue
+		}
+		if d.Severity != severity {
+			t.Errorf("diagnostic %s: severity want %s", d, severity)
+		}
+		delete(want, k)
+	}
+	for k := range want {
+		t.Errorf("missing diagnostic %s at line %d", k.rule, k.line)
+	}
+}
+
+func TestLintRange(t *testing.T) {
+	for _, d := range lintTestProto(t, "Game=1000-3999") {
+		if d.Rule == RuleCommandRange {
+			t.Errorf("unexpected diagnostic %s", d)
+		}
+	}
+	if _, err := newLinter([]string{"Game=2000-1000"}, 1000); err == nil {
+		t.Error("newLinter want error for an inverted range")
+	}
+	if _, err := newLinter([]string{"Game"}, 1000); err == nil {
+		t.Error("newLinter want error for a range without bounds")
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{File: "a.proto", Line: 1, Column: 2, Severity: SeverityError, Rule: RuleCommandMissing, Message: "m"}
+	if got, want := d.String(), "a.proto:1:2: error: command-missing: m"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,449 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+)
+
+// Severity of a diagnostic, errors make the command exit non-zero.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rules of the linter, the Rule of a diagnostic.
+const (
+	// RuleCommandMissing is a method without a command id.
+	RuleCommandMissing = "command-missing"
+	// RuleCommandDuplicate is a command id used by two methods.
+	RuleCommandDuplicate = "command-duplicate"
+	// RuleCommandRange is a command id outside the range of its service.
+	RuleCommandRange = "command-range"
+	// RuleCommandReserved is a command id reserved by the transports.
+	RuleCommandReserved = "command-reserved"
+	// RuleMessagePair is a request and response not named as a pair.
+	RuleMessagePair = "message-pair"
+	// RulePushUndeclared is a push not declared by a push service.
+	RulePushUndeclared = "push-undeclared"
+)
+
+const (
+	// gameCommandEnum is the enum whose values name the command ids of the
+	// methods without the (kratos.socket.cmd) option.
+	gameCommandEnum = "GameCommand"
+	emptyType       = "google.protobuf.Empty"
+)
+
+// reservedCommands are the command ids used by the transports themselves.
+var reservedCommands = map[int32]string{
+	-1: "AuthOps, the auth command of the tcp and websocket transports",
+	0:  "the unset command id",
+	1:  "the ping op of the socket transports",
+	2:  "the pong op of the socket transports",
+}
+
+var (
+	requestSuffixes = []string{"Request", "Req"}
+	replySuffixes   = []string{"Response", "Reply", "Resp", "Rsp"}
+)
+
+// Diagnostic is a violation of a socket convention.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Rule, d.Message)
+}
+
+type idRange struct {
+	lo, hi int32
+}
+
+func (r idRange) contains(id int32) bool { return id >= r.lo && id <= r.hi }
+
+func (r idRange) String() string { return fmt.Sprintf("%d-%d", r.lo, r.hi) }
+
+// isReserved reports whether id is reserved by the transports, the negative
+// ids and reservedCommands.
+func isReserved(id int32) bool {
+	_, ok := reservedCommands[id]
+	return ok || id < 0
+}
+
+// method is a unary rpc with its command id.
+type method struct {
+	service *service
+	name    string
+	input   string
+	output  string
+	id      int32
+	hasID   bool
+	pos     scanner.Position
+}
+
+func (m *method) fullName() string { return m.service.name + "." + m.name }
+
+type service struct {
+	pkg     string
+	name    string
+	push    bool
+	pos     scanner.Position
+	methods []*method
+}
+
+type enumValue struct {
+	name string
+	id   int32
+	pos  scanner.Position
+}
+
+// linter checks the proto files given to lintFile, the checks spanning
+// the files are run by finish.
+type linter struct {
+	ranges    map[string]idRange
+	block     int32
+	services  []*service
+	messages  map[string]scanner.Position // declared messages by full name
+	pushEnums []enumValue                 // GameCommand values named *Push
+	diags     []Diagnostic
+}
+
+func newLinter(ranges []string, block int32) (*linter, error) {
+	if block <= 0 {
+		return nil, fmt.Errorf("invalid block %d, expected a positive size", block)
+	}
+	l := &linter{
+		ranges:   make(map[string]idRange),
+		block:    block,
+		messages: make(map[string]scanner.Position),
+	}
+	for _, s := range ranges {
+		name, r, err := parseRange(s)
+		if err != nil {
+			return nil, err
+		}
+		l.ranges[name] = r
+	}
+	return l, nil
+}
+
+func (l *linter) report(pos scanner.Position, severity, rule, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintFile parses the proto file at path and collects its services.
+func (l *linter) lintFile(path string) error {
+	reader, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	parser := proto.NewParser(reader)
+	parser.Filename(path)
+	definition, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+	var (
+		pkg      string
+		commands = make(map[string]int32)
+	)
+	proto.Walk(definition,
+		proto.WithPackage(func(p *proto.Package) {
+			pkg = p.Name
+		}),
+	)
+	proto.Walk(definition,
+		proto.WithEnum(func(e *proto.Enum) {
+			if _, ok := e.Parent.(*proto.Proto); !ok || e.Name != gameCommandEnum {
+				return
+			}
+			for _, v := range e.Elements {
+				f, ok := v.(*proto.EnumField)
+				if !ok {
+					continue
+				}
+				commands[f.Name] = int32(f.Integer)
+				if strings.HasSuffix(f.Name, "Push") {
+					l.pushEnums = append(l.pushEnums, enumValue{name: f.Name, id: int32(f.Integer), pos: f.Position})
+				}
+			}
+		}),
+		proto.WithMessage(func(m *proto.Message) {
+			l.messages[fullName(pkg, messageName(m))] = m.Position
+		}),
+	)
+	proto.Walk(definition, proto.WithService(func(s *proto.Service) {
+		l.services = append(l.services, l.service(pkg, s, commands))
+	}))
+	return nil
+}
+
+func fullName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// messageName returns the name of m relative to its package, Outer.Inner.
+func messageName(m *proto.Message) string {
+	name := m.Name
+	for p := m.Parent; p != nil; {
+		outer, ok := p.(*proto.Message)
+		if !ok {
+			break
+		}
+		name = outer.Name + "." + name
+		p = outer.Parent
+	}
+	return name
+}
+
+func (l *linter) service(pkg string, s *proto.Service, commands map[string]int32) *service {
+	svc := &service{pkg: pkg, name: s.Name, pos: s.Position}
+	for _, e := range s.Elements {
+		if o, ok := e.(*proto.Option); ok && o.Name == "(kratos.socket.push)" && o.Constant.Source == "true" {
+			svc.push = true
+		}
+	}
+	for _, e := range s.Elements {
+		r, ok := e.(*proto.RPC)
+		if !ok || r.StreamsRequest || r.StreamsReturns {
+			continue
+		}
+		m := &method{service: svc, name: r.Name, input: r.RequestType, output: r.ReturnsType, pos: r.Position}
+		for _, re := range r.Elements {
+			o, ok := re.(*proto.Option)
+			if !ok || o.Name != "(kratos.socket.cmd)" {
+				continue
+			}
+			id, err := strconv.ParseInt(o.Constant.Source, 0, 32)
+			if err != nil {
+				l.report(o.Position, SeverityError, RuleCommandMissing, "method %s has an invalid command id %q", m.fullName(), o.Constant.Source)
+				continue
+			}
+			m.id, m.hasID = int32(id), true
+		}
+		if !m.hasID {
+			m.id, m.hasID = commands[r.Name]
+		}
+		svc.methods = append(svc.methods, m)
+	}
+	return svc
+}
+
+// finish runs the checks and returns the diagnostics sorted by position.
+func (l *linter) finish() []Diagnostic {
+	l.checkCommands()
+	l.checkRanges()
+	l.checkPairs()
+	l.checkPushes()
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		a, b := l.diags[i], l.diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diags
+}
+
+// checkCommands reports the methods without a command id, the ids used twice
+// and the reserved ids.
+func (l *linter) checkCommands() {
+	owners := make(map[int32]*method)
+	for _, s := range l.services {
+		for _, m := range s.methods {
+			if !m.hasID {
+				l.report(m.pos, SeverityError, RuleCommandMissing,
+					"method %s has no command id, set option (kratos.socket.cmd) or add %s to enum %s", m.fullName(), m.name, gameCommandEnum)
+				continue
+			}
+			if isReserved(m.id) {
+				reason, ok := reservedCommands[m.id]
+				if !ok {
+					reason = "the negative ids are reserved by the transports"
+				}
+				l.report(m.pos, SeverityError, RuleCommandReserved, "command id %d of method %s is reserved: %s", m.id, m.fullName(), reason)
+				continue
+			}
+			if other, ok := owners[m.id]; ok {
+				l.report(m.pos, SeverityError, RuleCommandDuplicate,
+					"command id %d of method %s is already used by %s", m.id, m.fullName(), other.fullName())
+				continue
+			}
+			owners[m.id] = m
+		}
+	}
+}
+
+// checkRanges reports the command ids outside the --range of their service,
+// or outside the block holding most ids of a service without one. Two
+// services sharing a block are reported as well.
+func (l *linter) checkRanges() {
+	blocks := make(map[int32]*service)
+	for _, s := range l.services {
+		r, ok := l.ranges[s.name]
+		if !ok {
+			b, ok := l.majorityBlock(s)
+			if !ok {
+				continue
+			}
+			r = idRange{lo: b * l.block, hi: b*l.block + l.block - 1}
+			if other, ok := blocks[b]; ok {
+				l.report(s.pos, SeverityError, RuleCommandRange,
+					"service %s shares the command ids %s with service %s, use a block of its own", s.name, r, other.name)
+			} else {
+				blocks[b] = s
+			}
+		}
+		for _, m := range s.methods {
+			if m.hasID && !isReserved(m.id) && !r.contains(m.id) {
+				l.report(m.pos, SeverityError, RuleCommandRange,
+					"command id %d of method %s is outside the range %s of service %s", m.id, m.fullName(), r, s.name)
+			}
+		}
+	}
+}
+
+// majorityBlock returns the block of size l.block holding most unreserved
+// command ids of s, the lowest one on a tie.
+func (l *linter) majorityBlock(s *service) (int32, bool) {
+	counts := make(map[int32]int)
+	for _, m := range s.methods {
+		if m.hasID && !isReserved(m.id) {
+			counts[m.id/l.block]++
+		}
+	}
+	var (
+		best  int32
+		found bool
+	)
+	for b, n := range counts {
+		if !found || n > counts[best] || (n == counts[best] && b < best) {
+			best, found = b, true
+		}
+	}
+	return best, found
+}
+
+// checkPairs reports the requests and responses not named as a pair,
+// FooReq and FooRsp, FooRequest and FooReply.
+func (l *linter) checkPairs() {
+	for _, s := range l.services {
+		if s.push {
+			continue
+		}
+		for _, m := range s.methods {
+			if m.input == emptyType || m.output == emptyType {
+				continue
+			}
+			in, inOK := trimSuffix(shortName(m.input), requestSuffixes)
+			out, outOK := trimSuffix(shortName(m.output), replySuffixes)
+			switch {
+			case !inOK:
+				l.report(m.pos, SeverityError, RuleMessagePair,
+					"request %s of method %s should end in one of %s", m.input, m.fullName(), strings.Join(requestSuffixes, ", "))
+			case !outOK:
+				l.report(m.pos, SeverityError, RuleMessagePair,
+					"response %s of method %s should end in one of %s", m.output, m.fullName(), strings.Join(replySuffixes, ", "))
+			case in != out:
+				l.report(m.pos, SeverityError, RuleMessagePair,
+					"request %s and response %s of method %s are not a pair", m.input, m.output, m.fullName())
+			}
+		}
+	}
+}
+
+// checkPushes reports the push methods sending undeclared messages, and the
+// GameCommand values and messages named *Push no push service declares.
+func (l *linter) checkPushes() {
+	sent := make(map[string]bool)
+	ids := make(map[int32]bool)
+	for _, s := range l.services {
+		if !s.push {
+			continue
+		}
+		for _, m := range s.methods {
+			if m.hasID {
+				ids[m.id] = true
+			}
+			name, ok := l.resolve(s.pkg, m.input)
+			if !ok {
+				l.report(m.pos, SeverityError, RulePushUndeclared, "push %s sends the undeclared message %s", m.fullName(), m.input)
+				continue
+			}
+			sent[name] = true
+		}
+	}
+	for _, v := range l.pushEnums {
+		if !ids[v.id] {
+			l.report(v.pos, SeverityWarning, RulePushUndeclared,
+				"%s.%s = %d is not the command of a push, declare it in a service with option (kratos.socket.push) = true", gameCommandEnum, v.name, v.id)
+		}
+	}
+	for name, pos := range l.messages {
+		if strings.HasSuffix(shortName(name), "Push") && !sent[name] {
+			l.report(pos, SeverityWarning, RulePushUndeclared,
+				"message %s is not sent by a push, declare it in a service with option (kratos.socket.push) = true", name)
+		}
+	}
+}
+
+// resolve returns the full name of the message typ used in package pkg,
+// ok is false when none of the linted files declares it.
+func (l *linter) resolve(pkg, typ string) (string, bool) {
+	if strings.HasPrefix(typ, ".") {
+		name := strings.TrimPrefix(typ, ".")
+		return name, l.hasMessage(name)
+	}
+	if name := fullName(pkg, typ); l.hasMessage(name) {
+		return name, true
+	}
+	return typ, l.hasMessage(typ)
+}
+
+func (l *linter) hasMessage(name string) bool {
+	_, ok := l.messages[name]
+	return ok
+}
+
+func shortName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func trimSuffix(name string, suffixes []string) (string, bool) {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) && len(name) > len(s) {
+			return strings.TrimSuffix(name, s), true
+		}
+	}
+	return name, false
+}
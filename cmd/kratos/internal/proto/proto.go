@@ -5,6 +5,8 @@ import (
 
 	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/add"
 	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/client"
+	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/diff"
+	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/lint"
 	"github.com/yola1107/kratos/cmd/kratos/v2/internal/proto/server"
 )
 
@@ -19,4 +21,6 @@ func init() {
 	CmdProto.AddCommand(add.CmdAdd)
 	CmdProto.AddCommand(client.CmdClient)
 	CmdProto.AddCommand(server.CmdServer)
+	CmdProto.AddCommand(lint.CmdLint)
+	CmdProto.AddCommand(diff.CmdDiff)
 }
@@ -17,13 +17,19 @@ import (
 var CmdServer = &cobra.Command{
 	Use:   "server",
 	Short: "Generate the proto server implementations",
-	Long:  "Generate the proto server implementations. Example: kratos proto server api/xxx.proto --target-dir=internal/service",
+	Long:  "Generate the proto server implementations. Example: kratos proto server api/xxx.proto --target-dir=internal/service --server-dir=internal/server",
 	Run:   run,
 }
-var targetDir string
+var (
+	targetDir string
+	serverDir string
+	socket    string
+)
 
 func init() {
 	CmdServer.Flags().StringVarP(&targetDir, "target-dir", "t", "internal/service", "generate target directory")
+	CmdServer.Flags().StringVarP(&serverDir, "server-dir", "", "internal/server", "generate socket server registrations directory, empty to skip")
+	CmdServer.Flags().StringVarP(&socket, "socket", "", "", "socket server interface: socket, websocket, tcp or gnet, detected from the proto when empty")
 }
 
 func run(_ *cobra.Command, args []string) {
@@ -46,7 +52,13 @@ func run(_ *cobra.Command, args []string) {
 	var (
 		pkg string
 		res []*Service
+		sp  = newSocketProto()
 	)
+	proto.Walk(definition, sp.walk()...)
+	kind, isSocket, err := sp.kind(socket)
+	if err != nil {
+		log.Fatal(err)
+	}
 	proto.Walk(definition,
 		proto.WithOption(func(o *proto.Option) {
 			if o.Name == "go_package" {
@@ -54,19 +66,33 @@ func run(_ *cobra.Command, args []string) {
 			}
 		}),
 		proto.WithService(func(s *proto.Service) {
+			if isSocket && sp.pushes[s.Name] {
+				return
+			}
 			cs := &Service{
 				Package: pkg,
 				Service: serviceName(s.Name),
 			}
+			if isSocket {
+				cs.Socket, cs.Interface, cs.Session, cs.SessionImport = true, kind.iface, kind.session, kind.imports
+			}
 			for _, e := range s.Elements {
 				r, ok := e.(*proto.RPC)
 				if !ok {
 					continue
 				}
-				cs.Methods = append(cs.Methods, &Method{
+				typ := getMethodType(r.StreamsRequest, r.StreamsReturns)
+				if isSocket && typ != unaryType {
+					continue
+				}
+				m := &Method{
 					Service: serviceName(s.Name), Name: serviceName(r.Name), Request: parametersName(r.RequestType),
-					Reply: parametersName(r.ReturnsType), Type: getMethodType(r.StreamsRequest, r.StreamsReturns),
-				})
+					Reply: parametersName(r.ReturnsType), Type: typ,
+				}
+				if isSocket {
+					m.Command = sp.command(s.Name, r.Name)
+				}
+				cs.Methods = append(cs.Methods, m)
 			}
 			res = append(res, cs)
 		}),
@@ -90,6 +116,11 @@ func run(_ *cobra.Command, args []string) {
 		}
 		fmt.Println(to)
 	}
+	if isSocket && serverDir != "" {
+		if err := writeSocketServers(sp, kind, pkg, res); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 func getMethodType(streamsRequest, streamsReturns bool) MethodType {
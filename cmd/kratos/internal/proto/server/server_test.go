@@ -1,6 +1,11 @@
 package server
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+)
 
 func Test_serviceName(t *testing.T) {
 	type args struct {
@@ -100,3 +105,88 @@ func Test_parametersName(t *testing.T) {
 		})
 	}
 }
+
+func Test_socketProto(t *testing.T) {
+	const src = `
+syntax = "proto3";
+package game.v1;
+import "kratos/socket/socket.proto";
+option go_package = "game/api/game/v1;v1";
+enum GameCommand {
+  Nothing = 0;
+  OnLoginReq = 1001;
+}
+service Game {
+  rpc OnLoginReq(LoginReq) returns (LoginRsp);
+  rpc OnChatReq(ChatReq) returns (ChatRsp) {
+    option (kratos.socket.cmd) = 1005;
+    option (kratos.socket.transport) = GNET;
+  }
+}
+service GamePush {
+  option (kratos.socket.push) = true;
+  rpc Chat(ChatPush) returns (google.protobuf.Empty) {
+    option (kratos.socket.cmd) = 2004;
+  }
+}
+`
+	definition, err := proto.NewParser(strings.NewReader(src)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := newSocketProto()
+	proto.Walk(definition, sp.walk()...)
+
+	k, ok, err := sp.kind("")
+	if err != nil || !ok || k.name != "socket" {
+		t.Fatalf("kind() = %v, %v, %v, want socket", k.name, ok, err)
+	}
+	if _, _, err = sp.kind("udp"); err == nil {
+		t.Error("kind(udp) want error")
+	}
+	if !sp.pushes["GamePush"] {
+		t.Error("GamePush want push service")
+	}
+	for m, want := range map[string]string{
+		"OnLoginReq": "pb.GameCommand_OnLoginReq",
+		"OnChatReq":  "1005",
+		"OnReadyReq": "",
+	} {
+		if got := sp.command("Game", m); got != want {
+			t.Errorf("command(%s) = %q, want %q", m, got, want)
+		}
+	}
+	if ts := sp.serverTransports(k); len(ts) != 1 || ts[0].Package != "gnet" {
+		t.Errorf("serverTransports() = %v, want gnet", ts)
+	}
+	legacy, _, _ := sp.kind("websocket")
+	if ts := sp.serverTransports(legacy); len(ts) != 1 || ts[0].Package != "websocket" {
+		t.Errorf("serverTransports() = %v, want websocket", ts)
+	}
+}
+
+func TestService_executeSocket(t *testing.T) {
+	s := &Service{
+		Package: "game/api/game/v1", Service: "Game",
+		Socket: true, Interface: "SocketServer", Session: "sess session.Session",
+		SessionImport: "github.com/yola1107/kratos/v2/transport/session",
+		Methods: []*Method{
+			{Service: "Game", Name: "OnLoginReq", Request: "LoginReq", Reply: "LoginRsp", Type: unaryType, Command: "pb.GameCommand_OnLoginReq"},
+		},
+	}
+	b, err := s.execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"var _ pb.GameSocketServer = (*GameService)(nil)",
+		"func (s *GameService) GetLoop() work.Loop {",
+		"func (s *GameService) OnSessionOpen(sess session.Session) {",
+		"func (s *GameService) OnSessionClose(sess session.Session) {",
+		"// OnLoginReq handles command pb.GameCommand_OnLoginReq.",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("generated service missing %q:\n%s", want, b)
+		}
+	}
+}
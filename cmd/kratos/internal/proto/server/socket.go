@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/emicklei/proto"
+
+	"github.com/yola1107/kratos/cmd/kratos/v2/internal/base"
+)
+
+const (
+	// socketImport is the proto declaring the (kratos.socket.*) options.
+	socketImport = "kratos/socket/socket.proto"
+	// gameCommandEnum is the enum whose values name the command ids of the
+	// methods without the (kratos.socket.cmd) option.
+	gameCommandEnum = "GameCommand"
+)
+
+// socketKind is the server interface the stubs are generated for.
+type socketKind struct {
+	name      string // socket, websocket, tcp or gnet
+	iface     string // suffix of the server interface, SocketServer
+	session   string // parameter of the session hooks
+	imports   string // package of the session type
+	transport string // transport of the legacy plugin, empty for socket
+}
+
+var socketKinds = []socketKind{
+	{name: "socket", iface: "SocketServer", session: "sess session.Session", imports: "github.com/yola1107/kratos/v2/transport/session"},
+	{name: "websocket", iface: "WebsocketServer", session: "sess *websocket.Session", imports: "github.com/yola1107/kratos/v2/transport/websocket", transport: "WEBSOCKET"},
+	{name: "tcp", iface: "TCPServer", session: "key string", transport: "TCP"},
+	{name: "gnet", iface: "GNETServer", session: "sess *gnet.Session", imports: "github.com/yola1107/kratos/v2/transport/gnet", transport: "GNET"},
+}
+
+// socketTransport is a transport a server registration is generated for.
+type socketTransport struct {
+	Name     string // WEBSOCKET, the (kratos.socket.transport) value
+	Package  string // websocket
+	Register string // Websocket, the suffix of the generated Register func
+	Conf     string // Websocket, the field of conf.Server
+}
+
+var socketTransports = []socketTransport{
+	{Name: "WEBSOCKET", Package: "websocket", Register: "Websocket", Conf: "Websocket"},
+	{Name: "TCP", Package: "tcp", Register: "TCP", Conf: "Tcp"},
+	{Name: "GNET", Package: "gnet", Register: "GNET", Conf: "Gnet"},
+}
+
+// socketProto is what the socket stubs need to know about a proto file.
+type socketProto struct {
+	imported   bool              // imports kratos/socket/socket.proto
+	commands   map[string]bool   // value names of the GameCommand enum
+	transports map[string]bool   // transports listed by (kratos.socket.transport)
+	pushes     map[string]bool   // services with (kratos.socket.push) = true
+	cmds       map[string]string // command id of rpc Service.Method from (kratos.socket.cmd)
+}
+
+func newSocketProto() *socketProto {
+	return &socketProto{
+		commands:   make(map[string]bool),
+		transports: make(map[string]bool),
+		pushes:     make(map[string]bool),
+		cmds:       make(map[string]string),
+	}
+}
+
+// walk returns the proto.Walk handlers collecting the socket declarations.
+func (sp *socketProto) walk() []proto.Handler {
+	return []proto.Handler{
+		proto.WithImport(func(i *proto.Import) {
+			if i.Filename == socketImport {
+				sp.imported = true
+			}
+		}),
+		proto.WithEnum(func(e *proto.Enum) {
+			if _, ok := e.Parent.(*proto.Proto); !ok || e.Name != gameCommandEnum {
+				return
+			}
+			for _, v := range e.Elements {
+				if f, ok := v.(*proto.EnumField); ok {
+					sp.commands[f.Name] = true
+				}
+			}
+		}),
+		proto.WithOption(func(o *proto.Option) {
+			switch o.Name {
+			case "(kratos.socket.push)":
+				if s, ok := o.Parent.(*proto.Service); ok && o.Constant.Source == "true" {
+					sp.pushes[s.Name] = true
+				}
+			case "(kratos.socket.cmd)":
+				if r, ok := o.Parent.(*proto.RPC); ok {
+					if s, ok := r.Parent.(*proto.Service); ok {
+						sp.cmds[s.Name+"."+r.Name] = o.Constant.Source
+					}
+				}
+			case "(kratos.socket.transport)":
+				sp.transports[o.Constant.Source] = true
+				for _, l := range o.Constant.Array {
+					sp.transports[l.Source] = true
+				}
+			}
+		}),
+	}
+}
+
+// kind returns the socket kind named by flag, or the one the proto is
+// written for: socket when it imports kratos/socket/socket.proto, websocket
+// when it only declares the GameCommand enum. ok is false for gRPC protos.
+func (sp *socketProto) kind(flag string) (socketKind, bool, error) {
+	if flag == "" {
+		switch {
+		case sp.imported:
+			flag = "socket"
+		case len(sp.commands) > 0:
+			flag = "websocket"
+		default:
+			return socketKind{}, false, nil
+		}
+	}
+	names := make([]string, 0, len(socketKinds))
+	for _, k := range socketKinds {
+		if k.name == flag {
+			return k, true, nil
+		}
+		names = append(names, k.name)
+	}
+	return socketKind{}, false, fmt.Errorf("unknown socket %q, expected one of: %s", flag, strings.Join(names, ", "))
+}
+
+// command returns how the stub of rpc s.m refers to its command id, the
+// GameCommand enum value or the (kratos.socket.cmd) id.
+func (sp *socketProto) command(s, m string) string {
+	if id, ok := sp.cmds[s+"."+m]; ok {
+		return id
+	}
+	if sp.commands[m] {
+		return "pb." + gameCommandEnum + "_" + m
+	}
+	return ""
+}
+
+// serverTransports returns the transports of k to register the services on,
+// those listed by (kratos.socket.transport) or websocket when none is.
+func (sp *socketProto) serverTransports(k socketKind) []socketTransport {
+	var res []socketTransport
+	for _, t := range socketTransports {
+		if k.transport == t.Name || (k.transport == "" && sp.transports[t.Name]) {
+			res = append(res, t)
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, socketTransports[0])
+	}
+	return res
+}
+
+//nolint:lll
+var socketServerTemplate = `
+{{- /* delete empty line */ -}}
+package server
+
+import (
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/middleware/recovery"
+	"github.com/yola1107/kratos/v2/transport/{{ .Transport.Package }}"
+
+	pb "{{ .Package }}"
+	"{{ .Conf }}"
+	"{{ .Service }}"
+)
+
+// New{{ .Transport.Register }}Server new a {{ .Transport.Register }} server.
+func New{{ .Transport.Register }}Server(c *conf.Server{{ range .Services }}, {{ .Param }} *service.{{ .Service }}Service{{ end }}, logger log.Logger) *{{ .Transport.Package }}.Server {
+	var opts = []{{ .Transport.Package }}.ServerOption{
+		{{ .Transport.Package }}.Middleware(
+			recovery.Recovery(),
+		),
+	}
+	if c.{{ .Transport.Conf }}.Network != "" {
+		opts = append(opts, {{ .Transport.Package }}.Network(c.{{ .Transport.Conf }}.Network))
+	}
+	if c.{{ .Transport.Conf }}.Addr != "" {
+		opts = append(opts, {{ .Transport.Package }}.Address(c.{{ .Transport.Conf }}.Addr))
+	}
+	if c.{{ .Transport.Conf }}.Timeout != nil {
+		opts = append(opts, {{ .Transport.Package }}.Timeout(c.{{ .Transport.Conf }}.Timeout.AsDuration()))
+	}
+	srv := {{ .Transport.Package }}.NewServer(opts...)
+	{{- range .Services }}
+	pb.Register{{ .Service }}{{ $.Infix }}{{ $.Transport.Register }}Server(srv, {{ .Param }})
+	{{- end }}
+	return srv
+}
+`
+
+// socketServer is an internal/server file registering services on a transport.
+type socketServer struct {
+	Transport socketTransport
+	Infix     string // Socket for the protoc-gen-go-socket registrations
+	Package   string // go package of the proto
+	Conf      string // go package of conf
+	Service   string // go package of the services
+	Services  []*Service
+}
+
+func (s *socketServer) execute() ([]byte, error) {
+	tmpl, err := template.New("server").Parse(socketServerTemplate)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, s); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// writeSocketServers writes the transport registrations of services to
+// serverDir, next to the conf package, skipping the files already there.
+func writeSocketServers(sp *socketProto, k socketKind, pkg string, services []*Service) error {
+	if len(services) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(serverDir); os.IsNotExist(err) {
+		fmt.Printf("Server directory: %s does not exist\n", serverDir)
+		return nil
+	}
+	root, mod, err := modulePath(serverDir)
+	if err != nil {
+		return err
+	}
+	importPath := func(dir string) (string, error) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return "", err
+		}
+		return mod + "/" + filepath.ToSlash(rel), nil
+	}
+	confPkg, err := importPath(filepath.Join(filepath.Dir(serverDir), "conf"))
+	if err != nil {
+		return err
+	}
+	svcPkg, err := importPath(targetDir)
+	if err != nil {
+		return err
+	}
+	infix := ""
+	if k.name == "socket" {
+		infix = "Socket"
+	}
+	for _, t := range sp.serverTransports(k) {
+		to := filepath.Join(serverDir, t.Package+".go")
+		if _, err := os.Stat(to); !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "%s server already exists: %s\n", t.Register, to)
+			continue
+		}
+		s := &socketServer{Transport: t, Infix: infix, Package: pkg, Conf: confPkg, Service: svcPkg, Services: services}
+		b, err := s.execute()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(to, b, 0o644); err != nil {
+			return err
+		}
+		fmt.Println(to)
+	}
+	return nil
+}
+
+// modulePath returns the root and the path of the go module containing dir.
+func modulePath(dir string) (root, mod string, err error) {
+	if root, err = filepath.Abs(dir); err != nil {
+		return "", "", err
+	}
+	for {
+		if _, err = os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			mod, err = base.ModulePath(filepath.Join(root, "go.mod"))
+			return root, mod, err
+		}
+		if root == filepath.Dir(root) {
+			return "", "", fmt.Errorf("go.mod not found for %s", dir)
+		}
+		root = filepath.Dir(root)
+	}
+}
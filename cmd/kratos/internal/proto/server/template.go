@@ -2,7 +2,9 @@ package server
 
 import (
 	"bytes"
+	"go/format"
 	"html/template"
+	"strings"
 )
 
 //nolint:lll
@@ -17,13 +19,47 @@ import (
 	{{- if .UseIO }}
 	"io"
 	{{- end }}
+	{{- if .Socket }}
+
+	"github.com/yola1107/kratos/v2/library/work"
+	{{- if .SessionImport }}
+	"{{ .SessionImport }}"
+	{{- end }}
+	{{- end }}
 
 	pb "{{ .Package }}"
 	{{- if .GoogleEmpty }}
 	"google.golang.org/protobuf/types/known/emptypb"
 	{{- end }}
 )
+{{ if .Socket }}
+var _ pb.{{ .Service }}{{ .Interface }} = (*{{ .Service }}Service)(nil)
+
+type {{ .Service }}Service struct {
+	loop work.Loop
+}
+
+func New{{ .Service }}Service() (*{{ .Service }}Service, func(), error) {
+	loop := work.NewLoop()
+	if err := loop.Start(); err != nil {
+		return nil, nil, err
+	}
+	return &{{ .Service }}Service{loop: loop}, loop.Stop, nil
+}
+
+// GetLoop returns the loop the requests of the service run on.
+func (s *{{ .Service }}Service) GetLoop() work.Loop {
+	return s.loop
+}
+
+// OnSessionOpen is called when a session connects.
+func (s *{{ .Service }}Service) OnSessionOpen({{ .Session }}) {
+}
 
+// OnSessionClose is called when a session disconnects.
+func (s *{{ .Service }}Service) OnSessionClose({{ .Session }}) {
+}
+{{- else }}
 type {{ .Service }}Service struct {
 	pb.Unimplemented{{ .Service }}Server
 }
@@ -31,10 +67,15 @@ type {{ .Service }}Service struct {
 func New{{ .Service }}Service() *{{ .Service }}Service {
 	return &{{ .Service }}Service{}
 }
+{{- end }}
 
 {{- $s1 := "google.protobuf.Empty" }}
 {{ range .Methods }}
 {{- if eq .Type 1 }}
+{{- if .Command }}
+
+// {{ .Name }} handles command {{ .Command }}.
+{{- end }}
 func (s *{{ .Service }}Service) {{ .Name }}(ctx context.Context, req {{ if eq .Request $s1 }}*emptypb.Empty{{ else }}*pb.{{ .Request }}{{ end }}) ({{ if eq .Reply $s1 }}*emptypb.Empty{{ else }}*pb.{{ .Reply }}{{ end }}, error) {
 	return {{ if eq .Reply $s1 }}&emptypb.Empty{}{{ else }}&pb.{{ .Reply }}{}{{ end }}, nil
 }
@@ -103,6 +144,17 @@ type Service struct {
 
 	UseIO      bool
 	UseContext bool
+
+	// Socket services implement the server interface of a socket plugin.
+	Socket        bool
+	Interface     string // SocketServer, WebsocketServer
+	Session       string // parameter of the session hooks
+	SessionImport string
+}
+
+// Param returns the name of the service as a parameter.
+func (s *Service) Param() string {
+	return strings.ToLower(s.Service[:1]) + s.Service[1:]
 }
 
 // Method is a proto method.
@@ -111,6 +163,9 @@ type Method struct {
 	Name    string
 	Request string
 	Reply   string
+	// Command is the command id of socket methods, the GameCommand enum value
+	// or the (kratos.socket.cmd) id.
+	Command string
 
 	// type: unary or stream
 	Type MethodType
@@ -138,5 +193,5 @@ func (s *Service) execute() ([]byte, error) {
 	if err := tmpl.Execute(buf, s); err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	return format.Source(buf.Bytes())
 }
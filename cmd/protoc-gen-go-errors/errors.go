@@ -68,6 +68,7 @@ func genErrorsReason(_ *protogen.Plugin, _ *protogen.File, g *protogen.Generated
 	if code > 600 || code < 0 {
 		panic(fmt.Sprintf("Enum '%s' range must be greater than 0 and less than or equal to 600", string(enum.Desc.Name())))
 	}
+	socket := proto.GetExtension(enum.Desc.Options(), errors.E_Socket).(bool)
 	var ew errorWrapper
 	for _, v := range enum.Values {
 		enumCode := code
@@ -80,6 +81,21 @@ func genErrorsReason(_ *protogen.Plugin, _ *protogen.File, g *protogen.Generated
 		if enumCode > 600 || enumCode < 0 {
 			panic(fmt.Sprintf("Enum '%s' range must be greater than 0 and less than or equal to 600", string(v.Desc.Name())))
 		}
+		// A socket error uses its socket code instead, the socket transports
+		// return it as is in the payload code.
+		socketCode := proto.GetExtension(v.Desc.Options(), errors.E_SocketCode).(int32)
+		if socketCode == 0 && socket {
+			// the zero value of a socket enum is the success code
+			if socketCode = int32(v.Desc.Number()); socketCode == 0 {
+				continue
+			}
+		}
+		if socketCode < 0 {
+			panic(fmt.Sprintf("Enum '%s' socket code must be greater than 0", string(v.Desc.Name())))
+		}
+		if socketCode != 0 {
+			enumCode = int(socketCode)
+		}
 		if enumCode == 0 {
 			continue
 		}
@@ -93,7 +109,7 @@ func genErrorsReason(_ *protogen.Plugin, _ *protogen.File, g *protogen.Generated
 			Name:       string(enum.Desc.Name()),
 			Value:      string(v.Desc.Name()),
 			CamelValue: case2Camel(string(v.Desc.Name())),
-			HTTPCode:   enumCode,
+			Code:       enumCode,
 			Comment:    comment,
 			HasComment: len(comment) > 0,
 		}
@@ -1,6 +1,16 @@
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-errors/v2/errors"
+)
 
 func Test_case2Camel(t *testing.T) {
 	type args struct {
@@ -60,3 +70,61 @@ func Test_case2Camel(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSocketErrors(t *testing.T) {
+	socketEnum := &descriptorpb.EnumOptions{}
+	proto.SetExtension(socketEnum, errors.E_Socket, true)
+	socketCode := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(socketCode, errors.E_SocketCode, int32(1001))
+	httpCode := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(httpCode, errors.E_Code, int32(404))
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("game/v1/error_reason.proto"),
+		Package: proto.String("game.v1"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/game/api/game/v1;v1")},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name:    proto.String("GameError"),
+				Options: socketEnum,
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("SUCCESS"), Number: proto.Int32(0)},
+					{Name: proto.String("TABLE_NOT_FOUND"), Number: proto.Int32(103)},
+					{Name: proto.String("TOKEN_FAIL"), Number: proto.Int32(101), Options: socketCode},
+				},
+			},
+			{
+				Name: proto.String("ErrorReason"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("USER_NOT_FOUND"), Number: proto.Int32(0), Options: httpCode},
+				},
+			},
+		},
+	}
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generateFile(gen, gen.Files[0])
+	resp := gen.Response()
+	if resp.Error != nil || len(resp.File) != 1 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	content := resp.File[0].GetContent()
+	for _, want := range []string{
+		"return e.Reason == GameError_TABLE_NOT_FOUND.String() && e.Code == 103",
+		"return errors.New(103, GameError_TABLE_NOT_FOUND.String(), fmt.Sprintf(format, args...))",
+		"return errors.New(1001, GameError_TOKEN_FAIL.String(), fmt.Sprintf(format, args...))",
+		"return errors.New(404, ErrorReason_USER_NOT_FOUND.String(), fmt.Sprintf(format, args...))",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expect %q in the generated file:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "IsSuccess") {
+		t.Errorf("expect no error for the success value:\n%s", content)
+	}
+}
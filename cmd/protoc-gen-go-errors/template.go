@@ -12,7 +12,7 @@ var errorsTemplate string
 type errorInfo struct {
 	Name       string
 	Value      string
-	HTTPCode   int
+	Code       int
 	CamelValue string
 	Comment    string
 	HasComment bool
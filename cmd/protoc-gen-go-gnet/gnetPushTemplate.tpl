@@ -0,0 +1,49 @@
+{{$svrType := .ServiceType}}
+{{$svrName := .ServiceName}}
+
+// {{$svrType}}GNETPusher sends the pushes of {{$svrType}} service.
+type {{$svrType}}GNETPusher struct{}
+{{range .Methods}}
+{{- if ne .Comment ""}}
+{{.Comment}}
+{{- else}}
+// Push{{.Name}} pushes msg to sess as command {{.Ops}}.
+{{- end}}
+func ({{$svrType}}GNETPusher) Push{{.Name}}(sess session.Session, msg *{{.Request}}) error {
+	return sess.Push({{.Ops}}, msg)
+}
+{{end}}
+// {{$svrType}}_GNET_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_GNET_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
+
+// {{$svrType}}GNETClient subscribes to the pushes of {{$svrType}} service.
+type {{$svrType}}GNETClient interface {
+{{- range .Methods}}
+	// On{{.Name}} subscribes to the {{.Name}} pushes, replacing the previous subscription.
+	On{{.Name}}(fn func(*{{.Request}}))
+{{- end}}
+}
+
+type {{$svrType}}GNETClientImpl struct {
+	cc *gnet.Client
+}
+
+func New{{$svrType}}GNETClient(client *gnet.Client) {{$svrType}}GNETClient {
+	return &{{$svrType}}GNETClientImpl{client}
+}
+{{range .Methods}}
+func (c *{{$svrType}}GNETClientImpl) On{{.Name}}(fn func(*{{.Request}})) {
+	c.cc.OnPush({{.Ops}}, func(data []byte) {
+		msg := new({{.Request}})
+		if err := proto.Unmarshal(data, msg); err != nil {
+			log.Warnf("[gnet] unmarshal push {{.Ops}} of {{$svrName}} error: %v", err)
+			return
+		}
+		fn(msg)
+	})
+}
+{{end}}
@@ -4,6 +4,8 @@
 // {{$svrType}}GNETServer is the server API for {{$svrType}} service.
 type {{$svrType}}GNETServer interface {
 	GetLoop() work.Loop
+	OnSessionOpen(*gnet.Session)
+	OnSessionClose(*gnet.Session)
 {{- range .Methods}}
 	{{- if ne .Comment ""}}
 	{{.Comment}}
@@ -13,7 +15,7 @@ type {{$svrType}}GNETServer interface {
 }
 
 func Register{{$svrType}}GNETServer(s *gnet.Server, srv {{$svrType}}GNETServer) {
-	s.RegisterService(&{{$svrType}}_GNET_ServiceDesc, srv)
+	s.RegisterService(&{{$svrType}}_GNET_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 {{range .Methods}}
@@ -67,3 +69,54 @@ var {{$svrType}}_GNET_ServiceDesc = gnet.ServiceDesc{
 		{{- end}}
 	},
 }
+
+// {{$svrType}}_GNET_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_GNET_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
+
+// {{$svrType}}GNETClient is the client API for {{$svrType}} service.
+type {{$svrType}}GNETClient interface {
+{{- range .Methods}}
+	{{- if ne .Comment ""}}
+	{{.Comment}}
+	{{- end}}
+	{{.Name}}(ctx context.Context, req *{{.Request}}, opts ...gnet.CallOption) (rsp *{{.Reply}}, err error)
+{{- end}}
+{{- range .Pushes}}
+	// {{.Name}} subscribes to the {{.Message}} pushes, replacing the previous subscription.
+	{{.Name}}(fn func(*{{.Message}}))
+{{- end}}
+}
+
+type {{$svrType}}GNETClientImpl struct {
+	cc *gnet.Client
+}
+
+func New{{$svrType}}GNETClient(client *gnet.Client) {{$svrType}}GNETClient {
+	return &{{$svrType}}GNETClientImpl{client}
+}
+{{range .Methods}}
+func (c *{{$svrType}}GNETClientImpl) {{.Name}}(ctx context.Context, in *{{.Request}}, opts ...gnet.CallOption) (*{{.Reply}}, error) {
+	out := new({{.Reply}})
+	opts = append([]gnet.CallOption{gnet.Operation("/{{$svrName}}/{{.OriginalName}}")}, opts...)
+	if err := c.cc.Invoke(ctx, {{.Ops}}, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+{{end}}
+{{- range .Pushes}}
+func (c *{{$svrType}}GNETClientImpl) {{.Name}}(fn func(*{{.Message}})) {
+	c.cc.OnPush({{.Ops}}, func(data []byte) {
+		msg := new({{.Message}})
+		if err := proto.Unmarshal(data, msg); err != nil {
+			log.Warnf("[gnet] unmarshal push {{.Ops}} of {{$svrName}} error: %v", err)
+			return
+		}
+		fn(msg)
+	})
+}
+{{end}}
@@ -2,9 +2,14 @@ module github.com/yola1107/kratos/cmd/protoc-gen-go-gnet/v2
 
 go 1.23
 
-require google.golang.org/protobuf v1.36.6
+require (
+	github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2 v2.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.36.6
+)
 
 require (
 	github.com/google/go-cmp v0.5.6 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
+
+replace github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2 => ../protoc-gen-go-socket
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket/sockettest"
+)
+
+func generate(t *testing.T, services ...*descriptorpb.ServiceDescriptorProto) string {
+	t.Helper()
+	gen, err := sockettest.DemoPlugin(services...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := socket.ResolveCommands(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generateFile(gen, gen.Files[len(gen.Files)-1], commands)
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	return resp.File[0].GetContent()
+}
+
+func TestTransports(t *testing.T) {
+	content := generate(t, &descriptorpb.ServiceDescriptorProto{
+		Name: proto.String("Lobby"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			sockettest.DemoMethod("Login", 0),
+			sockettest.DemoMethod("Match", 2001, socket.Transport_WEBSOCKET, socket.Transport_GNET),
+		},
+	})
+	for _, want := range []string{
+		"type LobbySocketServer interface {",
+		"OnSessionOpen(session.Session)",
+		"return loop.PostAndWaitCtx(ctx, call)",
+		"func RegisterLobbySocketWebsocketServer(s *websocket.Server, srv LobbySocketServer) {",
+		"func RegisterLobbySocketTCPServer(s *tcp.Server, srv LobbySocketServer) {",
+		"func RegisterLobbySocketGNETServer(s *gnet.Server, srv LobbySocketServer) {",
+		"s.AddSessionHook(srv.OnSessionOpen, srv.OnSessionClose)",
+		"_Lobby_Match_Socket_Websocket_Handler",
+		"_Lobby_Match_Socket_GNET_Handler",
+		`2001: "/demo.Lobby/Match"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated code should contain %q", want)
+		}
+	}
+	// Match isn't exposed on tcp
+	if strings.Contains(content, "_Lobby_Match_Socket_TCP_Handler") {
+		t.Error("generated code should not register Match on tcp")
+	}
+}
+
+func TestTransportsUnused(t *testing.T) {
+	content := generate(t, &descriptorpb.ServiceDescriptorProto{
+		Name:   proto.String("Lobby"),
+		Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Login", 0, socket.Transport_TCP)},
+	})
+	for _, unwanted := range []string{"transport/websocket", "transport/gnet", "RegisterLobbySocketGNETServer"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("generated code should not contain %q", unwanted)
+		}
+	}
+}
+
+func TestPushService(t *testing.T) {
+	svc := &descriptorpb.ServiceDescriptorProto{
+		Name:    proto.String("TablePush"),
+		Options: &descriptorpb.ServiceOptions{},
+		Method:  []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("UserInfo", 3001)},
+	}
+	svc.Method[0].InputType = proto.String(".demo.UserInfoPush")
+	proto.SetExtension(svc.Options, socket.E_Push, true)
+	content := generate(t, svc)
+	for _, want := range []string{
+		"func (TablePushSocketPusher) PushUserInfo(sess session.Session, msg *UserInfoPush) error {",
+		"return sess.Push(3001, msg)",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated code should contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Register", "\"context\"", "transport/websocket"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("generated code should not contain %q", unwanted)
+		}
+	}
+}
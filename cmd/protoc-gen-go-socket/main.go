@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+var (
+	showVersion = flag.Bool("version", false, "print the version and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-go-socket %v\n", release)
+		return
+	}
+	protogen.Options{
+		ParamFunc: flag.CommandLine.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		commands, err := socket.ResolveCommands(gen)
+		if err != nil {
+			return err
+		}
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			generateFile(gen, f, commands)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+// transport is a socket transport the service may be registered on.
+type transport struct {
+	value   socket.Transport
+	name    string // Websocket, used in the generated names
+	pkg     string // websocket
+	imports string // github.com/yola1107/kratos/v2/transport/websocket
+}
+
+var transports = []transport{
+	{value: socket.Transport_WEBSOCKET, name: "Websocket", pkg: "websocket", imports: "github.com/yola1107/kratos/v2/transport/websocket"},
+	{value: socket.Transport_TCP, name: "TCP", pkg: "tcp", imports: "github.com/yola1107/kratos/v2/transport/tcp"},
+	{value: socket.Transport_GNET, name: "GNET", pkg: "gnet", imports: "github.com/yola1107/kratos/v2/transport/gnet"},
+}
+
+var methodSets = make(map[string]int)
+
+// generateFile generates a _socket.pb.go file containing the transport-neutral
+// service interface and its websocket, tcp and gnet registrations.
+func generateFile(gen *protogen.Plugin, file *protogen.File, commands map[*protogen.Method]int32) *protogen.GeneratedFile {
+	if len(file.Services) == 0 {
+		return nil
+	}
+	filename := file.GeneratedFilenamePrefix + "_socket.pb.go"
+	g := gen.NewGeneratedFile(filename, file.GoImportPath)
+	g.P("// Code generated by protoc-gen-go-socket. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P(fmt.Sprintf("// - protoc-gen-go-socket %s", release))
+	g.P("// - protoc               ", protocVersion(gen))
+	if file.Proto.GetOptions().GetDeprecated() {
+		g.P("// ", file.Desc.Path(), " is a deprecated file.")
+	} else {
+		g.P("// source: ", file.Desc.Path())
+	}
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+	generateFileContent(gen, file, g, commands)
+	return g
+}
+
+// generateFileContent generates the socket service definitions, excluding the package statement.
+func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, commands map[*protogen.Method]int32) {
+	if len(file.Services) == 0 {
+		return
+	}
+	requests, pushes := socket.ServiceKinds(file)
+	used := usedTransports(file, commands)
+	g.P("// This is a compile-time assertion to ensure that this generated file")
+	g.P("// is compatible with the kratos package it is being compiled against.")
+	g.P(`import (`)
+	if requests {
+		g.P(`	"context"`)
+		g.P()
+		g.P(`	"github.com/yola1107/kratos/v2/library/work"`)
+	}
+	var paths []string
+	if requests || pushes {
+		paths = append(paths, "github.com/yola1107/kratos/v2/transport/session")
+	}
+	for _, t := range transports {
+		if used[t.value] {
+			paths = append(paths, t.imports)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		g.P(`	"`, path, `"`)
+	}
+	if requests {
+		g.P()
+		g.P(`	"google.golang.org/grpc/codes"`)
+		g.P(`	"google.golang.org/grpc/status"`)
+		g.P(`	"google.golang.org/protobuf/proto"`)
+	}
+	g.P(`)`)
+	g.P()
+
+	for _, service := range file.Services {
+		genService(gen, file, g, service, commands)
+	}
+}
+
+func genService(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, commands map[*protogen.Method]int32) {
+	if service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated() {
+		g.P("//")
+		g.P(deprecationComment)
+	}
+
+	sd := &serviceDesc{
+		ServiceType: service.GoName,
+		ServiceName: string(service.Desc.FullName()),
+		Metadata:    file.Desc.Path(),
+		Push:        socket.IsPushService(service),
+	}
+	exposed := make(map[socket.Transport][]*methodDesc)
+	for _, method := range service.Methods {
+		ops, ok := commands[method]
+		if !ok {
+			continue
+		}
+		defer func() { methodSets[method.GoName]++ }()
+		name := method.GoName
+		if sd.Push {
+			name = "Push" + name
+		}
+		comment := methodComment(name, method)
+		md := &methodDesc{
+			Name:         method.GoName,
+			OriginalName: string(method.Desc.Name()),
+			Num:          methodSets[method.GoName],
+			Request:      g.QualifiedGoIdent(method.Input.GoIdent),
+			Comment:      comment,
+			Ops:          strconv.Itoa(int(ops)),
+		}
+		// the output of a push is unused, e.g. google.protobuf.Empty
+		if !sd.Push {
+			md.Reply = g.QualifiedGoIdent(method.Output.GoIdent)
+		}
+		sd.Methods = append(sd.Methods, md)
+		for t := range methodTransports(method) {
+			exposed[t] = append(exposed[t], md)
+		}
+	}
+	if !sd.Push {
+		for _, t := range transports {
+			if methods := exposed[t.value]; len(methods) != 0 {
+				sd.Transports = append(sd.Transports, &transportDesc{Name: t.name, Package: t.pkg, Methods: methods})
+			}
+		}
+	}
+	if len(sd.Methods) != 0 {
+		g.P(sd.execute())
+	}
+}
+
+// methodComment returns the comment of m as the doc comment of name.
+func methodComment(name string, m *protogen.Method) string {
+	text := strings.TrimSpace(m.Comments.Leading.String() + m.Comments.Trailing.String())
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = "// " + strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "//"))
+	}
+	if !strings.HasPrefix(lines[0], "// "+m.GoName+" ") && !strings.HasPrefix(lines[0], "// "+name+" ") {
+		lines[0] = "// " + name + " " + strings.TrimPrefix(lines[0], "// ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// methodTransports returns the transports of the (kratos.socket.transport)
+// option of m, all of them when it's empty.
+func methodTransports(m *protogen.Method) map[socket.Transport]bool {
+	set := make(map[socket.Transport]bool)
+	if opts := m.Desc.Options(); proto.HasExtension(opts, socket.E_Transport) {
+		for _, t := range proto.GetExtension(opts, socket.E_Transport).([]socket.Transport) {
+			if t != socket.Transport_TRANSPORT_UNSPECIFIED {
+				set[t] = true
+			}
+		}
+	}
+	if len(set) == 0 {
+		for _, t := range transports {
+			set[t.value] = true
+		}
+	}
+	return set
+}
+
+// usedTransports returns the transports registrations are generated for.
+func usedTransports(file *protogen.File, commands map[*protogen.Method]int32) map[socket.Transport]bool {
+	used := make(map[socket.Transport]bool)
+	for _, s := range file.Services {
+		if socket.IsPushService(s) {
+			continue
+		}
+		for _, m := range s.Methods {
+			if _, ok := commands[m]; !ok {
+				continue
+			}
+			for t := range methodTransports(m) {
+				used[t] = true
+			}
+		}
+	}
+	return used
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
+
+const deprecationComment = "// Deprecated: Do not use."
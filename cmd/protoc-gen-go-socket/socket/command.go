@@ -0,0 +1,168 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// GameCommandEnum is the enum whose values name the command ids of the
+// methods without the (kratos.socket.cmd) option.
+const GameCommandEnum = "GameCommand"
+
+// ResolveCommands resolves the command id of every unary method of the files
+// to generate, it reports all methods without an id and all ids used twice.
+func ResolveCommands(gen *protogen.Plugin) (map[*protogen.Method]int32, error) {
+	var (
+		ids    = make(map[*protogen.Method]int32)
+		owners = make(map[int32]*protogen.Method)
+		errs   []string
+	)
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		fallback := gameCommands(f)
+		for _, s := range f.Services {
+			for _, m := range s.Methods {
+				if m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer() {
+					continue
+				}
+				id, ok := commandID(m, fallback)
+				if !ok {
+					errs = append(errs, fmt.Sprintf("%s: method %s has no command id, set option (kratos.socket.cmd) or add %s to enum %s",
+						position(f, m.Desc), m.Desc.FullName(), m.GoName, GameCommandEnum))
+					continue
+				}
+				if other, ok := owners[id]; ok {
+					errs = append(errs, fmt.Sprintf("%s: command id %d of method %s is already used by %s",
+						position(f, m.Desc), id, m.Desc.FullName(), other.Desc.FullName()))
+					continue
+				}
+				owners[id] = m
+				ids[m] = id
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return ids, nil
+}
+
+// IsPushService reports whether s declares server pushes, see (kratos.socket.push).
+func IsPushService(s *protogen.Service) bool {
+	opts := s.Desc.Options()
+	return proto.HasExtension(opts, E_Push) && proto.GetExtension(opts, E_Push).(bool)
+}
+
+// ServiceKinds reports whether file has request services and push services
+// with methods.
+func ServiceKinds(file *protogen.File) (requests, pushes bool) {
+	for _, s := range file.Services {
+		if len(s.Methods) == 0 {
+			continue
+		}
+		if IsPushService(s) {
+			pushes = true
+		} else {
+			requests = true
+		}
+	}
+	return
+}
+
+func commandID(m *protogen.Method, fallback map[string]int32) (int32, bool) {
+	if opts := m.Desc.Options(); proto.HasExtension(opts, E_Cmd) {
+		return proto.GetExtension(opts, E_Cmd).(int32), true
+	}
+	id, ok := fallback[m.GoName]
+	return id, ok
+}
+
+func gameCommands(f *protogen.File) map[string]int32 {
+	m := make(map[string]int32)
+	for _, enum := range f.Enums {
+		if enum.Desc.Name() != GameCommandEnum {
+			continue
+		}
+		for _, v := range enum.Values {
+			m[string(v.Desc.Name())] = int32(v.Desc.Number())
+		}
+	}
+	return m
+}
+
+func position(f *protogen.File, d protoreflect.Descriptor) string {
+	loc := f.Desc.SourceLocations().ByDescriptor(d)
+	if loc.StartLine == 0 && loc.StartColumn == 0 && len(loc.Path) == 0 {
+		return f.Desc.Path()
+	}
+	return fmt.Sprintf("%s:%d:%d", f.Desc.Path(), loc.StartLine+1, loc.StartColumn+1)
+}
+
+// PushCommand is a server push, the message pushed by the command id.
+type PushCommand struct {
+	Message *protogen.Message
+	Ops     int32
+}
+
+// PushCommands pairs the values of enum GameCommand ending with Push with the
+// messages of the same name, with or without the On prefix, e.g. OnUserInfoPush
+// with message UserInfoPush. Values without a message are skipped.
+func PushCommands(f *protogen.File) []PushCommand {
+	messages := make(map[string]*protogen.Message)
+	for _, m := range f.Messages {
+		messages[string(m.Desc.Name())] = m
+	}
+	var pushes []PushCommand
+	for _, enum := range f.Enums {
+		if enum.Desc.Name() != GameCommandEnum {
+			continue
+		}
+		for _, v := range enum.Values {
+			name := string(v.Desc.Name())
+			if !strings.HasSuffix(name, "Push") {
+				continue
+			}
+			m, ok := messages[strings.TrimPrefix(name, "On")]
+			if !ok {
+				m, ok = messages[name]
+			}
+			if ok {
+				pushes = append(pushes, PushCommand{Message: m, Ops: int32(v.Desc.Number())})
+			}
+		}
+	}
+	return pushes
+}
+
+// Subscriptions returns the GameCommand pushes of file subscribed by the
+// client of service, skipping those named the same as a method. Push services
+// and services without methods exposed, as reported by exposed, generate no
+// such client. A nil exposed exposes every method with a command id.
+func Subscriptions(file *protogen.File, service *protogen.Service, commands map[*protogen.Method]int32, exposed func(*protogen.Method) bool) []PushCommand {
+	if IsPushService(service) {
+		return nil
+	}
+	names := make(map[string]bool, len(service.Methods))
+	for _, m := range service.Methods {
+		if _, ok := commands[m]; ok && (exposed == nil || exposed(m)) {
+			names[m.GoName] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	var pushes []PushCommand
+	for _, p := range PushCommands(file) {
+		if !names["On"+p.Message.GoIdent.GoName] {
+			pushes = append(pushes, p)
+		}
+	}
+	return pushes
+}
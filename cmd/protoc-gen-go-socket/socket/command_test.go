@@ -0,0 +1,123 @@
+package socket_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	. "github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket/sockettest"
+)
+
+func demoPlugin(t *testing.T, services ...*descriptorpb.ServiceDescriptorProto) *protogen.Plugin {
+	t.Helper()
+	gen, err := sockettest.DemoPlugin(services...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestResolveCommands(t *testing.T) {
+	gen := demoPlugin(t, &descriptorpb.ServiceDescriptorProto{
+		Name:   proto.String("Lobby"),
+		Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Login", 0), sockettest.DemoMethod("Match", 2001)},
+	})
+	commands, err := ResolveCommands(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int32)
+	for m, id := range commands {
+		got[m.GoName] = id
+	}
+	if got["Login"] != 1001 || got["Match"] != 2001 {
+		t.Fatalf("got %v, want Login=1001 Match=2001", got)
+	}
+}
+
+func TestResolveCommandsError(t *testing.T) {
+	gen := demoPlugin(t,
+		&descriptorpb.ServiceDescriptorProto{
+			Name:   proto.String("Lobby"),
+			Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Match", 2001), sockettest.DemoMethod("Leave", 0)},
+		},
+		&descriptorpb.ServiceDescriptorProto{
+			Name:   proto.String("Table"),
+			Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Sit", 2001)},
+		},
+	)
+	_, err := ResolveCommands(gen)
+	if err == nil {
+		t.Fatal("ResolveCommands should fail")
+	}
+	for _, want := range []string{
+		"method demo.Lobby.Leave has no command id",
+		"command id 2001 of method demo.Table.Sit is already used by demo.Lobby.Match",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err, want)
+		}
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	push := &descriptorpb.ServiceDescriptorProto{
+		Name:    proto.String("TablePush"),
+		Options: &descriptorpb.ServiceOptions{},
+		Method:  []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("UserInfo", 3002)},
+	}
+	proto.SetExtension(push.Options, E_Push, true)
+	gen := demoPlugin(t,
+		&descriptorpb.ServiceDescriptorProto{
+			Name:   proto.String("Lobby"),
+			Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Login", 0)},
+		},
+		push,
+	)
+	commands, err := ResolveCommands(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := gen.Files[len(gen.Files)-1]
+	if requests, pushes := ServiceKinds(file); !requests || !pushes {
+		t.Errorf("expect request and push services, got %v %v", requests, pushes)
+	}
+	lobby, table := file.Services[0], file.Services[1]
+	if IsPushService(lobby) || !IsPushService(table) {
+		t.Error("expect only TablePush to be a push service")
+	}
+
+	pushes := Subscriptions(file, lobby, commands, nil)
+	if len(pushes) != 1 || pushes[0].Ops != 3001 || pushes[0].Message.GoIdent.GoName != "UserInfoPush" {
+		t.Errorf("expect OnUserInfoPush 3001, got %v", pushes)
+	}
+	if pushes = Subscriptions(file, table, commands, nil); len(pushes) != 0 {
+		t.Errorf("push services subscribe nothing, got %v", pushes)
+	}
+	if pushes = Subscriptions(file, lobby, commands, func(*protogen.Method) bool { return false }); len(pushes) != 0 {
+		t.Errorf("services without exposed methods subscribe nothing, got %v", pushes)
+	}
+}
+
+// TestSocketPB checks socket.pb.go is generated from the same proto as the
+// api/socket package, run make socket after changing the socket proto.
+func TestSocketPB(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("..", "..", "..", "api", "socket", "socket.pb.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("socket.pb.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("socket/socket.pb.go is out of date, run make socket")
+	}
+}
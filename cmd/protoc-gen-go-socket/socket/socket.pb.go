@@ -0,0 +1,210 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v3.21.12
+// source: kratos/socket/socket.proto
+
+package socket
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Transport is a socket transport a method is exposed on.
+type Transport int32
+
+const (
+	Transport_TRANSPORT_UNSPECIFIED Transport = 0
+	Transport_WEBSOCKET             Transport = 1
+	Transport_TCP                   Transport = 2
+	Transport_GNET                  Transport = 3
+)
+
+// Enum value maps for Transport.
+var (
+	Transport_name = map[int32]string{
+		0: "TRANSPORT_UNSPECIFIED",
+		1: "WEBSOCKET",
+		2: "TCP",
+		3: "GNET",
+	}
+	Transport_value = map[string]int32{
+		"TRANSPORT_UNSPECIFIED": 0,
+		"WEBSOCKET":             1,
+		"TCP":                   2,
+		"GNET":                  3,
+	}
+)
+
+func (x Transport) Enum() *Transport {
+	p := new(Transport)
+	*p = x
+	return p
+}
+
+func (x Transport) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Transport) Descriptor() protoreflect.EnumDescriptor {
+	return file_kratos_socket_socket_proto_enumTypes[0].Descriptor()
+}
+
+func (Transport) Type() protoreflect.EnumType {
+	return &file_kratos_socket_socket_proto_enumTypes[0]
+}
+
+func (x Transport) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Transport.Descriptor instead.
+func (Transport) EnumDescriptor() ([]byte, []int) {
+	return file_kratos_socket_socket_proto_rawDescGZIP(), []int{0}
+}
+
+var file_kratos_socket_socket_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*int32)(nil),
+		Field:         1110,
+		Name:          "kratos.socket.cmd",
+		Tag:           "varint,1110,opt,name=cmd",
+		Filename:      "kratos/socket/socket.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]Transport)(nil),
+		Field:         1111,
+		Name:          "kratos.socket.transport",
+		Tag:           "varint,1111,rep,packed,name=transport,enum=kratos.socket.Transport",
+		Filename:      "kratos/socket/socket.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         1110,
+		Name:          "kratos.socket.push",
+		Tag:           "varint,1110,opt,name=push",
+		Filename:      "kratos/socket/socket.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// cmd is the command id of the method on the websocket, tcp and gnet
+	// transports, unique among the services registered to a server.
+	// Without it the value of the GameCommand enum named after the method is used.
+	//
+	// optional int32 cmd = 1110;
+	E_Cmd = &file_kratos_socket_socket_proto_extTypes[0]
+	// transport lists the transports protoc-gen-go-socket exposes the method
+	// on, all of them when empty.
+	//
+	//   rpc Login(LoginReq) returns (LoginRsp) {
+	//     option (kratos.socket.cmd)       = 1001;
+	//     option (kratos.socket.transport) = WEBSOCKET;
+	//     option (kratos.socket.transport) = GNET;
+	//   }
+	//
+	// repeated kratos.socket.Transport transport = 1111;
+	E_Transport = &file_kratos_socket_socket_proto_extTypes[1]
+)
+
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// push marks a service declaring the server pushes instead of requests,
+	// the input of each method is the pushed message and its cmd the command id.
+	//
+	//   service TablePush {
+	//     option (kratos.socket.push) = true;
+	//     rpc UserInfo(UserInfoPush) returns (google.protobuf.Empty) {
+	//       option (kratos.socket.cmd) = 2001;
+	//     }
+	//   }
+	//
+	// optional bool push = 1110;
+	E_Push = &file_kratos_socket_socket_proto_extTypes[2]
+)
+
+var File_kratos_socket_socket_proto protoreflect.FileDescriptor
+
+const file_kratos_socket_socket_proto_rawDesc = "" +
+	"\n" +
+	"\x1akratos/socket/socket.proto\x12\rkratos.socket\x1a google/protobuf/descriptor.proto*H\n" +
+	"\tTransport\x12\x19\n" +
+	"\x15TRANSPORT_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tWEBSOCKET\x10\x01\x12\a\n" +
+	"\x03TCP\x10\x02\x12\b\n" +
+	"\x04GNET\x10\x03:1\n" +
+	"\x03cmd\x12\x1e.google.protobuf.MethodOptions\x18\xd6\b \x01(\x05R\x03cmd:W\n" +
+	"\ttransport\x12\x1e.google.protobuf.MethodOptions\x18\xd7\b \x03(\x0e2\x18.kratos.socket.TransportR\ttransport:4\n" +
+	"\x04push\x12\x1f.google.protobuf.ServiceOptions\x18\xd6\b \x01(\bR\x04pushB\\\n" +
+	"\x18com.github.kratos.socketP\x01Z/github.com/yola1107/kratos/v2/api/socket;socket\xa2\x02\fKratosSocketb\x06proto3"
+
+var (
+	file_kratos_socket_socket_proto_rawDescOnce sync.Once
+	file_kratos_socket_socket_proto_rawDescData []byte
+)
+
+func file_kratos_socket_socket_proto_rawDescGZIP() []byte {
+	file_kratos_socket_socket_proto_rawDescOnce.Do(func() {
+		file_kratos_socket_socket_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_kratos_socket_socket_proto_rawDesc), len(file_kratos_socket_socket_proto_rawDesc)))
+	})
+	return file_kratos_socket_socket_proto_rawDescData
+}
+
+var file_kratos_socket_socket_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_kratos_socket_socket_proto_goTypes = []any{
+	(Transport)(0),                      // 0: kratos.socket.Transport
+	(*descriptorpb.MethodOptions)(nil),  // 1: google.protobuf.MethodOptions
+	(*descriptorpb.ServiceOptions)(nil), // 2: google.protobuf.ServiceOptions
+}
+var file_kratos_socket_socket_proto_depIdxs = []int32{
+	1, // 0: kratos.socket.cmd:extendee -> google.protobuf.MethodOptions
+	1, // 1: kratos.socket.transport:extendee -> google.protobuf.MethodOptions
+	2, // 2: kratos.socket.push:extendee -> google.protobuf.ServiceOptions
+	0, // 3: kratos.socket.transport:type_name -> kratos.socket.Transport
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	3, // [3:4] is the sub-list for extension type_name
+	0, // [0:3] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_kratos_socket_socket_proto_init() }
+func file_kratos_socket_socket_proto_init() {
+	if File_kratos_socket_socket_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_kratos_socket_socket_proto_rawDesc), len(file_kratos_socket_socket_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 3,
+			NumServices:   0,
+		},
+		GoTypes:           file_kratos_socket_socket_proto_goTypes,
+		DependencyIndexes: file_kratos_socket_socket_proto_depIdxs,
+		EnumInfos:         file_kratos_socket_socket_proto_enumTypes,
+		ExtensionInfos:    file_kratos_socket_socket_proto_extTypes,
+	}.Build()
+	File_kratos_socket_socket_proto = out.File
+	file_kratos_socket_socket_proto_goTypes = nil
+	file_kratos_socket_socket_proto_depIdxs = nil
+}
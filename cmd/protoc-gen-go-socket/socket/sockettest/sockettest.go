@@ -0,0 +1,67 @@
+// Package sockettest provides the demo proto file the socket plugin tests
+// generate code for.
+package sockettest
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+// DemoMethod returns a method of demo/demo.proto from demo.Req to demo.Rsp,
+// with the (kratos.socket.cmd) option when cmd is not zero and the
+// (kratos.socket.transport) option when transports are given.
+func DemoMethod(name string, cmd int32, transports ...socket.Transport) *descriptorpb.MethodDescriptorProto {
+	m := &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(".demo.Req"),
+		OutputType: proto.String(".demo.Rsp"),
+		Options:    &descriptorpb.MethodOptions{},
+	}
+	if cmd != 0 {
+		proto.SetExtension(m.Options, socket.E_Cmd, cmd)
+	}
+	if len(transports) != 0 {
+		proto.SetExtension(m.Options, socket.E_Transport, transports)
+	}
+	return m
+}
+
+// DemoPlugin returns the plugin generating demo/demo.proto with services, the
+// messages Req, Rsp and UserInfoPush and the GameCommand values Login = 1001
+// and OnUserInfoPush = 3001.
+func DemoPlugin(services ...*descriptorpb.ServiceDescriptorProto) (*protogen.Plugin, error) {
+	f := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("demo/demo.proto"),
+		Package:    proto.String("demo"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"kratos/socket/socket.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/demo;demo")},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String(socket.GameCommandEnum),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("Nothing"), Number: proto.Int32(0)},
+				{Name: proto.String("Login"), Number: proto.Int32(1001)},
+				{Name: proto.String("OnUserInfoPush"), Number: proto.Int32(3001)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Req")},
+			{Name: proto.String("Rsp")},
+			{Name: proto.String("UserInfoPush")},
+		},
+		Service: services,
+	}
+	return protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{f.GetName()},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(socket.File_kratos_socket_socket_proto),
+			f,
+		},
+	})
+}
@@ -0,0 +1,21 @@
+{{$svrType := .ServiceType}}
+{{$svrName := .ServiceName}}
+
+// {{$svrType}}SocketPusher sends the pushes of {{$svrType}} service on any socket transport.
+type {{$svrType}}SocketPusher struct{}
+{{range .Methods}}
+{{- if ne .Comment ""}}
+{{.Comment}}
+{{- else}}
+// Push{{.Name}} pushes msg to sess as command {{.Ops}}.
+{{- end}}
+func ({{$svrType}}SocketPusher) Push{{.Name}}(sess session.Session, msg *{{.Request}}) error {
+	return sess.Push({{.Ops}}, msg)
+}
+{{end}}
+// {{$svrType}}_Socket_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_Socket_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
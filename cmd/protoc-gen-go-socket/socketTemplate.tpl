@@ -0,0 +1,81 @@
+{{$svrType := .ServiceType}}
+{{$svrName := .ServiceName}}
+
+// {{$svrType}}SocketServer is the server API for {{$svrType}} service on the socket transports.
+type {{$svrType}}SocketServer interface {
+	GetLoop() work.Loop
+	OnSessionOpen(session.Session)
+	OnSessionClose(session.Session)
+{{- range .Methods}}
+	{{- if ne .Comment ""}}
+	{{.Comment}}
+	{{- end}}
+	{{.Name}}(context.Context, *{{.Request}}) (*{{.Reply}}, error)
+{{- end}}
+}
+{{range .Methods}}
+// _{{$svrType}}_{{.Name}}_Socket_Call calls the method on the loop of srv, if any.
+func _{{$svrType}}_{{.Name}}_Socket_Call(srv interface{}, ctx context.Context, req *{{.Request}}) ([]byte, error) {
+	call := func() ([]byte, error) {
+		resp, err := srv.({{$svrType}}SocketServer).{{.Name}}(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(resp)
+	}
+	if loop := srv.({{$svrType}}SocketServer).GetLoop(); loop != nil {
+		return loop.PostAndWaitCtx(ctx, call)
+	}
+	return call()
+}
+{{end}}
+{{- range .Transports}}
+{{- $tr := .}}
+// Register{{$svrType}}Socket{{.Name}}Server registers the methods of srv exposed on {{.Package}}.
+func Register{{$svrType}}Socket{{.Name}}Server(s *{{.Package}}.Server, srv {{$svrType}}SocketServer) {
+	s.RegisterService(&{{$svrType}}_Socket_{{.Name}}_ServiceDesc, srv, nil, nil)
+	s.AddSessionHook(srv.OnSessionOpen, srv.OnSessionClose)
+}
+{{range .Methods}}
+func _{{$svrType}}_{{.Name}}_Socket_{{$tr.Name}}_Handler(srv interface{}, ctx context.Context, data []byte, interceptor {{$tr.Package}}.UnaryServerInterceptor) ([]byte, error) {
+	in := new({{.Request}})
+	if err := proto.Unmarshal(data, in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return _{{$svrType}}_{{.Name}}_Socket_Call(srv, ctx, in)
+	}
+	info := &{{$tr.Package}}.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/{{$svrName}}/{{.OriginalName}}",
+	}
+	handler := func(ctx context.Context, req interface{}) ([]byte, error) {
+		r, ok := req.(*{{.Request}})
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid Request Argument, expect: *{{.Request}}, Not: %T", req)
+		}
+		return _{{$svrType}}_{{.Name}}_Socket_Call(srv, ctx, r)
+	}
+	return interceptor(ctx, in, info, handler)
+}
+{{end}}
+var {{$svrType}}_Socket_{{.Name}}_ServiceDesc = {{.Package}}.ServiceDesc{
+	ServiceName: "{{$svrName}}",
+	HandlerType: (*{{$svrType}}SocketServer)(nil),
+	Methods: []{{.Package}}.MethodDesc{
+		{{- range .Methods}}
+		{
+			MethodName: "{{.OriginalName}}",
+			Handler:    _{{$svrType}}_{{.Name}}_Socket_{{$tr.Name}}_Handler,
+			Ops:        {{.Ops}},
+		},
+		{{- end}}
+	},
+}
+{{end}}
+// {{$svrType}}_Socket_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_Socket_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"strings"
+	"text/template"
+)
+
+//go:embed socketTemplate.tpl
+var socketTemplate string
+
+//go:embed socketPushTemplate.tpl
+var socketPushTemplate string
+
+type serviceDesc struct {
+	ServiceType string // Greeter
+	ServiceName string // helloworld.Greeter
+	Metadata    string // api/helloworld/helloworld.proto
+	Methods     []*methodDesc
+	Transports  []*transportDesc
+	Push        bool // (kratos.socket.push) service, Methods are the pushes
+}
+
+type methodDesc struct {
+	// method
+	Name         string
+	OriginalName string // The parsed original name
+	Num          int
+	Request      string
+	Reply        string
+	Comment      string
+	// socket specific
+	Ops string // Operation code from (kratos.socket.cmd) or the GameCommand enum
+}
+
+// transportDesc is a transport and the methods exposed on it.
+type transportDesc struct {
+	Name    string // Websocket
+	Package string // websocket
+	Methods []*methodDesc
+}
+
+func (s *serviceDesc) execute() string {
+	text := socketTemplate
+	if s.Push {
+		text = socketPushTemplate
+	}
+	buf := new(bytes.Buffer)
+	tmpl, err := template.New("socket").Parse(strings.TrimSpace(text))
+	if err != nil {
+		panic(err)
+	}
+	if err := tmpl.Execute(buf, s); err != nil {
+		panic(err)
+	}
+	return strings.Trim(buf.String(), "\r\n")
+}
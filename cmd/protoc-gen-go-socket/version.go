@@ -0,0 +1,4 @@
+package main
+
+// release is the current protoc-gen-go-socket version.
+const release = "v2.8.8"
@@ -6,10 +6,12 @@ import (
 
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
 )
 
 // generateFile generates a _tcp.pb.go file containing kratos TCP service definitions.
-func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+func generateFile(gen *protogen.Plugin, file *protogen.File, commands map[*protogen.Method]int32) *protogen.GeneratedFile {
 	if len(file.Services) == 0 {
 		return nil
 	}
@@ -17,10 +19,10 @@ func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 	g := gen.NewGeneratedFile(filename, file.GoImportPath)
 
 	generateFileHeader(gen, file, g)
-	generateImports(gen, file, g)
+	generateImports(gen, file, g, commands)
 
 	for _, service := range file.Services {
-		genService(gen, file, g, service)
+		genService(gen, file, g, service, commands)
 	}
 
 	return g
@@ -41,28 +43,39 @@ func generateFileHeader(gen *protogen.Plugin, file *protogen.File, g *protogen.G
 	g.P()
 }
 
-func generateImports(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
+func generateImports(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, commands map[*protogen.Method]int32) {
 	if len(file.Services) == 0 {
 		return
 	}
 	g.P("// This is a compile-time assertion to ensure that this generated file")
 	g.P("// is compatible with the kratos package it is being compiled against.")
 
+	requests, pushes := socket.ServiceKinds(file)
 	g.P(`import (`)
-	g.P(`	"context"`)
-	g.P()
-	g.P(`	"github.com/yola1107/kratos/v2/library/work"`)
+	if requests {
+		g.P(`	"context"`)
+		g.P()
+		g.P(`	"github.com/yola1107/kratos/v2/library/work"`)
+	}
+	if pushes || hasSubscriptions(file, commands) {
+		g.P(`	"github.com/yola1107/kratos/v2/log"`)
+	}
+	if pushes {
+		g.P(`	"github.com/yola1107/kratos/v2/transport/session"`)
+	}
 	g.P(`	"github.com/yola1107/kratos/v2/transport/tcp"`)
 	g.P()
-	g.P(`	"google.golang.org/grpc/codes"`)
-	g.P(`	"google.golang.org/grpc/status"`)
+	if requests {
+		g.P(`	"google.golang.org/grpc/codes"`)
+		g.P(`	"google.golang.org/grpc/status"`)
+	}
 	g.P(`	"google.golang.org/protobuf/proto"`)
 	g.P(`)`)
 	g.P()
 }
 
 // 生成 TCP 服务器端代码
-func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service) {
+func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, commands map[*protogen.Method]int32) {
 	// 检查服务是否被标记为已弃用
 	if service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated() {
 		g.P("//")
@@ -75,48 +88,177 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 		fullServName   = fmt.Sprintf("%s.%s", file.Desc.Package(), serviceName)
 	)
 
+	// 推送服务只生成推送发送和订阅
+	if socket.IsPushService(service) {
+		genPushService(g, service, fullServName, commands)
+		return
+	}
+
 	// 生成 TCP 服务器接口
 	generateTCPInterface(g, serviceName, service)
 
 	// 生成 TCP 服务器注册代码
 	g.P("func Register", serviceName, "TCPServer(s *tcp.Server, srv ", serviceName, "TCPServer) {")
-	g.P(`	chanList := s.RegisterService(&`, serviceDescVar, `, srv)`)
-	g.P("	srv.SetCometChan(chanList, s)")
+	g.P(`	s.RegisterService(&`, serviceDescVar, `, srv, srv.OnSessionOpen, srv.OnSessionClose)`)
 	g.P("}")
 	g.P()
 
 	// 生成 TCP 服务器处理函数
-	var handlerNames []string
+	var (
+		methods      []*protogen.Method
+		handlerNames []string
+	)
 	for _, method := range service.Methods {
-		hname := generateServerMethod(g, serviceName, fullServName, method)
-		handlerNames = append(handlerNames, hname)
-	}
-
-	// 生成 TCP 服务器处理函数的Ops (请求协议code)
-	gameCommand := make(map[string]string)
-	for _, enum := range file.Enums {
-		if enum.Desc.Name() != "GameCommand" {
+		if _, ok := commands[method]; !ok {
 			continue
 		}
-		for _, v := range enum.Values {
-			gameCommand[string(v.Desc.Name())] = fmt.Sprintf("%d", v.Desc.Number())
-		}
+		hname := generateServerMethod(g, serviceName, fullServName, method)
+		methods = append(methods, method)
+		handlerNames = append(handlerNames, hname)
 	}
 
-	// 生成Service descriptor
+	// 生成Service descriptor, Ops 为 (kratos.socket.cmd) 或 GameCommand 中的请求协议code
 	g.P(`var `, serviceDescVar, ` = tcp.ServiceDesc{`)
 	g.P(`	ServiceName: `, strconv.Quote(fullServName), `,`)
 	g.P(`	HandlerType: (*`, serviceName, `TCPServer)(nil),`)
 	g.P(`	Methods: []tcp.MethodDesc{`)
-	for i, method := range service.Methods {
+	for i, method := range methods {
 		g.P(`		{`)
 		g.P(`			MethodName: `, strconv.Quote(method.GoName), `,`)
 		g.P(`			Handler: `, handlerNames[i], `,`)
-		g.P(`			Ops: `, gameCommand[method.GoName], `,`)
+		g.P(`			Ops: `, commands[method], `,`)
 		g.P(`		},`)
 	}
 	g.P(`	},`)
 	g.P(`}`)
+	g.P()
+
+	// 生成命令名表, 用于日志和监控标签
+	g.P("// ", serviceName, "_TCP_CommandNames maps the command ids of ", serviceName, " to full method names, for logging and metrics labels.")
+	g.P(`var `, serviceName, `_TCP_CommandNames = map[int32]string{`)
+	for _, method := range methods {
+		g.P(`	`, commands[method], `: `, strconv.Quote(fmt.Sprintf("/%s/%s", fullServName, method.GoName)), `,`)
+	}
+	g.P(`}`)
+	g.P()
+
+	// 生成 TCP 客户端代码
+	generateTCPClient(g, serviceName, methods, commands, socket.Subscriptions(file, service, commands, nil))
+}
+
+// 生成TCP客户端, 每个方法一个类型化调用, 每个推送一个订阅
+func generateTCPClient(g *protogen.GeneratedFile, serviceName string, methods []*protogen.Method, commands map[*protogen.Method]int32, pushes []socket.PushCommand) {
+	clientImpl := serviceName + "TCPClientImpl"
+	g.P("// ", serviceName, "TCPClient is the client API for ", serviceName, " service.")
+	g.P("type ", serviceName, "TCPClient interface {")
+	for _, method := range methods {
+		g.P("	", method.GoName, "(ctx context.Context, req *", method.Input.GoIdent, ") (rsp *", method.Output.GoIdent, ", err error)")
+	}
+	for _, p := range pushes {
+		name := "On" + p.Message.GoIdent.GoName
+		g.P("	// ", name, " subscribes to the ", p.Message.GoIdent.GoName, " pushes, replacing the previous subscription.")
+		g.P("	", name, "(fn func(*", p.Message.GoIdent, "))")
+	}
+	g.P(`}`)
+	g.P()
+	g.P("type ", clientImpl, " struct {")
+	g.P(`	cc *tcp.Client`)
+	g.P(`}`)
+	g.P()
+	g.P("func New", serviceName, "TCPClient(client *tcp.Client) ", serviceName, "TCPClient {")
+	g.P(`	return &`, clientImpl, `{client}`)
+	g.P(`}`)
+	g.P()
+	for _, method := range methods {
+		g.P("func (c *", clientImpl, ") ", method.GoName, "(ctx context.Context, in *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error) {")
+		g.P(`	out := new(`, method.Output.GoIdent, `)`)
+		g.P(`	if err := c.cc.Call(ctx, `, commands[method], `, in, out); err != nil {`)
+		g.P(`		return nil, err`)
+		g.P(`	}`)
+		g.P(`	return out, nil`)
+		g.P(`}`)
+		g.P()
+	}
+	for _, p := range pushes {
+		g.P("func (c *", clientImpl, ") On", p.Message.GoIdent.GoName, "(fn func(*", p.Message.GoIdent, ")) {")
+		g.P(`	c.cc.OnPush(`, p.Ops, `, func(data []byte) {`)
+		g.P(`		msg := new(`, p.Message.GoIdent, `)`)
+		g.P(`		if err := proto.Unmarshal(data, msg); err != nil {`)
+		g.P(`			log.Warnf("[tcp] unmarshal push `, p.Ops, ` of `, serviceName, ` error: %v", err)`)
+		g.P(`			return`)
+		g.P(`		}`)
+		g.P(`		fn(msg)`)
+		g.P(`	})`)
+		g.P(`}`)
+		g.P()
+	}
+}
+
+// 生成推送服务, 服务端类型化推送发送, 客户端类型化推送订阅
+func genPushService(g *protogen.GeneratedFile, service *protogen.Service, fullServName string, commands map[*protogen.Method]int32) {
+	var (
+		serviceName = service.GoName
+		pusher      = serviceName + "TCPPusher"
+		clientImpl  = serviceName + "TCPClientImpl"
+		methods     []*protogen.Method
+	)
+	for _, method := range service.Methods {
+		if _, ok := commands[method]; ok {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return
+	}
+
+	g.P("// ", pusher, " sends the pushes of ", serviceName, " service.")
+	g.P("type ", pusher, " struct{}")
+	g.P()
+	for _, method := range methods {
+		g.P("// Push", method.GoName, " pushes msg to sess as command ", commands[method], ".")
+		g.P("func (", pusher, ") Push", method.GoName, "(sess session.Session, msg *", method.Input.GoIdent, ") error {")
+		g.P(`	return sess.Push(`, commands[method], `, msg)`)
+		g.P(`}`)
+		g.P()
+	}
+
+	g.P("// ", serviceName, "_TCP_CommandNames maps the command ids of ", serviceName, " to full method names, for logging and metrics labels.")
+	g.P(`var `, serviceName, `_TCP_CommandNames = map[int32]string{`)
+	for _, method := range methods {
+		g.P(`	`, commands[method], `: `, strconv.Quote(fmt.Sprintf("/%s/%s", fullServName, method.GoName)), `,`)
+	}
+	g.P(`}`)
+	g.P()
+
+	g.P("// ", serviceName, "TCPClient subscribes to the pushes of ", serviceName, " service.")
+	g.P("type ", serviceName, "TCPClient interface {")
+	for _, method := range methods {
+		g.P("	// On", method.GoName, " subscribes to the ", method.GoName, " pushes, replacing the previous subscription.")
+		g.P("	On", method.GoName, "(fn func(*", method.Input.GoIdent, "))")
+	}
+	g.P(`}`)
+	g.P()
+	g.P("type ", clientImpl, " struct {")
+	g.P(`	cc *tcp.Client`)
+	g.P(`}`)
+	g.P()
+	g.P("func New", serviceName, "TCPClient(client *tcp.Client) ", serviceName, "TCPClient {")
+	g.P(`	return &`, clientImpl, `{client}`)
+	g.P(`}`)
+	g.P()
+	for _, method := range methods {
+		g.P("func (c *", clientImpl, ") On", method.GoName, "(fn func(*", method.Input.GoIdent, ")) {")
+		g.P(`	c.cc.OnPush(`, commands[method], `, func(data []byte) {`)
+		g.P(`		msg := new(`, method.Input.GoIdent, `)`)
+		g.P(`		if err := proto.Unmarshal(data, msg); err != nil {`)
+		g.P(`			log.Warnf("[tcp] unmarshal push `, commands[method], ` of `, fullServName, ` error: %v", err)`)
+		g.P(`			return`)
+		g.P(`		}`)
+		g.P(`		fn(msg)`)
+		g.P(`	})`)
+		g.P(`}`)
+		g.P()
+	}
 }
 
 // 生成TCP服务接口
@@ -124,7 +266,8 @@ func generateTCPInterface(g *protogen.GeneratedFile, serviceName string, service
 	g.P("// ", serviceName, "TCPServer is the server API for ", serviceName, " service.")
 	g.P("type ", serviceName, "TCPServer interface {")
 	g.P(`	GetLoop() work.Loop`)
-	g.P(`	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)`)
+	g.P(`	OnSessionOpen(key string)`)
+	g.P(`	OnSessionClose(key string)`)
 
 	for _, method := range service.Methods {
 		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
@@ -196,6 +339,15 @@ func generateServerMethod(g *protogen.GeneratedFile, servName, fullServName stri
 	return hname
 }
 
+func hasSubscriptions(file *protogen.File, commands map[*protogen.Method]int32) bool {
+	for _, service := range file.Services {
+		if len(socket.Subscriptions(file, service, commands, nil)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func protocVersion(gen *protogen.Plugin) string {
 	v := gen.Request.GetCompilerVersion()
 	if v == nil {
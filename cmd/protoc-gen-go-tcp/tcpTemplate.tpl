@@ -4,7 +4,8 @@
 // {{$svrType}}TCPServer is the server API for {{$svrType}} service.
 type {{$svrType}}TCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 {{- range .Methods}}
 	{{- if ne .Comment ""}}
 	{{.Comment}}
@@ -14,8 +15,7 @@ type {{$svrType}}TCPServer interface {
 }
 
 func Register{{$svrType}}TCPServer(s *tcp.Server, srv {{$svrType}}TCPServer) {
-	chanList := s.RegisterService(&{{$svrType}}_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&{{$svrType}}_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 {{range .Methods}}
@@ -69,3 +69,10 @@ var {{$svrType}}_TCP_ServiceDesc = tcp.ServiceDesc{
 		{{- end}}
 	},
 }
+
+// {{$svrType}}_TCP_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_TCP_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
@@ -27,7 +27,7 @@ type methodDesc struct {
 	Reply        string
 	Comment      string
 	// tcp specific
-	Ops string // Operation code from GameCommand enum
+	Ops string // Operation code from (kratos.socket.cmd) or the GameCommand enum
 }
 
 func (s *serviceDesc) execute() string {
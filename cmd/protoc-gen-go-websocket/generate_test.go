@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket/sockettest"
+)
+
+func generate(t *testing.T, services ...*descriptorpb.ServiceDescriptorProto) string {
+	t.Helper()
+	gen, err := sockettest.DemoPlugin(services...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := socket.ResolveCommands(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generateFile(gen, gen.Files[len(gen.Files)-1], commands)
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	return resp.File[0].GetContent()
+}
+
+func TestGenerateFile(t *testing.T) {
+	content := generate(t, &descriptorpb.ServiceDescriptorProto{
+		Name:   proto.String("Lobby"),
+		Method: []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("Login", 0), sockettest.DemoMethod("Match", 2001)},
+	})
+	for _, want := range []string{
+		"Ops:        2001",
+		`2001: "/demo.Lobby/Match"`,
+		"Lobby_Websocket_CommandNames",
+		"func NewLobbyWebsocketClient(",
+		"c.cc.Call(ctx, 2001, in, out)",
+		"OnUserInfoPush(fn func(*UserInfoPush))",
+		"c.cc.OnPush(3001, ",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated code should contain %q", want)
+		}
+	}
+}
+
+func TestPushService(t *testing.T) {
+	svc := &descriptorpb.ServiceDescriptorProto{
+		Name:    proto.String("TablePush"),
+		Options: &descriptorpb.ServiceOptions{},
+		Method:  []*descriptorpb.MethodDescriptorProto{sockettest.DemoMethod("UserInfo", 3001)},
+	}
+	svc.Method[0].InputType = proto.String(".demo.UserInfoPush")
+	proto.SetExtension(svc.Options, socket.E_Push, true)
+	content := generate(t, svc)
+	for _, want := range []string{
+		"func (TablePushWebsocketPusher) PushUserInfo(sess session.Session, msg *UserInfoPush) error {",
+		"return sess.Push(3001, msg)",
+		"OnUserInfo(fn func(*UserInfoPush))",
+		"c.cc.OnPush(3001, ",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated code should contain %q", want)
+		}
+	}
+	// a push service has no server to register
+	for _, unwanted := range []string{"Register", "\"context\""} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("generated code should not contain %q", unwanted)
+		}
+	}
+}
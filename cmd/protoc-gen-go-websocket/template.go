@@ -10,12 +10,17 @@ import (
 //go:embed websocketTemplate.tpl
 var websocketTemplate string
 
+//go:embed websocketPushTemplate.tpl
+var websocketPushTemplate string
+
 type serviceDesc struct {
 	ServiceType string // Greeter
 	ServiceName string // helloworld.Greeter
 	Metadata    string // api/helloworld/helloworld.proto
 	Methods     []*methodDesc
 	MethodSets  map[string]*methodDesc
+	Pushes      []*pushDesc
+	Push        bool // (kratos.socket.push) service, Methods are the pushes
 }
 
 type methodDesc struct {
@@ -27,7 +32,13 @@ type methodDesc struct {
 	Reply        string
 	Comment      string
 	// websocket specific
-	Ops string // Operation code from GameCommand enum
+	Ops string // Operation code from (kratos.socket.cmd) or the GameCommand enum
+}
+
+type pushDesc struct {
+	Name    string // OnUserInfoPush
+	Message string // UserInfoPush
+	Ops     string // Command id of the GameCommand value
 }
 
 func (s *serviceDesc) execute() string {
@@ -36,7 +47,11 @@ func (s *serviceDesc) execute() string {
 		s.MethodSets[m.Name] = m
 	}
 	buf := new(bytes.Buffer)
-	tmpl, err := template.New("websocket").Parse(strings.TrimSpace(websocketTemplate))
+	text := websocketTemplate
+	if s.Push {
+		text = websocketPushTemplate
+	}
+	tmpl, err := template.New("websocket").Parse(strings.TrimSpace(text))
 	if err != nil {
 		panic(err)
 	}
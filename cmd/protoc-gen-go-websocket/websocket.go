@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
 )
 
 const (
@@ -20,7 +23,7 @@ const (
 var methodSets = make(map[string]int)
 
 // generateFile generates a _websocket.pb.go file containing kratos websocket service definitions.
-func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+func generateFile(gen *protogen.Plugin, file *protogen.File, commands map[*protogen.Method]int32) *protogen.GeneratedFile {
 	if len(file.Services) == 0 {
 		return nil
 	}
@@ -38,52 +41,52 @@ func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 	g.P()
 	g.P("package ", file.GoPackageName)
 	g.P()
-	generateFileContent(gen, file, g)
+	generateFileContent(gen, file, g, commands)
 	return g
 }
 
 // generateFileContent generates the kratos websocket service definitions, excluding the package statement.
-func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
+func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, commands map[*protogen.Method]int32) {
 	if len(file.Services) == 0 {
 		return
 	}
 	g.P("// This is a compile-time assertion to ensure that this generated file")
 	g.P("// is compatible with the kratos package it is being compiled against.")
+	requests, pushes := socket.ServiceKinds(file)
 	g.P(`import (`)
-	g.P(`	"context"`)
-	// g.P(`	"fmt"`) // 添加 fmt 用于错误格式化
-	g.P()
-	g.P(`	"github.com/yola1107/kratos/v2/library/work"`)
+	if requests {
+		g.P(`	"context"`)
+		// g.P(`	"fmt"`) // 添加 fmt 用于错误格式化
+		g.P()
+		g.P(`	"github.com/yola1107/kratos/v2/library/work"`)
+	}
+	if pushes || hasSubscriptions(file, commands) {
+		g.P(`	"github.com/yola1107/kratos/v2/log"`)
+	}
+	if pushes {
+		g.P(`	"github.com/yola1107/kratos/v2/transport/session"`)
+	}
 	g.P(`	"github.com/yola1107/kratos/v2/transport/websocket"`)
 	g.P()
-	g.P(`	"google.golang.org/grpc/codes"`)  // 添加 gRPC 状态码
-	g.P(`	"google.golang.org/grpc/status"`) // 添加 gRPC 状态
+	if requests {
+		g.P(`	"google.golang.org/grpc/codes"`)  // 添加 gRPC 状态码
+		g.P(`	"google.golang.org/grpc/status"`) // 添加 gRPC 状态
+	}
 	g.P(`	"google.golang.org/protobuf/proto"`)
 	g.P(`)`)
 	g.P()
 
 	for _, service := range file.Services {
-		genService(gen, file, g, service)
+		genService(gen, file, g, service, commands)
 	}
 }
 
-func genService(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service) {
+func genService(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, service *protogen.Service, commands map[*protogen.Method]int32) {
 	if service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated() {
 		g.P("//")
 		g.P(deprecationComment)
 	}
 
-	// Build GameCommand map
-	gameCommand := make(map[string]string)
-	for _, enum := range file.Enums {
-		if enum.Desc.Name() != "GameCommand" {
-			continue
-		}
-		for _, v := range enum.Values {
-			gameCommand[string(v.Desc.Name())] = fmt.Sprintf("%d", v.Desc.Number())
-		}
-	}
-
 	// Websocket Server.
 	// Reference packages used in template to ensure they are imported
 	_ = transportWebsocketPackage.Ident("Server")
@@ -95,24 +98,41 @@ func genService(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFi
 		ServiceType: service.GoName,
 		ServiceName: string(service.Desc.FullName()),
 		Metadata:    file.Desc.Path(),
+		Push:        socket.IsPushService(service),
 	}
 	for _, method := range service.Methods {
-		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+		ops, ok := commands[method]
+		if !ok {
 			continue
 		}
 		defer func() { methodSets[method.GoName]++ }()
+		name := method.GoName
+		if sd.Push {
+			name = "Push" + name
+		}
 		comment := method.Comments.Leading.String() + method.Comments.Trailing.String()
 		if comment != "" {
-			comment = "// " + method.GoName + strings.TrimPrefix(strings.TrimSuffix(comment, "\n"), "//")
+			comment = "// " + name + strings.TrimPrefix(strings.TrimSuffix(comment, "\n"), "//")
 		}
-		sd.Methods = append(sd.Methods, &methodDesc{
+		md := &methodDesc{
 			Name:         method.GoName,
 			OriginalName: string(method.Desc.Name()),
 			Num:          methodSets[method.GoName],
 			Request:      g.QualifiedGoIdent(method.Input.GoIdent),
-			Reply:        g.QualifiedGoIdent(method.Output.GoIdent),
 			Comment:      comment,
-			Ops:          gameCommand[method.GoName],
+			Ops:          strconv.Itoa(int(ops)),
+		}
+		// the output of a push is unused, e.g. google.protobuf.Empty
+		if !sd.Push {
+			md.Reply = g.QualifiedGoIdent(method.Output.GoIdent)
+		}
+		sd.Methods = append(sd.Methods, md)
+	}
+	for _, p := range socket.Subscriptions(file, service, commands, nil) {
+		sd.Pushes = append(sd.Pushes, &pushDesc{
+			Name:    "On" + p.Message.GoIdent.GoName,
+			Message: g.QualifiedGoIdent(p.Message.GoIdent),
+			Ops:     strconv.Itoa(int(p.Ops)),
 		})
 	}
 	if len(sd.Methods) != 0 {
@@ -120,6 +140,15 @@ func genService(_ *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFi
 	}
 }
 
+func hasSubscriptions(file *protogen.File, commands map[*protogen.Method]int32) bool {
+	for _, service := range file.Services {
+		if len(socket.Subscriptions(file, service, commands, nil)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func protocVersion(gen *protogen.Plugin) string {
 	v := gen.Request.GetCompilerVersion()
 	if v == nil {
@@ -0,0 +1,49 @@
+{{$svrType := .ServiceType}}
+{{$svrName := .ServiceName}}
+
+// {{$svrType}}WebsocketPusher sends the pushes of {{$svrType}} service.
+type {{$svrType}}WebsocketPusher struct{}
+{{range .Methods}}
+{{- if ne .Comment ""}}
+{{.Comment}}
+{{- else}}
+// Push{{.Name}} pushes msg to sess as command {{.Ops}}.
+{{- end}}
+func ({{$svrType}}WebsocketPusher) Push{{.Name}}(sess session.Session, msg *{{.Request}}) error {
+	return sess.Push({{.Ops}}, msg)
+}
+{{end}}
+// {{$svrType}}_Websocket_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_Websocket_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
+
+// {{$svrType}}WebsocketClient subscribes to the pushes of {{$svrType}} service.
+type {{$svrType}}WebsocketClient interface {
+{{- range .Methods}}
+	// On{{.Name}} subscribes to the {{.Name}} pushes, replacing the previous subscription.
+	On{{.Name}}(fn func(*{{.Request}}))
+{{- end}}
+}
+
+type {{$svrType}}WebsocketClientImpl struct {
+	cc *websocket.Client
+}
+
+func New{{$svrType}}WebsocketClient(client *websocket.Client) {{$svrType}}WebsocketClient {
+	return &{{$svrType}}WebsocketClientImpl{client}
+}
+{{range .Methods}}
+func (c *{{$svrType}}WebsocketClientImpl) On{{.Name}}(fn func(*{{.Request}})) {
+	c.cc.OnPush({{.Ops}}, func(data []byte) {
+		msg := new({{.Request}})
+		if err := proto.Unmarshal(data, msg); err != nil {
+			log.Warnf("[websocket] unmarshal push {{.Ops}} of {{$svrName}} error: %v", err)
+			return
+		}
+		fn(msg)
+	})
+}
+{{end}}
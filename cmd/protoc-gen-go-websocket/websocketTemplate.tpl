@@ -69,3 +69,53 @@ var {{$svrType}}_Websocket_ServiceDesc = websocket.ServiceDesc{
 		{{- end}}
 	},
 }
+
+// {{$svrType}}_Websocket_CommandNames maps the command ids of {{$svrType}} to full method names, for logging and metrics labels.
+var {{$svrType}}_Websocket_CommandNames = map[int32]string{
+	{{- range .Methods}}
+	{{.Ops}}: "/{{$svrName}}/{{.OriginalName}}",
+	{{- end}}
+}
+
+// {{$svrType}}WebsocketClient is the client API for {{$svrType}} service.
+type {{$svrType}}WebsocketClient interface {
+{{- range .Methods}}
+	{{- if ne .Comment ""}}
+	{{.Comment}}
+	{{- end}}
+	{{.Name}}(ctx context.Context, req *{{.Request}}) (rsp *{{.Reply}}, err error)
+{{- end}}
+{{- range .Pushes}}
+	// {{.Name}} subscribes to the {{.Message}} pushes, replacing the previous subscription.
+	{{.Name}}(fn func(*{{.Message}}))
+{{- end}}
+}
+
+type {{$svrType}}WebsocketClientImpl struct {
+	cc *websocket.Client
+}
+
+func New{{$svrType}}WebsocketClient(client *websocket.Client) {{$svrType}}WebsocketClient {
+	return &{{$svrType}}WebsocketClientImpl{client}
+}
+{{range .Methods}}
+func (c *{{$svrType}}WebsocketClientImpl) {{.Name}}(ctx context.Context, in *{{.Request}}) (*{{.Reply}}, error) {
+	out := new({{.Reply}})
+	if err := c.cc.Call(ctx, {{.Ops}}, in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+{{end}}
+{{- range .Pushes}}
+func (c *{{$svrType}}WebsocketClientImpl) {{.Name}}(fn func(*{{.Message}})) {
+	c.cc.OnPush({{.Ops}}, func(data []byte) {
+		msg := new({{.Message}})
+		if err := proto.Unmarshal(data, msg); err != nil {
+			log.Warnf("[websocket] unmarshal push {{.Ops}} of {{$svrName}} error: %v", err)
+			return
+		}
+		fn(msg)
+	})
+}
+{{end}}
@@ -0,0 +1,38 @@
+{{$svrType := .ServiceType}}
+/** {{$svrType}}Command holds the command ids of {{.ServiceName}}. */
+export const {{$svrType}}Command = {
+{{- range .Methods}}
+  {{.Name}}: {{.Ops}},
+{{- end}}
+} as const;
+
+/** {{$svrType}}WebsocketClient is the client API for {{.ServiceName}} service. */
+export class {{$svrType}}WebsocketClient {
+  constructor(private readonly client: Client) {}
+{{- range .Methods}}
+{{if $.Push}}
+  {{- if ne .Comment ""}}
+  {{.Comment}}
+  {{- else}}
+  /** {{.Func}} subscribes fn to the {{.Name}} pushes. */
+  {{- end}}
+  {{.Func}}(fn: (msg: {{.Request}}) => void): () => void {
+    return this.client.onPush({{$svrType}}Command.{{.Name}}, {{.Request}}, fn);
+  }
+{{- else}}
+  {{- if ne .Comment ""}}
+  {{.Comment}}
+  {{- end}}
+  {{.Func}}(req: {{.Request}}, timeout?: number): Promise<{{.Reply}}> {
+    return this.client.call({{$svrType}}Command.{{.Name}}, req, {{.Request}}, {{.Reply}}, timeout);
+  }
+{{- end}}
+{{- end}}
+{{- range .Pushes}}
+
+  /** {{.Func}} subscribes fn to the {{.Message}} pushes. */
+  {{.Func}}(fn: (msg: {{.Message}}) => void): () => void {
+    return this.client.onPush({{.Ops}}, {{.Message}}, fn);
+  }
+{{- end}}
+}
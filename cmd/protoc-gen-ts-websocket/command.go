@@ -0,0 +1,25 @@
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+// onWebsocket reports whether m is exposed on websocket, that is its
+// (kratos.socket.transport) option is empty or lists WEBSOCKET.
+func onWebsocket(m *protogen.Method) bool {
+	opts := m.Desc.Options()
+	if !proto.HasExtension(opts, socket.E_Transport) {
+		return true
+	}
+	restricted := false
+	for _, t := range proto.GetExtension(opts, socket.E_Transport).([]socket.Transport) {
+		if t == socket.Transport_WEBSOCKET {
+			return true
+		}
+		restricted = restricted || t != socket.Transport_TRANSPORT_UNSPECIFIED
+	}
+	return !restricted
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+var (
+	showVersion  = flag.Bool("version", false, "print the version and exit")
+	importSuffix = flag.String("import_suffix", "", "suffix of the relative imports, e.g. .js for ES modules")
+)
+
+func main() {
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-ts-websocket %v\n", release)
+		return
+	}
+	if err := run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
+
+func run(r io.Reader, w io.Writer) error {
+	in, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err = proto.Unmarshal(in, req); err != nil {
+		return err
+	}
+	gen, err := newPlugin(req)
+	if err != nil {
+		return err
+	}
+	if err = generate(gen); err != nil {
+		gen.Error(err)
+	}
+	out, err := proto.Marshal(gen.Response())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// newPlugin returns the plugin of req, the files without go_package, usual
+// for the protos of a TypeScript client, get a placeholder Go import path.
+func newPlugin(req *pluginpb.CodeGeneratorRequest) (*protogen.Plugin, error) {
+	for _, f := range req.GetProtoFile() {
+		if f.GetOptions().GetGoPackage() == "" {
+			pkg := strings.NewReplacer(".", "_", "-", "_").Replace(f.GetPackage())
+			if pkg == "" {
+				pkg = "main"
+			}
+			param := fmt.Sprintf("M%s=protoc-gen-ts-websocket/%s;%s", f.GetName(), path.Dir(f.GetName()), pkg)
+			if req.Parameter == nil || req.GetParameter() == "" {
+				req.Parameter = proto.String(param)
+			} else {
+				req.Parameter = proto.String(req.GetParameter() + "," + param)
+			}
+		}
+	}
+	gen, err := protogen.Options{ParamFunc: flag.CommandLine.Set}.New(req)
+	if err != nil {
+		return nil, err
+	}
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	return gen, nil
+}
+
+func generate(gen *protogen.Plugin) error {
+	commands, err := socket.ResolveCommands(gen)
+	if err != nil {
+		return err
+	}
+	generated := false
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		if generateFile(gen, f, commands) != nil {
+			generated = true
+		}
+	}
+	if generated {
+		generateRuntime(gen)
+	}
+	return nil
+}
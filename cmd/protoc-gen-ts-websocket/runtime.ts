@@ -0,0 +1,380 @@
+/** Op is the op of a websocket Payload, see transport/websocket/proto. */
+export const Op = {
+  Push: 0,
+  Ping: 1,
+  Pong: 2,
+  Request: 3,
+  Response: 4,
+} as const;
+
+/** PlaceClient marks the payloads sent by the client. */
+export const PlaceClient = 0;
+
+/** Payload is the envelope of every websocket message, websocket.proto.Payload. */
+export interface Payload {
+  op: number;
+  place: number;
+  seq: number;
+  code: number;
+  command: number;
+  body: Uint8Array;
+  /** error details of a response with a non-zero code. */
+  error?: PayloadError;
+}
+
+/** PayloadError is the error details of a response, websocket.proto.Error. */
+export interface PayloadError {
+  reason: string;
+  message: string;
+  metadata: Record<string, string>;
+}
+
+function writeVarint(buf: number[], v: number): void {
+  if (v < 0) {
+    // a negative int32 is sign extended to 10 bytes
+    let lo = v >>> 0;
+    for (let i = 0; i < 4; i++) {
+      buf.push((lo & 0x7f) | 0x80);
+      lo >>>= 7;
+    }
+    buf.push(lo | 0xf0, 0xff, 0xff, 0xff, 0xff, 0x01);
+    return;
+  }
+  while (v > 0x7f) {
+    buf.push((v & 0x7f) | 0x80);
+    v >>>= 7;
+  }
+  buf.push(v);
+}
+
+/** encodePayload marshals p as websocket.proto.Payload, zero fields are omitted. */
+export function encodePayload(p: Partial<Payload>): Uint8Array {
+  const buf: number[] = [];
+  const fields = [p.op, p.place, p.seq, p.code, p.command];
+  for (let i = 0; i < fields.length; i++) {
+    const v = fields[i] ?? 0;
+    if (v !== 0) {
+      buf.push((i + 1) << 3);
+      writeVarint(buf, v);
+    }
+  }
+  if (p.body && p.body.length > 0) {
+    buf.push((6 << 3) | 2);
+    writeVarint(buf, p.body.length);
+    const out = new Uint8Array(buf.length + p.body.length);
+    out.set(buf);
+    out.set(p.body, buf.length);
+    return out;
+  }
+  return Uint8Array.from(buf);
+}
+
+/**
+ * readFields calls fn with the varint and the length delimited fields of a
+ * message, the other fields are skipped.
+ */
+function readFields(data: Uint8Array, fn: (field: number, v: number | Uint8Array) => void): void {
+  let pos = 0;
+  const varint = (): number => {
+    let v = 0;
+    for (let shift = 0; ; shift += 7) {
+      if (pos >= data.length) {
+        throw new Error("payload: unexpected end of data");
+      }
+      const b = data[pos++];
+      if (shift < 32) {
+        v |= (b & 0x7f) << shift;
+      }
+      if (b < 0x80) {
+        return v | 0;
+      }
+    }
+  };
+  while (pos < data.length) {
+    const tag = varint();
+    const field = tag >>> 3;
+    switch (tag & 7) {
+      case 0:
+        fn(field, varint());
+        break;
+      case 1:
+        pos += 8;
+        break;
+      case 2: {
+        const n = varint();
+        fn(field, data.subarray(pos, pos + n));
+        pos += n;
+        break;
+      }
+      case 5:
+        pos += 4;
+        break;
+      default:
+        throw new Error(`payload: unsupported wire type ${tag & 7}`);
+    }
+  }
+}
+
+/** decodePayload unmarshals a websocket.proto.Payload, unknown fields are skipped. */
+export function decodePayload(data: Uint8Array): Payload {
+  const p: Payload = { op: 0, place: 0, seq: 0, code: 0, command: 0, body: new Uint8Array(0) };
+  readFields(data, (field, v) => {
+    if (typeof v === "number") {
+      if (field === 1) p.op = v;
+      else if (field === 2) p.place = v;
+      else if (field === 3) p.seq = v;
+      else if (field === 4) p.code = v;
+      else if (field === 5) p.command = v;
+    } else if (field === 6) {
+      p.body = v;
+    } else if (field === 7) {
+      p.error = decodeError(v);
+    }
+  });
+  return p;
+}
+
+const utf8 = new TextDecoder();
+
+/** decodeError unmarshals a websocket.proto.Error. */
+function decodeError(data: Uint8Array): PayloadError {
+  const e: PayloadError = { reason: "", message: "", metadata: {} };
+  readFields(data, (field, v) => {
+    if (typeof v === "number") {
+      return;
+    }
+    if (field === 1) {
+      e.reason = utf8.decode(v);
+    } else if (field === 2) {
+      e.message = utf8.decode(v);
+    } else if (field === 3) {
+      let key = "";
+      let value = "";
+      readFields(v, (f, kv) => {
+        if (typeof kv === "number") return;
+        if (f === 1) key = utf8.decode(kv);
+        else if (f === 2) value = utf8.decode(kv);
+      });
+      e.metadata[key] = value;
+    }
+  });
+  return e;
+}
+
+/** MessageType encodes and decodes a message, e.g. the message objects generated by ts-proto. */
+export interface MessageType<T> {
+  encode(message: T): { finish(): Uint8Array };
+  decode(input: Uint8Array): T;
+}
+
+/**
+ * SocketError is a response with a non-zero code, it carries the reason,
+ * message and metadata of the errors.Error returned by the server.
+ */
+export class SocketError extends Error {
+  readonly reason: string;
+  readonly metadata: Record<string, string>;
+
+  constructor(
+    readonly code: number,
+    readonly command: number,
+    detail?: PayloadError,
+  ) {
+    super(detail?.message || `websocket: command ${command} returned error code ${code}`);
+    this.name = "SocketError";
+    this.reason = detail?.reason ?? "";
+    this.metadata = detail?.metadata ?? {};
+  }
+}
+
+/** WebSocketLike is the subset of the WebSocket API used by Client. */
+export interface WebSocketLike {
+  binaryType: string;
+  readonly readyState: number;
+  onopen: ((ev: unknown) => void) | null;
+  onclose: ((ev: unknown) => void) | null;
+  onerror: ((ev: unknown) => void) | null;
+  onmessage: ((ev: { data: unknown }) => void) | null;
+  send(data: Uint8Array): void;
+  close(): void;
+}
+
+export interface ClientOptions {
+  /** url of the websocket server, e.g. ws://127.0.0.1:3102 */
+  url: string;
+  /** timeout of a request in milliseconds, defaults to 2000. */
+  timeout?: number;
+  /** interval of the pings in milliseconds, defaults to 15000 like the server. */
+  pingInterval?: number;
+  /** the connection is closed after no message for readDeadline milliseconds, defaults to 60000. */
+  readDeadline?: number;
+  /** WebSocket constructor, defaults to the global WebSocket. */
+  WebSocket?: new (url: string) => WebSocketLike;
+  onOpen?: () => void;
+  onClose?: () => void;
+}
+
+interface Pending {
+  command: number;
+  resolve: (body: Uint8Array) => void;
+  reject: (err: Error) => void;
+  timer: ReturnType<typeof setTimeout>;
+}
+
+const OPEN = 1;
+
+/**
+ * Client is a websocket client of the kratos websocket transport. It matches
+ * the responses to the requests by seq, dispatches the pushes by command and
+ * answers the pings of the server.
+ */
+export class Client {
+  private ws: WebSocketLike | null = null;
+  private seq = 0;
+  private lastActive = 0;
+  private heartbeat: ReturnType<typeof setInterval> | null = null;
+  private readonly pending = new Map<number, Pending>();
+  private readonly pushes = new Map<number, (body: Uint8Array) => void>();
+
+  constructor(private readonly opts: ClientOptions) {}
+
+  /** connect opens the connection, it resolves when the connection is open. */
+  connect(): Promise<void> {
+    this.close();
+    const ctor = this.opts.WebSocket ?? (globalThis as unknown as { WebSocket: new (url: string) => WebSocketLike }).WebSocket;
+    const ws = new ctor(this.opts.url);
+    ws.binaryType = "arraybuffer";
+    this.ws = ws;
+    return new Promise((resolve, reject) => {
+      ws.onopen = () => {
+        this.lastActive = Date.now();
+        this.startHeartbeat();
+        this.opts.onOpen?.();
+        resolve();
+      };
+      ws.onerror = () => reject(new Error(`websocket: failed to connect ${this.opts.url}`));
+      ws.onclose = () => {
+        if (this.ws === ws) {
+          this.shutdown();
+          this.opts.onClose?.();
+        }
+      };
+      ws.onmessage = (ev) => this.dispatch(ev.data);
+    });
+  }
+
+  /** isAlive reports whether the connection is open. */
+  isAlive(): boolean {
+    return this.ws !== null && this.ws.readyState === OPEN;
+  }
+
+  /** close closes the connection and rejects the pending requests. */
+  close(): void {
+    const ws = this.ws;
+    if (ws === null) {
+      return;
+    }
+    this.shutdown();
+    ws.close();
+  }
+
+  /** call sends req as command and resolves the decoded response. */
+  call<Req, Rsp>(command: number, req: Req, reqType: MessageType<Req>, rspType: MessageType<Rsp>, timeout?: number): Promise<Rsp> {
+    return this.request(command, reqType.encode(req).finish(), timeout).then((body) => rspType.decode(body));
+  }
+
+  /** request sends body as command and resolves the body of the response. */
+  request(command: number, body: Uint8Array, timeout?: number): Promise<Uint8Array> {
+    const ws = this.ws;
+    if (ws === null || ws.readyState !== OPEN) {
+      return Promise.reject(new Error("websocket: session not established"));
+    }
+    this.seq = this.seq >= 0x7ffffffe ? 1 : this.seq + 1;
+    const seq = this.seq;
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => {
+        this.pending.delete(seq);
+        reject(new Error(`websocket: command ${command} timed out`));
+      }, timeout ?? this.opts.timeout ?? 2000);
+      this.pending.set(seq, { command, resolve, reject, timer });
+      ws.send(encodePayload({ op: Op.Request, place: PlaceClient, seq, command, body }));
+    });
+  }
+
+  /** onPush subscribes fn to the pushes of command, it returns the unsubscribe function. */
+  onPush<T>(command: number, type: MessageType<T>, fn: (msg: T) => void): () => void {
+    const handler = (body: Uint8Array) => fn(type.decode(body));
+    this.pushes.set(command, handler);
+    return () => {
+      if (this.pushes.get(command) === handler) {
+        this.pushes.delete(command);
+      }
+    };
+  }
+
+  private dispatch(data: unknown): void {
+    this.lastActive = Date.now();
+    if (!(data instanceof ArrayBuffer) && !ArrayBuffer.isView(data)) {
+      return;
+    }
+    const bytes = data instanceof ArrayBuffer ? new Uint8Array(data) : new Uint8Array(data.buffer, data.byteOffset, data.byteLength);
+    const p = decodePayload(bytes);
+    switch (p.op) {
+      case Op.Response: {
+        const pending = this.pending.get(p.seq);
+        if (pending === undefined) {
+          return;
+        }
+        this.pending.delete(p.seq);
+        clearTimeout(pending.timer);
+        if (p.code !== 0) {
+          pending.reject(new SocketError(p.code, pending.command, p.error));
+        } else {
+          pending.resolve(p.body);
+        }
+        return;
+      }
+      case Op.Push: {
+        const handler = this.pushes.get(p.command);
+        if (handler !== undefined) {
+          try {
+            handler(p.body);
+          } catch (err) {
+            console.warn(`websocket: push ${p.command} handler failed`, err);
+          }
+        }
+        return;
+      }
+      case Op.Ping:
+        this.ws?.send(encodePayload({ op: Op.Pong }));
+        return;
+    }
+  }
+
+  private startHeartbeat(): void {
+    const interval = this.opts.pingInterval ?? 15000;
+    const deadline = this.opts.readDeadline ?? 60000;
+    this.heartbeat = setInterval(() => {
+      if (Date.now() - this.lastActive > deadline) {
+        this.close();
+        this.opts.onClose?.();
+        return;
+      }
+      this.ws?.send(encodePayload({ op: Op.Ping }));
+    }, interval);
+  }
+
+  private shutdown(): void {
+    if (this.heartbeat !== null) {
+      clearInterval(this.heartbeat);
+      this.heartbeat = null;
+    }
+    this.ws = null;
+    const err = new Error("websocket: session closed");
+    for (const pending of this.pending.values()) {
+      clearTimeout(pending.timer);
+      pending.reject(err);
+    }
+    this.pending.clear();
+  }
+}
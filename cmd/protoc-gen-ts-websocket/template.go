@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"strings"
+	"text/template"
+)
+
+//go:embed clientTemplate.tpl
+var clientTemplate string
+
+//go:embed runtime.ts
+var runtime string
+
+type serviceDesc struct {
+	ServiceType string // Greeter
+	ServiceName string // helloworld.Greeter
+	Methods     []*methodDesc
+	Pushes      []*pushDesc
+	Push        bool // (kratos.socket.push) service, Methods are the pushes
+}
+
+type methodDesc struct {
+	Name    string // Login, the name of the command constant
+	Func    string // login, or onLogin for the methods of a push service
+	Request string
+	Reply   string
+	Comment string
+	Ops     string
+}
+
+type pushDesc struct {
+	Func    string // onUserInfoPush
+	Message string // UserInfoPush
+	Ops     string // Command id of the GameCommand value
+}
+
+func (s *serviceDesc) execute() string {
+	buf := new(bytes.Buffer)
+	tmpl, err := template.New("ts-websocket").Parse(strings.TrimSpace(clientTemplate))
+	if err != nil {
+		panic(err)
+	}
+	if err := tmpl.Execute(buf, s); err != nil {
+		panic(err)
+	}
+	return strings.Trim(buf.String(), "\r\n")
+}
@@ -0,0 +1,54 @@
+// Code generated by protoc-gen-ts-websocket. DO NOT EDIT.
+// versions:
+// - protoc-gen-ts-websocket v2.8.8
+// - protoc                  v3.21.12
+// source: demo/demo.proto
+
+/* eslint-disable */
+
+import { Client } from "../kratos_websocket";
+import { ChatPush, LoginReq, LoginRsp, Match_Req, UserInfoPush } from "./demo";
+import { Empty } from "../google/protobuf/empty";
+
+/** LobbyCommand holds the command ids of demo.Lobby. */
+export const LobbyCommand = {
+  Login: 1001,
+  Match: 2001,
+} as const;
+
+/** LobbyWebsocketClient is the client API for demo.Lobby service. */
+export class LobbyWebsocketClient {
+  constructor(private readonly client: Client) {}
+
+  /**
+   * Login logs in the player,
+   * it's the first request of a session.
+   */
+  login(req: LoginReq, timeout?: number): Promise<LoginRsp> {
+    return this.client.call(LobbyCommand.Login, req, LoginReq, LoginRsp, timeout);
+  }
+
+  match(req: Match_Req, timeout?: number): Promise<Empty> {
+    return this.client.call(LobbyCommand.Match, req, Match_Req, Empty, timeout);
+  }
+
+  /** onChatPush subscribes fn to the ChatPush pushes. */
+  onChatPush(fn: (msg: ChatPush) => void): () => void {
+    return this.client.onPush(3001, ChatPush, fn);
+  }
+}
+
+/** TablePushCommand holds the command ids of demo.TablePush. */
+export const TablePushCommand = {
+  UserInfo: 3002,
+} as const;
+
+/** TablePushWebsocketClient is the client API for demo.TablePush service. */
+export class TablePushWebsocketClient {
+  constructor(private readonly client: Client) {}
+
+  /** onUserInfo subscribes fn to the UserInfo pushes. */
+  onUserInfo(fn: (msg: UserInfoPush) => void): () => void {
+    return this.client.onPush(TablePushCommand.UserInfo, UserInfoPush, fn);
+  }
+}
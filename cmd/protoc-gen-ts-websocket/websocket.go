@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+// runtimeFile is the client runtime shared by the generated clients, at the
+// root of the output directory.
+const runtimeFile = "kratos_websocket.ts"
+
+// generateRuntime generates the client runtime, the Payload envelope, the
+// request/response matching, the push dispatching and the heartbeat.
+func generateRuntime(gen *protogen.Plugin) {
+	g := gen.NewGeneratedFile(runtimeFile, "")
+	header(gen, g, "")
+	g.P(strings.TrimSpace(runtime))
+}
+
+// generateFile generates a _websocket.ts file containing the websocket clients
+// of the services of file, nil if it has no websocket methods.
+func generateFile(gen *protogen.Plugin, file *protogen.File, commands map[*protogen.Method]int32) *protogen.GeneratedFile {
+	var services []*serviceDesc
+	imports := newImporter()
+	for _, service := range file.Services {
+		if sd := genService(file, service, commands, imports); sd != nil {
+			services = append(services, sd)
+		}
+	}
+	if len(services) == 0 {
+		return nil
+	}
+	filename := strings.TrimSuffix(file.Desc.Path(), ".proto") + "_websocket.ts"
+	g := gen.NewGeneratedFile(filename, "")
+	header(gen, g, file.Desc.Path())
+	g.P(`import { Client } from "`, importPath(path.Dir(filename), runtimeFile), `";`)
+	imports.print(g, path.Dir(filename))
+	for _, sd := range services {
+		g.P()
+		g.P(sd.execute())
+	}
+	return g
+}
+
+func header(gen *protogen.Plugin, g *protogen.GeneratedFile, source string) {
+	g.P("// Code generated by protoc-gen-ts-websocket. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P(fmt.Sprintf("// - protoc-gen-ts-websocket %s", release))
+	g.P("// - protoc                  ", protocVersion(gen))
+	if source != "" {
+		g.P("// source: ", source)
+	}
+	g.P()
+	g.P("/* eslint-disable */")
+	g.P()
+}
+
+func genService(file *protogen.File, service *protogen.Service, commands map[*protogen.Method]int32, imports *importer) *serviceDesc {
+	sd := &serviceDesc{
+		ServiceType: service.GoName,
+		ServiceName: string(service.Desc.FullName()),
+		Push:        socket.IsPushService(service),
+	}
+	for _, method := range service.Methods {
+		ops, ok := commands[method]
+		if !ok || (!sd.Push && !onWebsocket(method)) {
+			continue
+		}
+		md := &methodDesc{
+			Name:    method.GoName,
+			Func:    lowerFirst(method.GoName),
+			Request: imports.name(method.Input),
+			Ops:     strconv.Itoa(int(ops)),
+		}
+		if sd.Push {
+			md.Func = "on" + method.GoName
+		} else {
+			md.Reply = imports.name(method.Output)
+		}
+		md.Comment = methodComment(md.Func, method)
+		sd.Methods = append(sd.Methods, md)
+	}
+	if len(sd.Methods) == 0 {
+		return nil
+	}
+	for _, p := range socket.Subscriptions(file, service, commands, onWebsocket) {
+		sd.Pushes = append(sd.Pushes, &pushDesc{
+			Func:    "on" + p.Message.GoIdent.GoName,
+			Message: imports.name(p.Message),
+			Ops:     strconv.Itoa(int(p.Ops)),
+		})
+	}
+	return sd
+}
+
+// methodComment returns the comment of m as the JSDoc of name.
+func methodComment(name string, m *protogen.Method) string {
+	text := strings.TrimSpace(m.Comments.Leading.String() + m.Comments.Trailing.String())
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "//"))
+	}
+	if first := lines[0]; !strings.HasPrefix(first, m.GoName+" ") && !strings.HasPrefix(first, name+" ") {
+		lines[0] = name + " " + first
+	}
+	if len(lines) == 1 {
+		return "/** " + lines[0] + " */"
+	}
+	return "/**\n   * " + strings.Join(lines, "\n   * ") + "\n   */"
+}
+
+// importer names the messages used by the clients of a file, the messages of
+// another file are imported from the module generated for it, e.g. by ts-proto.
+type importer struct {
+	names  map[protoreflect.FullName]string
+	byFile map[string]map[string]string // proto file -> message -> local name
+	taken  map[string]protoreflect.FullName
+}
+
+func newImporter() *importer {
+	return &importer{
+		names:  make(map[protoreflect.FullName]string),
+		byFile: make(map[string]map[string]string),
+		taken:  make(map[string]protoreflect.FullName),
+	}
+}
+
+// name returns the local name of m, the name of ts-proto, e.g. Outer_Inner
+// for a nested message, aliased when two files define the same name.
+func (im *importer) name(m *protogen.Message) string {
+	full := m.Desc.FullName()
+	if n, ok := im.names[full]; ok {
+		return n
+	}
+	source := m.Desc.ParentFile().Path()
+	exported := messageName(m.Desc)
+	local := exported
+	if other, ok := im.taken[local]; ok && other != full {
+		local = identifier(strings.TrimSuffix(source, ".proto")) + "_" + exported
+	}
+	im.names[full] = local
+	im.taken[local] = full
+	if im.byFile[source] == nil {
+		im.byFile[source] = make(map[string]string)
+	}
+	im.byFile[source][exported] = local
+	return local
+}
+
+func (im *importer) print(g *protogen.GeneratedFile, dir string) {
+	sources := make([]string, 0, len(im.byFile))
+	for source := range im.byFile {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		var specs []string
+		for exported, local := range im.byFile[source] {
+			if exported == local {
+				specs = append(specs, exported)
+			} else {
+				specs = append(specs, exported+" as "+local)
+			}
+		}
+		sort.Strings(specs)
+		g.P(`import { `, strings.Join(specs, ", "), ` } from "`, importPath(dir, strings.TrimSuffix(source, ".proto")), `";`)
+	}
+}
+
+// messageName returns the name of the message in its package, with _ between
+// the names of nested messages.
+func messageName(d protoreflect.MessageDescriptor) string {
+	name := string(d.FullName())
+	if pkg := string(d.ParentFile().Package()); pkg != "" {
+		name = strings.TrimPrefix(name, pkg+".")
+	}
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// importPath returns the relative import of target, a file without the .ts
+// extension, from dir.
+func importPath(dir, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(strings.TrimSuffix(target, ".ts")))
+	if err != nil {
+		panic(err)
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel + *importSuffix
+}
+
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func protocVersion(gen *protogen.Plugin) string {
+	v := gen.Request.GetCompilerVersion()
+	if v == nil {
+		return "(unknown)"
+	}
+	var suffix string
+	if s := v.GetSuffix(); s != "" {
+		suffix = "-" + s
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.GetMajor(), v.GetMinor(), v.GetPatch(), suffix)
+}
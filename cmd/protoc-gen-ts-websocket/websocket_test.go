@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/yola1107/kratos/cmd/protoc-gen-go-socket/v2/socket"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func method(name, input, output string, cmd int32, transports ...socket.Transport) *descriptorpb.MethodDescriptorProto {
+	m := &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(input),
+		OutputType: proto.String(output),
+		Options:    &descriptorpb.MethodOptions{},
+	}
+	if cmd != 0 {
+		proto.SetExtension(m.Options, socket.E_Cmd, cmd)
+	}
+	if len(transports) != 0 {
+		proto.SetExtension(m.Options, socket.E_Transport, transports)
+	}
+	return m
+}
+
+// demoRequest returns the request of demo/demo.proto, a Lobby service with a
+// GNET only method, a TablePush service and GameCommand pushes.
+func demoRequest() *pluginpb.CodeGeneratorRequest {
+	push := &descriptorpb.ServiceDescriptorProto{
+		Name:    proto.String("TablePush"),
+		Options: &descriptorpb.ServiceOptions{},
+		Method:  []*descriptorpb.MethodDescriptorProto{method("UserInfo", ".demo.UserInfoPush", ".google.protobuf.Empty", 3002)},
+	}
+	proto.SetExtension(push.Options, socket.E_Push, true)
+	f := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("demo/demo.proto"),
+		Package:    proto.String("demo"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"kratos/socket/socket.proto", "google/protobuf/empty.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String(socket.GameCommandEnum),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("Nothing"), Number: proto.Int32(0)},
+				{Name: proto.String("Login"), Number: proto.Int32(1001)},
+				{Name: proto.String("OnChatPush"), Number: proto.Int32(3001)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("LoginReq")},
+			{Name: proto.String("LoginRsp")},
+			{Name: proto.String("Match"), NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Req")}}},
+			{Name: proto.String("ChatPush")},
+			{Name: proto.String("UserInfoPush")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Lobby"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					method("Login", ".demo.LoginReq", ".demo.LoginRsp", 0),
+					method("Match", ".demo.Match.Req", ".google.protobuf.Empty", 2001),
+					method("Kick", ".demo.LoginReq", ".google.protobuf.Empty", 2002, socket.Transport_GNET),
+				},
+			},
+			push,
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{Location: []*descriptorpb.SourceCodeInfo_Location{{
+			Path:            []int32{6, 0, 2, 0},
+			Span:            []int32{20, 2, 40},
+			LeadingComments: proto.String(" Login logs in the player,\n it's the first request of a session.\n"),
+		}}},
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:  []string{f.GetName()},
+		CompilerVersion: &pluginpb.Version{Major: proto.Int32(3), Minor: proto.Int32(21), Patch: proto.Int32(12)},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto),
+			protodesc.ToFileDescriptorProto(socket.File_kratos_socket_socket_proto),
+			f,
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	gen, err := newPlugin(demoRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = generate(gen); err != nil {
+		t.Fatal(err)
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	if len(resp.File) != 2 {
+		t.Fatalf("got %d files, want 2", len(resp.File))
+	}
+	for _, f := range resp.File {
+		golden := filepath.Join("testdata", filepath.FromSlash(f.GetName()))
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(golden, []byte(f.GetContent()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.GetContent() != string(want) {
+			t.Errorf("%s differs from %s, run go test -update to update it\n%s", f.GetName(), golden, f.GetContent())
+		}
+	}
+}
+
+func TestNoWebsocketMethods(t *testing.T) {
+	req := demoRequest()
+	f := req.ProtoFile[len(req.ProtoFile)-1]
+	f.Service = f.Service[:1]
+	f.Service[0].Method = f.Service[0].Method[2:] // Kick is GNET only
+	gen, err := newPlugin(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := socket.ResolveCommands(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generateFile(gen, gen.Files[len(gen.Files)-1], commands) != nil {
+		t.Fatal("generateFile should skip a file without websocket methods")
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	tests := []struct {
+		dir, target, want string
+	}{
+		{".", "kratos_websocket.ts", "./kratos_websocket"},
+		{"demo", "kratos_websocket.ts", "../kratos_websocket"},
+		{"demo", "demo/demo", "./demo"},
+		{"api/demo", "google/protobuf/empty", "../../google/protobuf/empty"},
+	}
+	for _, tt := range tests {
+		if got := importPath(tt.dir, tt.target); got != tt.want {
+			t.Errorf("importPath(%q, %q) = %q, want %q", tt.dir, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package work
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yola1107/kratos/v2/log"
+)
+
+const defaultMailboxSize = 1024 // 默认邮箱容量
+
+var (
+	// ErrActorStopped Actor 已停止，任务未提交
+	ErrActorStopped = errors.New("work: actor stopped")
+	// ErrMailboxFull 邮箱已满，任务被拒绝或被丢弃
+	ErrMailboxFull = errors.New("work: actor mailbox full")
+	// ErrSelfPost Actor 的任务向自身的满邮箱阻塞提交，等待会死锁，任务被拒绝
+	ErrSelfPost = errors.New("work: actor posts to its own full mailbox")
+)
+
+// OverflowPolicy 邮箱满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞等待邮箱空位，带 ctx 的提交在 ctx 结束时放弃，
+	// Actor 的任务向自身的满邮箱提交时返回 ErrSelfPost
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject 拒绝新任务
+	OverflowReject
+	// OverflowDropOldest 丢弃最早排队的任务，为新任务腾出空位
+	OverflowDropOldest
+)
+
+// Actor 串行执行器（SerialExecutor）
+// 任务进入邮箱后由同一个协程按提交顺序（FIFO）逐个执行，同一 Actor 的任务不会并发，
+// 桌子等状态只在 Actor 中访问时无需加锁。Actor 实现了 Loop，可替换 ants 协程池。
+// 注意：不要在 Actor 的任务中调用同一 Actor 的 PostAndWait 或 Stop，会死锁
+type Actor interface {
+	Loop
+
+	// Len 返回邮箱中排队的任务数
+	Len() int
+}
+
+// ActorOption Actor 选项
+type ActorOption func(*actor)
+
+// WithMailboxSize 设置邮箱容量
+// size 必须大于 0，否则使用默认值 defaultMailboxSize
+func WithMailboxSize(size int) ActorOption {
+	return func(a *actor) {
+		if size > 0 {
+			a.size = size
+		} else {
+			log.Warnf("Invalid mailbox size %d, using default %d", size, defaultMailboxSize)
+		}
+	}
+}
+
+// WithOverflow 设置邮箱满时的处理策略，默认 OverflowBlock
+func WithOverflow(policy OverflowPolicy) ActorOption {
+	return func(a *actor) {
+		a.overflow = policy
+	}
+}
+
+// task 邮箱中的任务
+type task struct {
+	ctx  context.Context
+	fn   func()
+	drop func() // 被 OverflowDropOldest 丢弃时调用，可为 nil
+}
+
+type actor struct {
+	mu       sync.RWMutex
+	size     int
+	overflow OverflowPolicy
+	mailbox  chan *task
+	started  bool
+	stopped  bool
+	senders  sync.WaitGroup // 进行中的提交，Stop 等待其退出
+	runner   atomic.Uint64  // 执行协程的 goroutine id
+	stopping chan struct{}  // 关闭后唤醒阻塞的提交方
+	quit     chan struct{}  // 关闭后执行协程处理完剩余任务退出
+	exited   chan struct{}
+}
+
+// NewActor 创建 Actor 实例，Start 之前提交的任务在 Start 后执行
+func NewActor(opts ...ActorOption) Actor {
+	a := &actor{
+		size:     defaultMailboxSize,
+		overflow: OverflowBlock,
+		stopping: make(chan struct{}),
+		quit:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.mailbox = make(chan *task, a.size)
+	return a
+}
+
+// Start 启动执行协程
+// 可安全地重复调用，后续调用会被忽略；停止后不能再启动
+func (a *actor) Start() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stopped {
+		return ErrActorStopped
+	}
+	if a.started {
+		log.Warnf("actor already started, ignoring duplicate Start() call")
+		return nil
+	}
+	a.started = true
+	go a.run()
+	return nil
+}
+
+// Stop 停止接收任务，执行完邮箱中剩余的任务后返回
+func (a *actor) Stop() {
+	a.mu.Lock()
+	first := !a.stopped
+	a.stopped = true
+	if first && !a.started {
+		a.started = true
+		go a.run()
+	}
+	a.mu.Unlock()
+
+	if first {
+		// 先唤醒阻塞在满邮箱上的提交方，等待所有提交方退出后再通知执行协程
+		close(a.stopping)
+		a.senders.Wait()
+		close(a.quit)
+	}
+	<-a.exited
+}
+
+// Monitor 返回邮箱状态
+// Capacity 为邮箱容量，Running 为排队中的任务数，Free 为剩余空位
+func (a *actor) Monitor() LoopMonitor {
+	n := len(a.mailbox)
+	return LoopMonitor{
+		Capacity: cap(a.mailbox),
+		Running:  n,
+		Free:     cap(a.mailbox) - n,
+	}
+}
+
+// Len 返回邮箱中排队的任务数
+func (a *actor) Len() int {
+	return len(a.mailbox)
+}
+
+// Post 提交无返回任务，使用 background context
+func (a *actor) Post(job func()) {
+	a.PostCtx(context.Background(), job)
+}
+
+// PostCtx 提交无返回任务，携带上下文
+// 如果 ctx 已取消，任务不会被提交；执行前 ctx 已取消的任务会被跳过
+func (a *actor) PostCtx(ctx context.Context, job func()) {
+	if ctx.Err() != nil {
+		return
+	}
+	if err := a.submit(&task{ctx: ctx, fn: job}); err != nil {
+		log.Warnf("actor post failed. err=%v", err)
+	}
+}
+
+// PostAndWait 提交有返回结果任务，阻塞等待结果，使用 background context
+func (a *actor) PostAndWait(job func() ([]byte, error)) ([]byte, error) {
+	return a.PostAndWaitCtx(context.Background(), job)
+}
+
+// PostAndWaitCtx 提交有返回结果任务，阻塞等待结果或 ctx 结束
+// ctx 结束时仍在排队的任务不会再执行
+func (a *actor) PostAndWaitCtx(ctx context.Context, job func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("canceled: %w", err)
+	}
+	ch := make(chan *asyncResult, 1)
+	t := &task{
+		ctx: ctx,
+		fn: func() {
+			defer RecoverFromError(func(e any) {
+				ch <- &asyncResult{nil, fmt.Errorf("panic: %v", e)}
+			})
+			data, err := job()
+			ch <- &asyncResult{data, err}
+		},
+		drop: func() {
+			ch <- &asyncResult{nil, ErrMailboxFull}
+		},
+	}
+	if err := a.submit(t); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res.data, res.err
+	case <-ctx.Done():
+		select {
+		case res := <-ch:
+			return res.data, res.err
+		default:
+			return nil, fmt.Errorf("canceled: %w", ctx.Err())
+		}
+	}
+}
+
+// submit 按溢出策略将任务放入邮箱，阻塞等待时不持有锁
+func (a *actor) submit(t *task) error {
+	a.mu.RLock()
+	if a.stopped {
+		a.mu.RUnlock()
+		return ErrActorStopped
+	}
+	a.senders.Add(1)
+	a.mu.RUnlock()
+	defer a.senders.Done()
+
+	switch a.overflow {
+	case OverflowReject:
+		select {
+		case a.mailbox <- t:
+			return nil
+		default:
+			return ErrMailboxFull
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case a.mailbox <- t:
+				return nil
+			default:
+			}
+			select {
+			case old := <-a.mailbox:
+				log.Warnf("actor mailbox full, dropping the oldest job")
+				if old.drop != nil {
+					old.drop()
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case a.mailbox <- t:
+			return nil
+		default:
+		}
+		// 执行协程阻塞在自身的邮箱上将永远等待
+		if goid() == a.runner.Load() {
+			return ErrSelfPost
+		}
+		select {
+		case a.mailbox <- t:
+			return nil
+		case <-t.ctx.Done():
+			return fmt.Errorf("canceled: %w", t.ctx.Err())
+		case <-a.stopping:
+			return ErrActorStopped
+		}
+	}
+}
+
+// run 执行协程，逐个执行邮箱中的任务，停止时执行完剩余任务后退出
+func (a *actor) run() {
+	defer close(a.exited)
+	a.runner.Store(goid())
+	for {
+		select {
+		case t := <-a.mailbox:
+			a.exec(t)
+		case <-a.quit:
+			for {
+				select {
+				case t := <-a.mailbox:
+					a.exec(t)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// exec 执行任务，隔离 panic，跳过 ctx 已取消的任务
+func (a *actor) exec(t *task) {
+	defer RecoverFromError(nil)
+	if t.ctx.Err() == nil {
+		t.fn()
+	}
+}
+
+// goid 返回当前协程的 id，只在提交需要阻塞时调用
+func goid() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// ActorGroup 固定数量的 Actor 分片
+// 按 key（如桌子ID）将任务映射到其中一个 Actor，同一 key 的任务总是串行执行，
+// 不同 key 可能共享同一个 Actor，成千上万张桌子只占用固定数量的协程
+type ActorGroup struct {
+	actors []Actor
+}
+
+// NewActorGroup 创建包含 size 个 Actor 的分片组，opts 应用于每个 Actor
+// size 必须大于 0，否则为 1
+func NewActorGroup(size int, opts ...ActorOption) *ActorGroup {
+	if size <= 0 {
+		log.Warnf("Invalid actor group size %d, using 1", size)
+		size = 1
+	}
+	g := &ActorGroup{actors: make([]Actor, size)}
+	for i := range g.actors {
+		g.actors[i] = NewActor(opts...)
+	}
+	return g
+}
+
+// Start 启动所有 Actor
+func (g *ActorGroup) Start() error {
+	for _, a := range g.actors {
+		if err := a.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop 停止所有 Actor，等待剩余任务执行完毕
+func (g *ActorGroup) Stop() {
+	var wg sync.WaitGroup
+	for _, a := range g.actors {
+		wg.Add(1)
+		go func(a Actor) {
+			defer wg.Done()
+			a.Stop()
+		}(a)
+	}
+	wg.Wait()
+}
+
+// Size 返回 Actor 数量
+func (g *ActorGroup) Size() int {
+	return len(g.actors)
+}
+
+// Get 返回 key 对应的 Actor
+func (g *ActorGroup) Get(key int64) Actor {
+	return g.actors[uint64(key)%uint64(len(g.actors))]
+}
+
+// Post 向 key 对应的 Actor 提交无返回任务
+func (g *ActorGroup) Post(key int64, job func()) {
+	g.Get(key).Post(job)
+}
+
+// PostAndWaitCtx 向 key 对应的 Actor 提交有返回结果任务并等待结果
+func (g *ActorGroup) PostAndWaitCtx(ctx context.Context, key int64, job func() ([]byte, error)) ([]byte, error) {
+	return g.Get(key).PostAndWaitCtx(ctx, job)
+}
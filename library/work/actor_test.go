@@ -0,0 +1,283 @@
+package work
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockActor 阻塞 Actor 的执行协程，直到返回的函数被调用
+func blockActor(t *testing.T, a Actor) func() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	a.Post(func() {
+		close(started)
+		<-release
+	})
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("blocking job not started")
+	}
+	return func() { close(release) }
+}
+
+func TestActor(t *testing.T) {
+	a := NewActor(WithMailboxSize(16))
+	require.NoError(t, a.Start())
+	defer a.Stop()
+
+	t.Run("FIFO and one at a time", func(t *testing.T) {
+		var (
+			order  []int
+			active atomic.Int32
+			wg     sync.WaitGroup
+		)
+		for i := 0; i < 1000; i++ {
+			i := i
+			wg.Add(1)
+			a.Post(func() {
+				defer wg.Done()
+				if active.Add(1) != 1 {
+					t.Error("jobs run concurrently")
+				}
+				order = append(order, i)
+				active.Add(-1)
+			})
+		}
+		wg.Wait()
+		for i, v := range order {
+			if v != i {
+				t.Fatalf("expect job %d at %d, got %d", i, i, v)
+			}
+		}
+	})
+
+	t.Run("PostAndWait returns expected value", func(t *testing.T) {
+		val, err := a.PostAndWait(func() ([]byte, error) {
+			return []byte("hello"), nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), val)
+	})
+
+	t.Run("panic is isolated", func(t *testing.T) {
+		a.Post(func() { panic("oops") })
+		_, err := a.PostAndWait(func() ([]byte, error) {
+			panic("oops2")
+		})
+		require.ErrorContains(t, err, "panic: oops2")
+		val, err := a.PostAndWait(func() ([]byte, error) {
+			return []byte("alive"), nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []byte("alive"), val)
+	})
+
+	t.Run("canceled job is skipped", func(t *testing.T) {
+		release := blockActor(t, a)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var ran atomic.Bool
+		_, err := a.PostAndWaitCtx(ctx, func() ([]byte, error) {
+			ran.Store(true)
+			return nil, nil
+		})
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+		release()
+		_, err = a.PostAndWait(func() ([]byte, error) { return nil, nil })
+		require.NoError(t, err)
+		require.False(t, ran.Load())
+	})
+}
+
+func TestActorOverflow(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		a := NewActor(WithMailboxSize(1), WithOverflow(OverflowReject))
+		require.NoError(t, a.Start())
+		defer a.Stop()
+		release := blockActor(t, a)
+		defer release()
+
+		a.Post(func() {})
+		require.Equal(t, 1, a.Len())
+		_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+		require.ErrorIs(t, err, ErrMailboxFull)
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		a := NewActor(WithMailboxSize(1), WithOverflow(OverflowDropOldest))
+		require.NoError(t, a.Start())
+		defer a.Stop()
+		release := blockActor(t, a)
+
+		dropped := make(chan error, 1)
+		go func() {
+			_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+			dropped <- err
+		}()
+		require.Eventually(t, func() bool { return a.Len() == 1 }, time.Second, time.Millisecond)
+		done := make(chan struct{})
+		a.Post(func() { close(done) })
+		select {
+		case err := <-dropped:
+			require.ErrorIs(t, err, ErrMailboxFull)
+		case <-time.After(time.Second):
+			t.Fatal("oldest job not dropped")
+		}
+		release()
+		<-done
+	})
+
+	t.Run("block until ctx done", func(t *testing.T) {
+		a := NewActor(WithMailboxSize(1))
+		require.NoError(t, a.Start())
+		defer a.Stop()
+		release := blockActor(t, a)
+		defer release()
+
+		a.Post(func() {})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := a.PostAndWaitCtx(ctx, func() ([]byte, error) { return nil, nil })
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("self post", func(t *testing.T) {
+		a := NewActor(WithMailboxSize(1))
+		require.NoError(t, a.Start())
+		defer a.Stop()
+
+		// 任务向自身的满邮箱提交时失败，不会死锁
+		var ran atomic.Int32
+		errc := make(chan error, 1)
+		a.Post(func() {
+			a.Post(func() { ran.Add(1) })
+			_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+			errc <- err
+		})
+		select {
+		case err := <-errc:
+			require.ErrorIs(t, err, ErrSelfPost)
+		case <-time.After(time.Second):
+			t.Fatal("self post deadlocked")
+		}
+		require.Eventually(t, func() bool { return ran.Load() == 1 }, time.Second, time.Millisecond)
+	})
+}
+
+func TestActorStop(t *testing.T) {
+	a := NewActor(WithMailboxSize(1))
+	var count atomic.Int32
+	// Start 之前提交的任务在 Start 后执行，Stop 执行完剩余任务
+	a.Post(func() { count.Add(1) })
+	require.NoError(t, a.Start())
+	release := blockActor(t, a)
+	a.Post(func() { count.Add(1) })
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+		blocked <- err
+	}()
+	stopped := make(chan struct{})
+	go func() {
+		a.Stop()
+		close(stopped)
+	}()
+	select {
+	case err := <-blocked:
+		require.ErrorIs(t, err, ErrActorStopped)
+	case <-time.After(time.Second):
+		t.Fatal("blocked post not woken by Stop")
+	}
+	release()
+	<-stopped
+	require.Equal(t, int32(2), count.Load())
+
+	a.Stop()
+	require.ErrorIs(t, a.Start(), ErrActorStopped)
+	_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+	require.ErrorIs(t, err, ErrActorStopped)
+}
+
+func TestActorStopBlockedPosts(t *testing.T) {
+	a := NewActor(WithMailboxSize(1))
+	require.NoError(t, a.Start())
+	release := blockActor(t, a)
+	a.Post(func() {})
+
+	// 阻塞的提交不持有锁，Stop 唤醒全部提交方后返回
+	const n = 8
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := a.PostAndWait(func() ([]byte, error) { return nil, nil })
+			errs <- err
+		}()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		a.Stop()
+		close(stopped)
+	}()
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errs:
+			require.ErrorIs(t, err, ErrActorStopped)
+		case <-time.After(time.Second):
+			t.Fatal("blocked post not woken by Stop")
+		}
+	}
+	require.Equal(t, 1, a.Len())
+	release()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop not returned")
+	}
+	require.Equal(t, 0, a.Len())
+}
+
+func TestActorGroup(t *testing.T) {
+	g := NewActorGroup(4)
+	require.NoError(t, g.Start())
+	defer g.Stop()
+
+	require.Equal(t, 4, g.Size())
+	require.Same(t, g.Get(7), g.Get(11))
+	require.NotSame(t, g.Get(7), g.Get(8))
+	require.NotNil(t, g.Get(-1))
+
+	// 每张桌子的计数只在其 Actor 中修改，无需加锁
+	const tables, jobs = 1000, 20
+	counts := make([]int, tables)
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		for id := 0; id < tables; id++ {
+			id := id
+			wg.Add(1)
+			g.Post(int64(id), func() {
+				defer wg.Done()
+				counts[id]++
+			})
+		}
+	}
+	wg.Wait()
+	for id, n := range counts {
+		if n != jobs {
+			t.Fatalf("table %d expect %d jobs, got %d", id, jobs, n)
+		}
+	}
+
+	val, err := g.PostAndWaitCtx(context.Background(), 3, func() ([]byte, error) {
+		return []byte("table 3"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("table 3"), val)
+}
@@ -39,7 +39,7 @@ func setClientSpan(ctx context.Context, span trace.Span, m any) {
 				attrs = append(attrs, semconv.HTTPTargetKey.String(path))
 				remote = ht.Request().Host
 			}
-		case transport.KindGRPC:
+		case transport.KindGRPC, transport.KindGNet:
 			remote, _ = parseTarget(tr.Endpoint())
 		}
 	}
@@ -82,6 +82,10 @@ func setServerSpan(ctx context.Context, span trace.Span, m any) {
 			if p, ok := peer.FromContext(ctx); ok {
 				remote = p.Addr.String()
 			}
+		case transport.KindGNet:
+			if pt, ok := tr.(interface{ RemoteAddr() net.Addr }); ok && pt.RemoteAddr() != nil {
+				remote = pt.RemoteAddr().String()
+			}
 		}
 	}
 	attrs = append(attrs, semconv.RPCSystemKey.String(rpcKind))
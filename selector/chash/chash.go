@@ -0,0 +1,246 @@
+package chash
+
+import (
+	"context"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/selector/node/direct"
+	"github.com/yola1107/kratos/v2/transport"
+)
+
+const (
+	// Name is chash(Consistent Hash) balancer name
+	Name = "chash"
+
+	defaultReplicas   = 160
+	defaultLoadFactor = 1.25
+)
+
+var _ selector.Balancer = (*Balancer)(nil)
+
+type keyContext struct{}
+
+// NewKeyContext returns a new context carrying the hash key, e.g. a player or table id.
+func NewKeyContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContext{}, key)
+}
+
+// FromKeyContext returns the hash key stored in ctx.
+func FromKeyContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyContext{}).(string)
+	return key, ok && key != ""
+}
+
+// Option is chash builder option.
+type Option func(o *options)
+
+// options is chash builder options
+type options struct {
+	replicas   int
+	header     string
+	loadFactor float64
+}
+
+// WithReplicas with the virtual nodes per node, default is 160.
+func WithReplicas(n int) Option {
+	return func(o *options) { o.replicas = n }
+}
+
+// WithHeader with the request header the key is read from when the
+// context carries none.
+func WithHeader(key string) Option {
+	return func(o *options) { o.header = key }
+}
+
+// WithLoadFactor with the bounded load factor, a node takes at most
+// ceil(factor * average in-flight requests). A factor below 1 disables the bound.
+func WithLoadFactor(f float64) Option {
+	return func(o *options) { o.loadFactor = f }
+}
+
+// New creates a chash selector.
+func New(opts ...Option) selector.Selector {
+	return NewBuilder(opts...).Build()
+}
+
+// Balancer is a consistent hash balancer with bounded load.
+type Balancer struct {
+	opts options
+
+	mu    sync.Mutex
+	ring  *ring
+	loads map[string]int64
+	total int64
+}
+
+// Pick picks the node owning the key of ctx, walking the ring past nodes
+// over the load bound. Without a key a random node is picked.
+func (b *Balancer) Pick(ctx context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	if len(nodes) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	key, ok := b.key(ctx)
+	if !ok {
+		selected := nodes[rand.Intn(len(nodes))]
+		return selected, selected.Pick(), nil
+	}
+
+	b.mu.Lock()
+	r := b.ring
+	if r == nil || !r.same(nodes) {
+		r = newRing(nodes, b.opts.replicas)
+		b.ring = r
+	}
+	selected := r.nodes[r.owner(key, b.bounded(len(nodes)))]
+	addr := selected.Address()
+	b.loads[addr]++
+	b.total++
+	b.mu.Unlock()
+
+	d := selected.Pick()
+	return selected, func(ctx context.Context, di selector.DoneInfo) {
+		b.mu.Lock()
+		if b.loads[addr]--; b.loads[addr] <= 0 {
+			delete(b.loads, addr)
+		}
+		b.total--
+		b.mu.Unlock()
+		d(ctx, di)
+	}, nil
+}
+
+func (b *Balancer) key(ctx context.Context) (string, bool) {
+	if key, ok := FromKeyContext(ctx); ok {
+		return key, true
+	}
+	if b.opts.header == "" {
+		return "", false
+	}
+	if tr, ok := transport.FromClientContext(ctx); ok {
+		if key := tr.RequestHeader().Get(b.opts.header); key != "" {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// bounded returns the predicate accepting nodes under the load bound.
+func (b *Balancer) bounded(n int) func(addr string) bool {
+	if b.opts.loadFactor < 1 {
+		return func(string) bool { return true }
+	}
+	limit := int64(math.Ceil(float64(b.total+1) * b.opts.loadFactor / float64(n)))
+	return func(addr string) bool { return b.loads[addr] < limit }
+}
+
+// ring is the hash ring of a node set.
+type ring struct {
+	hashes []uint32
+	owners []int // index of nodes per hash
+	nodes  []selector.WeightedNode
+	index  map[string]int // index of nodes per address
+}
+
+func newRing(nodes []selector.WeightedNode, replicas int) *ring {
+	r := &ring{
+		hashes: make([]uint32, 0, len(nodes)*replicas),
+		owners: make([]int, 0, len(nodes)*replicas),
+		nodes:  nodes,
+		index:  make(map[string]int, len(nodes)),
+	}
+	for i, n := range nodes {
+		r.index[n.Address()] = i
+	}
+	type point struct {
+		hash  uint32
+		owner int
+	}
+	points := make([]point, 0, len(nodes)*replicas)
+	for i, n := range nodes {
+		for j := 0; j < replicas; j++ {
+			points = append(points, point{hash: hashKey(n.Address() + "#" + strconv.Itoa(j)), owner: i})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return nodes[points[i].owner].Address() < nodes[points[j].owner].Address()
+	})
+	for _, p := range points {
+		r.hashes = append(r.hashes, p.hash)
+		r.owners = append(r.owners, p.owner)
+	}
+	return r
+}
+
+// same reports whether nodes has the addresses of the ring in any order,
+// picking up the latest node instances, so only filters and rebalances
+// changing the node set rebuild the ring.
+func (r *ring) same(nodes []selector.WeightedNode) bool {
+	if len(nodes) != len(r.nodes) {
+		return false
+	}
+	latest := make([]selector.WeightedNode, len(nodes))
+	for _, n := range nodes {
+		i, ok := r.index[n.Address()]
+		if !ok || latest[i] != nil {
+			return false
+		}
+		latest[i] = n
+	}
+	r.nodes = latest
+	return true
+}
+
+// owner returns the index of the first node clockwise from key accepted by ok,
+// or the key owner when all nodes are over the bound.
+func (r *ring) owner(key string, ok func(addr string) bool) int {
+	h := hashKey(key)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for i := 0; i < len(r.hashes); i++ {
+		idx := r.owners[(start+i)%len(r.hashes)]
+		if ok(r.nodes[idx].Address()) {
+			return idx
+		}
+	}
+	return r.owners[start%len(r.hashes)]
+}
+
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// NewBuilder returns a selector builder with chash balancer
+func NewBuilder(opts ...Option) selector.Builder {
+	option := options{
+		replicas:   defaultReplicas,
+		loadFactor: defaultLoadFactor,
+	}
+	for _, opt := range opts {
+		opt(&option)
+	}
+	if option.replicas <= 0 {
+		option.replicas = defaultReplicas
+	}
+	return &selector.DefaultBuilder{
+		Balancer: &Builder{opts: option},
+		Node:     &direct.Builder{},
+	}
+}
+
+// Builder is chash builder
+type Builder struct {
+	opts options
+}
+
+// Build creates Balancer
+func (b *Builder) Build() selector.Balancer {
+	return &Balancer{opts: b.opts, loads: make(map[string]int64)}
+}
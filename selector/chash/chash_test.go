@@ -0,0 +1,170 @@
+package chash
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/selector/node/direct"
+	"github.com/yola1107/kratos/v2/transport"
+)
+
+func newNodes(n int) []selector.Node {
+	nodes := make([]selector.Node, 0, n)
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.%d:8080", i)
+		nodes = append(nodes, selector.NewNode("tcp", addr, &registry.ServiceInstance{ID: addr}))
+	}
+	return nodes
+}
+
+func pick(t *testing.T, s selector.Selector, key string) string {
+	n, done, err := s.Select(NewKeyContext(context.Background(), key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(context.Background(), selector.DoneInfo{})
+	return n.Address()
+}
+
+func TestSticky(t *testing.T) {
+	s := New()
+	s.Apply(newNodes(5))
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("table-%d", i)
+		want := pick(t, s, key)
+		for j := 0; j < 10; j++ {
+			if got := pick(t, s, key); got != want {
+				t.Fatalf("%s: expect %s, got %s", key, want, got)
+			}
+		}
+	}
+}
+
+func TestMinimalRemap(t *testing.T) {
+	s := New()
+	nodes := newNodes(4)
+	s.Apply(nodes)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("player-%d", i)
+		before[key] = pick(t, s, key)
+	}
+
+	s.Apply(append(nodes, newNodes(5)[4]))
+	moved := 0
+	for key, addr := range before {
+		got := pick(t, s, key)
+		if got == addr {
+			continue
+		}
+		moved++
+		if got != "127.0.0.4:8080" {
+			t.Errorf("%s: moved from %s to an old node %s", key, addr, got)
+		}
+	}
+	// about 1/5 of the keys move to the new node
+	if moved == 0 || moved > 350 {
+		t.Errorf("expect about 200 keys moved, got %d", moved)
+	}
+}
+
+func TestBoundedLoad(t *testing.T) {
+	s := New(WithLoadFactor(1.25))
+	s.Apply(newNodes(4))
+	counts := make(map[string]int)
+	var dones []selector.DoneFunc
+	for i := 0; i < 100; i++ {
+		n, done, err := s.Select(NewKeyContext(context.Background(), "hot-table"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[n.Address()]++
+		dones = append(dones, done)
+	}
+	for addr, c := range counts {
+		if c > 32 {
+			t.Errorf("%s: expect at most 32 in-flight, got %d", addr, c)
+		}
+	}
+	for _, done := range dones {
+		done(context.Background(), selector.DoneInfo{})
+	}
+	if len(s.(*selector.Default).Balancer.(*Balancer).loads) != 0 {
+		t.Error("expect loads released")
+	}
+}
+
+func TestHeaderKey(t *testing.T) {
+	s := New(WithHeader("x-md-table"))
+	s.Apply(newNodes(3))
+	want := pick(t, s, "42")
+	ctx := transport.NewClientContext(context.Background(), &mockTransport{header: headerCarrier{"x-md-table": "42"}})
+	for i := 0; i < 10; i++ {
+		n, done, err := s.Select(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(ctx, selector.DoneInfo{})
+		if n.Address() != want {
+			t.Errorf("expect %s, got %s", want, n.Address())
+		}
+	}
+}
+
+func TestNoKey(t *testing.T) {
+	s := New()
+	s.Apply(newNodes(3))
+	n, done, err := s.Select(context.Background())
+	if err != nil || n == nil || done == nil {
+		t.Errorf("expect a node, got %v %v", n, err)
+	}
+	if _, _, err = New().Select(context.Background()); err != selector.ErrNoAvailable {
+		t.Errorf("expect %v, got %v", selector.ErrNoAvailable, err)
+	}
+}
+
+type headerCarrier map[string]string
+
+func (h headerCarrier) Get(key string) string      { return h[key] }
+func (h headerCarrier) Set(key, value string)      { h[key] = value }
+func (h headerCarrier) Add(key, value string)      { h[key] = value }
+func (h headerCarrier) Keys() []string             { return nil }
+func (h headerCarrier) Values(key string) []string { return []string{h[key]} }
+
+type mockTransport struct {
+	transport.Transporter
+	header headerCarrier
+}
+
+func (m *mockTransport) RequestHeader() transport.Header { return m.header }
+
+func TestRingSameUnordered(t *testing.T) {
+	b := &direct.Builder{}
+	nodes := make([]selector.WeightedNode, 0, 4)
+	for _, n := range newNodes(4) {
+		nodes = append(nodes, b.Build(n))
+	}
+	r := newRing(nodes, defaultReplicas)
+	owners := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("player-%d", i)
+		owners[key] = r.nodes[r.owner(key, func(string) bool { return true })].Address()
+	}
+
+	// the same node set in another order keeps the ring
+	reversed := []selector.WeightedNode{nodes[3], nodes[2], nodes[1], nodes[0]}
+	if !r.same(reversed) {
+		t.Fatal("expect the reordered nodes the same set")
+	}
+	for key, addr := range owners {
+		if got := r.nodes[r.owner(key, func(string) bool { return true })].Address(); got != addr {
+			t.Errorf("%s: expect %s, got %s", key, addr, got)
+		}
+	}
+	if r.same([]selector.WeightedNode{nodes[0], nodes[0], nodes[1], nodes[2]}) {
+		t.Error("expect duplicated nodes a different set")
+	}
+}
@@ -0,0 +1,190 @@
+// Package sticky routes keys, e.g. players or tables, to the node they are
+// assigned to in a Store, assigning unassigned keys with a balancer pick.
+package sticky
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/selector/chash"
+	"github.com/yola1107/kratos/v2/selector/node/direct"
+	"github.com/yola1107/kratos/v2/selector/wrr"
+)
+
+// KeyFunc returns the routing key of a request.
+type KeyFunc func(ctx context.Context) (string, bool)
+
+// Option is router option.
+type Option func(*Router)
+
+// WithKey with the routing key of a request, default is the key of chash.NewKeyContext.
+func WithKey(fn KeyFunc) Option {
+	return func(r *Router) { r.key = fn }
+}
+
+// WithBalancer with the balancer assigning unassigned keys, default is wrr.
+func WithBalancer(b selector.BalancerBuilder) Option {
+	return func(r *Router) { r.balancer = b.Build() }
+}
+
+// Router keeps keys on the nodes they are assigned to.
+type Router struct {
+	store    Store
+	key      KeyFunc
+	balancer selector.Balancer
+	nodes    selector.WeightedNodeBuilder
+}
+
+// NewRouter new a sticky router.
+func NewRouter(store Store, opts ...Option) *Router {
+	r := &Router{
+		store:    store,
+		key:      chash.FromKeyContext,
+		balancer: (&wrr.Builder{}).Build(),
+		nodes:    &direct.Builder{},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Filter returns the node filter narrowing nodes to the node of the request
+// key. Requests without a key, and store failures, leave nodes untouched.
+// A key assigned to a node missing from nodes, e.g. dropped by another filter,
+// is routed to a balancer pick without reassigning it, the assignments of the
+// nodes removed from the registry are cleared by Watch.
+func (r *Router) Filter() selector.NodeFilter {
+	return func(ctx context.Context, nodes []selector.Node) []selector.Node {
+		key, ok := r.key(ctx)
+		if !ok || len(nodes) == 0 {
+			return nodes
+		}
+		n, err := r.route(ctx, key, nodes)
+		if err != nil {
+			log.Errorf("[sticky] route key %s failed: %v", key, err)
+			return nodes
+		}
+		return []selector.Node{n}
+	}
+}
+
+func (r *Router) route(ctx context.Context, key string, nodes []selector.Node) (selector.Node, error) {
+	addr, err := r.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if addr != "" {
+		if n := find(nodes, addr); n != nil {
+			return n, nil
+		}
+		return r.pick(ctx, nodes)
+	}
+	picked, err := r.pick(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	// another router may assign the key first
+	if addr, err = r.store.Assign(ctx, key, picked.Address()); err != nil {
+		return nil, err
+	}
+	if n := find(nodes, addr); n != nil {
+		return n, nil
+	}
+	return nil, selector.ErrNoAvailable
+}
+
+func (r *Router) pick(ctx context.Context, nodes []selector.Node) (selector.Node, error) {
+	candidates := make([]selector.WeightedNode, len(nodes))
+	for i, n := range nodes {
+		if wn, ok := n.(selector.WeightedNode); ok {
+			candidates[i] = wn
+		} else {
+			candidates[i] = r.nodes.Build(n)
+		}
+	}
+	wn, done, err := r.balancer.Pick(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	done(ctx, selector.DoneInfo{})
+	return nodes[indexOf(candidates, wn)], nil
+}
+
+// Watch watches service and clears the assignments of the nodes removed
+// from it, until ctx is done.
+func (r *Router) Watch(ctx context.Context, discovery registry.Discovery, service string) error {
+	w, err := discovery.Watch(ctx, service)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = w.Stop()
+	}()
+	go r.watch(ctx, w)
+	return nil
+}
+
+func (r *Router) watch(ctx context.Context, w registry.Watcher) {
+	var seen map[string]struct{}
+	for {
+		services, err := w.Next()
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			log.Errorf("[sticky] watch service got unexpected error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		current := addrs(services)
+		for addr := range seen {
+			if _, ok := current[addr]; ok {
+				continue
+			}
+			if err = r.store.Clear(ctx, addr); err != nil {
+				log.Errorf("[sticky] clear node %s failed: %v", addr, err)
+				continue
+			}
+			log.Infof("[sticky] node %s removed, assignments cleared", addr)
+		}
+		seen = current
+	}
+}
+
+// addrs returns the endpoint addresses of services, the same as selector.Node.Address.
+func addrs(services []*registry.ServiceInstance) map[string]struct{} {
+	m := make(map[string]struct{})
+	for _, ins := range services {
+		for _, e := range ins.Endpoints {
+			if u, err := url.Parse(e); err == nil && u.Host != "" {
+				m[u.Host] = struct{}{}
+			}
+		}
+	}
+	return m
+}
+
+func find(nodes []selector.Node, addr string) selector.Node {
+	for _, n := range nodes {
+		if n.Address() == addr {
+			return n
+		}
+	}
+	return nil
+}
+
+func indexOf(nodes []selector.WeightedNode, n selector.WeightedNode) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package sticky
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+
+	libredis "github.com/yola1107/kratos/v2/library/db/redis"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/selector/chash"
+	"github.com/yola1107/kratos/v2/selector/random"
+)
+
+func newStore(t *testing.T) (*miniredis.Miniredis, *RedisStore) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	client := libredis.NewClient(libredis.WithAddress(mr.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewRedisStore(client, WithTTL(time.Minute))
+}
+
+func newInstances(n int) []*registry.ServiceInstance {
+	services := make([]*registry.ServiceInstance, 0, n)
+	for i := 0; i < n; i++ {
+		services = append(services, &registry.ServiceInstance{
+			ID:        fmt.Sprint(i),
+			Name:      "game",
+			Endpoints: []string{fmt.Sprintf("tcp://127.0.0.%d:9000", i)},
+		})
+	}
+	return services
+}
+
+func newNodes(services []*registry.ServiceInstance) []selector.Node {
+	nodes := make([]selector.Node, 0, len(services))
+	for _, ins := range services {
+		nodes = append(nodes, selector.NewNode("tcp", addrOf(ins), ins))
+	}
+	return nodes
+}
+
+func addrOf(ins *registry.ServiceInstance) string {
+	for addr := range addrs([]*registry.ServiceInstance{ins}) {
+		return addr
+	}
+	return ""
+}
+
+func TestRedisStore(t *testing.T) {
+	mr, s := newStore(t)
+	ctx := context.Background()
+
+	if addr, err := s.Get(ctx, "1001"); err != nil || addr != "" {
+		t.Fatalf("expect unassigned, got %q %v", addr, err)
+	}
+	if addr, err := s.Assign(ctx, "1001", "a"); err != nil || addr != "a" {
+		t.Fatalf("expect a, got %q %v", addr, err)
+	}
+	if addr, err := s.Assign(ctx, "1001", "b"); err != nil || addr != "a" {
+		t.Fatalf("expect a kept, got %q %v", addr, err)
+	}
+	if _, err := s.Assign(ctx, "1002", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Assign(ctx, "1003", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	mr.FastForward(30 * time.Second)
+	if addr, _ := s.Get(ctx, "1001"); addr != "a" {
+		t.Errorf("expect a, got %q", addr)
+	}
+	// 1001 was refreshed by the lookup, 1002 expires
+	mr.FastForward(45 * time.Second)
+	if addr, _ := s.Get(ctx, "1001"); addr != "a" {
+		t.Errorf("expect a refreshed, got %q", addr)
+	}
+	if addr, _ := s.Get(ctx, "1002"); addr != "" {
+		t.Errorf("expect 1002 expired, got %q", addr)
+	}
+
+	// 1004 is still indexed by a after it's reassigned to b
+	if _, err := s.Assign(ctx, "1004", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mr.Set(defaultPrefix+"key:1004", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Clear(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if addr, _ := s.Get(ctx, "1001"); addr != "" {
+		t.Errorf("expect 1001 cleared, got %q", addr)
+	}
+	if addr, _ := s.Get(ctx, "1004"); addr != "b" {
+		t.Errorf("expect 1004 kept on b, got %q", addr)
+	}
+	if err := s.Delete(ctx, "1003"); err != nil {
+		t.Fatal(err)
+	}
+	if addr, _ := s.Get(ctx, "1003"); addr != "" {
+		t.Errorf("expect 1003 deleted, got %q", addr)
+	}
+}
+
+func TestRouterFilter(t *testing.T) {
+	_, s := newStore(t)
+	sel := random.New()
+	sel.Apply(newNodes(newInstances(4)))
+	router := NewRouter(s)
+	filter := selector.WithNodeFilter(router.Filter())
+
+	for i := 0; i < 20; i++ {
+		ctx := chash.NewKeyContext(context.Background(), fmt.Sprint(i))
+		first, done, err := sel.Select(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(ctx, selector.DoneInfo{})
+		for j := 0; j < 5; j++ {
+			n, done, err := sel.Select(ctx, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			done(ctx, selector.DoneInfo{})
+			if n.Address() != first.Address() {
+				t.Fatalf("key %d: expect %s, got %s", i, first.Address(), n.Address())
+			}
+		}
+		if addr, _ := s.Get(ctx, fmt.Sprint(i)); addr != first.Address() {
+			t.Errorf("key %d: expect assignment %s, got %s", i, first.Address(), addr)
+		}
+	}
+
+	// the assigned node is missing from nodes, the key goes to a live node
+	// and keeps its assignment until Watch clears it
+	ctx := chash.NewKeyContext(context.Background(), "missing")
+	if _, err := s.Assign(ctx, "missing", "127.0.0.9:9000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sel.Select(ctx, filter); err != nil {
+		t.Fatal(err)
+	}
+	if addr, _ := s.Get(ctx, "missing"); addr != "127.0.0.9:9000" {
+		t.Errorf("expect assignment 127.0.0.9:9000 kept, got %s", addr)
+	}
+}
+
+type watcher struct {
+	ch  chan []*registry.ServiceInstance
+	ctx context.Context
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case services := <-w.ch:
+		return services, nil
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	}
+}
+
+func (w *watcher) Stop() error { return nil }
+
+type discovery struct{ w *watcher }
+
+func (d *discovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return nil, nil
+}
+
+func (d *discovery) Watch(context.Context, string) (registry.Watcher, error) { return d.w, nil }
+
+func TestRouterWatch(t *testing.T) {
+	_, s := newStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &watcher{ch: make(chan []*registry.ServiceInstance), ctx: ctx}
+	router := NewRouter(s)
+	if err := router.Watch(ctx, &discovery{w: w}, "game"); err != nil {
+		t.Fatal(err)
+	}
+
+	services := newInstances(3)
+	w.ch <- services
+	for i, ins := range services {
+		if _, err := s.Assign(ctx, fmt.Sprint(i), addrOf(ins)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.ch <- services[1:]
+	w.ch <- services[1:] // wait for the previous update being handled
+
+	if addr, _ := s.Get(ctx, "0"); addr != "" {
+		t.Errorf("expect assignment of the removed node cleared, got %s", addr)
+	}
+	if addr, _ := s.Get(ctx, "1"); addr != addrOf(services[1]) {
+		t.Errorf("expect %s kept, got %s", addrOf(services[1]), addr)
+	}
+}
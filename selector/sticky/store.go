@@ -0,0 +1,158 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultPrefix = "kratos:sticky:"
+	defaultTTL    = 30 * time.Minute
+)
+
+// Store records the node address each key is assigned to.
+type Store interface {
+	// Get returns the address key is assigned to and refreshes its TTL,
+	// or "" when key is unassigned.
+	Get(ctx context.Context, key string) (string, error)
+	// Assign assigns key to addr unless it's already assigned, and returns
+	// the address key is assigned to.
+	Assign(ctx context.Context, key, addr string) (string, error)
+	// Delete deletes the assignment of key.
+	Delete(ctx context.Context, key string) error
+	// Clear deletes all assignments to addr.
+	Clear(ctx context.Context, addr string) error
+}
+
+// StoreOption is redis store option.
+type StoreOption func(*RedisStore)
+
+// WithPrefix with the redis key prefix, default is kratos:sticky:.
+func WithPrefix(prefix string) StoreOption {
+	return func(s *RedisStore) { s.prefix = prefix }
+}
+
+// WithTTL with the assignment ttl, refreshed on every lookup, default is 30m.
+func WithTTL(ttl time.Duration) StoreOption {
+	return func(s *RedisStore) { s.ttl = ttl }
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore is a Store in redis, the client is usually created by library/db/redis.
+//
+// Each assignment is a string key with TTL, and the keys of a node are
+// indexed in a set so they can be cleared when the node goes away.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore new a redis store.
+func NewRedisStore(client redis.Cmdable, opts ...StoreOption) *RedisStore {
+	s := &RedisStore{
+		client: client,
+		prefix: defaultPrefix,
+		ttl:    defaultTTL,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+func (s *RedisStore) keyOf(key string) string   { return s.prefix + "key:" + key }
+func (s *RedisStore) nodeOf(addr string) string { return s.prefix + "node:" + addr }
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	var get *redis.StringCmd
+	_, err := s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, s.keyOf(key))
+		p.Expire(ctx, s.keyOf(key), s.ttl)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// keep the node index alive as long as its keys
+	if err = s.client.Expire(ctx, s.nodeOf(get.Val()), s.ttl).Err(); err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+func (s *RedisStore) Assign(ctx context.Context, key, addr string) (string, error) {
+	ok, err := s.client.SetNX(ctx, s.keyOf(key), addr, s.ttl).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		current, err := s.client.Get(ctx, s.keyOf(key)).Result()
+		if errors.Is(err, redis.Nil) {
+			// expired in between, try again
+			return s.Assign(ctx, key, addr)
+		}
+		return current, err
+	}
+	_, err = s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.SAdd(ctx, s.nodeOf(addr), key)
+		p.Expire(ctx, s.nodeOf(addr), s.ttl)
+		return nil
+	})
+	return addr, err
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	addr, err := s.client.Get(ctx, s.keyOf(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, s.keyOf(key))
+		p.SRem(ctx, s.nodeOf(addr), key)
+		return nil
+	})
+	return err
+}
+
+// clearScript deletes KEYS[1] if it's still assigned to ARGV[1], so a key
+// reassigned to another node between SMEMBERS and DEL is kept.
+var clearScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (s *RedisStore) Clear(ctx context.Context, addr string) error {
+	keys, err := s.client.SMembers(ctx, s.nodeOf(addr)).Result()
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		// load the script once, EVALSHA in a pipeline does not fall back to EVAL
+		if err = clearScript.Load(ctx, s.client).Err(); err != nil {
+			return err
+		}
+		_, err = s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, key := range keys {
+				clearScript.EvalSha(ctx, p, []string{s.keyOf(key)}, addr)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.client.Del(ctx, s.nodeOf(addr)).Err()
+}
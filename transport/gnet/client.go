@@ -1,45 +1,88 @@
 package gnet
 
 import (
+	"bufio"
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	gproto "google.golang.org/protobuf/proto"
 
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/metadata"
 	"github.com/yola1107/kratos/v2/middleware"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
 	"github.com/yola1107/kratos/v2/transport"
+	"github.com/yola1107/kratos/v2/transport/internal/resolver"
 	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
 )
 
+// maxDatagramSize is the largest udp payload.
+const (
+	maxDatagramSize = 64 << 10
+	// readBufSize is the stream reader buffer size, larger frames are copied.
+	readBufSize = 8 << 10
+)
+
+var (
+	// ErrClientClosed is returned when calling a closed client.
+	ErrClientClosed = errors.New("gnet client: client closed")
+	// ErrConnClosed is returned to in-flight calls when their connection drops.
+	// It carries codes.Unavailable so the call can be retried.
+	ErrConnClosed = status.Error(codes.Unavailable, "gnet client: connection closed")
+)
+
+var warnOpsOperation sync.Once
+
 // ClientOption configures the gnet client.
 type ClientOption func(*clientOptions)
 
+// PushHandler handles the data of a server push.
+type PushHandler func(data []byte)
+
 type clientOptions struct {
-	endpoint   string
-	timeout    time.Duration
-	middleware []middleware.Middleware
+	endpoint         string
+	timeout          time.Duration
+	codec            tcpproto.Codec
+	middleware       []middleware.Middleware
+	poolSize         int
+	discovery        registry.Discovery
+	nodeFilters      []selector.NodeFilter
+	selector         selector.Builder
+	heartbeat        time.Duration
+	heartbeatTimeout time.Duration
+	pushHandler      map[int32]PushHandler
 }
 
-// WithEndpoint sets target endpoint (host:port).
+// WithEndpoint sets target endpoint, host:port dials tcp, the gnet+udp:// and
+// gnet+unix:// schemes dial udp and unix sockets.
 func WithEndpoint(endpoint string) ClientOption {
 	return func(o *clientOptions) {
 		o.endpoint = endpoint
 	}
 }
 
-// WithTimeout sets dial/request timeout.
+// WithTimeout sets dial timeout and the request timeout used when ctx has no deadline.
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(o *clientOptions) {
 		o.timeout = timeout
 	}
 }
 
+// WithCodec sets the wire codec, defaults to DefaultCodec.
+func WithCodec(codec tcpproto.Codec) ClientOption {
+	return func(o *clientOptions) {
+		o.codec = codec
+	}
+}
+
 // WithMiddleware adds client middleware.
 func WithMiddleware(m ...middleware.Middleware) ClientOption {
 	return func(o *clientOptions) {
@@ -47,29 +90,164 @@ func WithMiddleware(m ...middleware.Middleware) ClientOption {
 	}
 }
 
-// Client is a lightweight gnet client (sync over TCP).
+// WithPoolSize sets the number of connections calls are spread over, defaults to 1.
+func WithPoolSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		o.poolSize = size
+	}
+}
+
+// WithHeartbeat sets the ping interval, a connection that receives nothing
+// within timeout is closed. Zero interval disables heartbeats.
+func WithHeartbeat(interval, timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.heartbeat = interval
+		o.heartbeatTimeout = timeout
+	}
+}
+
+// WithPushHandler sets the handlers of server pushes by command. Pushes
+// arrive on the connections dialed by calls.
+func WithPushHandler(handler map[int32]PushHandler) ClientOption {
+	return func(o *clientOptions) {
+		o.pushHandler = handler
+	}
+}
+
+// CallOption configures a single call.
+type CallOption func(*callInfo)
+
+type callInfo struct {
+	operation string
+}
+
+// Operation sets the FullMethod of the call, e.g. /helloworld.Greeter/SayHello.
+//
+// Deprecated default: calls without it use /ops/<ops>, which middleware cannot
+// match to a method. The default will be removed in the next release.
+func Operation(fullMethod string) CallOption {
+	return func(c *callInfo) {
+		c.operation = fullMethod
+	}
+}
+
+// WithDiscovery with client discovery, used by discovery:///<service> endpoints.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) {
+		o.discovery = d
+	}
+}
+
+// WithNodeFilter with select filters.
+func WithNodeFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *clientOptions) {
+		o.nodeFilters = filters
+	}
+}
+
+// WithSelector with node selector builder (p2c, wrr, random),
+// defaults to the global selector.
+func WithSelector(b selector.Builder) ClientOption {
+	return func(o *clientOptions) {
+		o.selector = b
+	}
+}
+
+// Client is a gnet client multiplexing concurrent calls over a pool of
+// long-lived connections per node. Responses are matched to calls by Seq,
+// dropped connections are redialed on the next call.
 type Client struct {
-	opts clientOptions
+	opts     clientOptions
+	network  string
+	address  string
+	err      error
+	selector selector.Selector
+	r        *resolver.Resolver
+	mu       sync.Mutex
+	pools    map[string]*connPool // address -> pool
+	pushes   sync.Map             // command -> PushHandler
+	closed   atomic.Bool
 }
 
-// NewClient creates a gnet client.
+// NewClient creates a gnet client, connections are dialed lazily.
 func NewClient(opts ...ClientOption) *Client {
 	c := &Client{
 		opts: clientOptions{
-			timeout: 2 * time.Second,
+			timeout:          2 * time.Second,
+			codec:            DefaultCodec,
+			poolSize:         1,
+			heartbeat:        10 * time.Second,
+			heartbeatTimeout: 30 * time.Second,
 		},
 	}
 	for _, o := range opts {
 		o(&c.opts)
 	}
+	if c.opts.poolSize <= 0 {
+		c.opts.poolSize = 1
+	}
+	for command, handler := range c.opts.pushHandler {
+		c.pushes.Store(command, handler)
+	}
+	c.pools = make(map[string]*connPool)
+	service, ok := resolver.ParseTarget(c.opts.endpoint)
+	if !ok {
+		c.network, c.address, c.err = parseEndpoint(c.opts.endpoint)
+		return c
+	}
+	if c.opts.discovery == nil {
+		c.err = fmt.Errorf("gnet client: discovery is required by endpoint %s", c.opts.endpoint)
+		return c
+	}
+	c.network = "tcp"
+	c.selector = resolver.NewSelector(c.opts.selector)
+	c.r, c.err = resolver.New(context.Background(), c.opts.discovery, service, SchemeTCP, rebalancerFunc(c.apply), 0)
 	return c
 }
 
+type rebalancerFunc func(nodes []selector.Node)
+
+func (f rebalancerFunc) Apply(nodes []selector.Node) { f(nodes) }
+
+// apply updates the selector and closes the pools of removed nodes.
+func (c *Client) apply(nodes []selector.Node) {
+	c.selector.Apply(nodes)
+	alive := make(map[string]struct{}, len(nodes))
+	for _, n := range nodes {
+		alive[n.Address()] = struct{}{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr, p := range c.pools {
+		if _, ok := alive[addr]; !ok {
+			delete(c.pools, addr)
+			p.close(ErrConnClosed)
+		}
+	}
+}
+
 // Invoke sends a request with given ops and decodes response into reply.
-func (c *Client) Invoke(ctx context.Context, ops int32, req gproto.Message, reply gproto.Message) error {
+// Metadata of metadata.NewClientContext is sent in the payload header.
+func (c *Client) Invoke(ctx context.Context, ops int32, req gproto.Message, reply gproto.Message, opts ...CallOption) error {
 	if c.opts.endpoint == "" {
 		return fmt.Errorf("gnet client: endpoint is empty")
 	}
+	if c.err != nil {
+		return c.err
+	}
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	var info callInfo
+	for _, o := range opts {
+		o(&info)
+	}
+	if info.operation == "" {
+		warnOpsOperation.Do(func() {
+			log.Warn("[gnet] client calls without the Operation call option use the deprecated /ops/<ops> operation")
+		})
+		info.operation = fmt.Sprintf("/ops/%d", ops)
+	}
 	bodyData, err := gproto.Marshal(req)
 	if err != nil {
 		return err
@@ -82,18 +260,28 @@ func (c *Client) Invoke(ctx context.Context, ops int32, req gproto.Message, repl
 	payload := &tcpproto.Payload{
 		Op:   ops,
 		Type: int32(tcpproto.Request),
-		Seq:  1,
 		Body: bodyBytes,
 	}
 
-	ctx = transport.NewClientContext(ctx, &Transport{
-		endpoint:  c.opts.endpoint,
-		operation: fmt.Sprintf("/ops/%d", ops),
-		reqHeader: headerCarrier{},
-	})
+	tr := &Transport{
+		endpoint:   c.opts.endpoint,
+		operation:  info.operation,
+		reqHeader:  headerCarrier{},
+		respHeader: headerCarrier{},
+	}
+	ctx = transport.NewClientContext(ctx, tr)
 
 	h := func(ctx context.Context, _ any) (any, error) {
-		return nil, c.roundTrip(ctx, payload, reply)
+		if md, ok := metadata.FromClientContext(ctx); ok {
+			// metadata not already copied by the metadata middleware
+			for k, v := range md {
+				if _, ok := tr.reqHeader[k]; !ok {
+					tr.reqHeader[k] = v
+				}
+			}
+		}
+		payload.Header = tr.reqHeader.payloadHeader()
+		return nil, c.roundTrip(ctx, tr, payload, reply)
 	}
 	if len(c.opts.middleware) > 0 {
 		h = middleware.Chain(c.opts.middleware...)(h)
@@ -102,38 +290,92 @@ func (c *Client) Invoke(ctx context.Context, ops int32, req gproto.Message, repl
 	return err
 }
 
-func (c *Client) roundTrip(ctx context.Context, payload *tcpproto.Payload, reply gproto.Message) error {
-	dialer := &net.Dialer{Timeout: c.opts.timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", c.opts.endpoint)
-	if err != nil {
-		return err
+// OnPush sets the handler of the pushes of command, replacing the handler
+// of WithPushHandler. A nil handler removes it.
+func (c *Client) OnPush(command int32, handler PushHandler) {
+	if handler == nil {
+		c.pushes.Delete(command)
+		return
 	}
-	defer conn.Close()
+	c.pushes.Store(command, handler)
+}
 
-	if err := conn.SetDeadline(time.Now().Add(c.opts.timeout)); err != nil {
-		return err
+func (c *Client) handlePush(p *tcpproto.Payload) {
+	body := &tcpproto.Body{}
+	if err := gproto.Unmarshal(p.Body, body); err != nil {
+		log.Warnf("[gnet] client unmarshal push error: %v", err)
+		return
+	}
+	v, ok := c.pushes.Load(body.Ops)
+	if !ok {
+		return
 	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[gnet] client push handler of %d panic: %v", body.Ops, r)
+		}
+	}()
+	v.(PushHandler)(body.Data)
+}
 
-	out, err := encodePayload(payload)
-	if err != nil {
-		return err
+// Close closes all connections and fails in-flight calls.
+func (c *Client) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
 	}
-	if _, err = conn.Write(out); err != nil {
-		return err
+	if c.r != nil {
+		_ = c.r.Close()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr, p := range c.pools {
+		delete(c.pools, addr)
+		p.close(ErrClientClosed)
 	}
+	return nil
+}
 
-	resp, err := readPayload(conn)
+func (c *Client) roundTrip(ctx context.Context, tr *Transport, payload *tcpproto.Payload, reply gproto.Message) (err error) {
+	if _, ok := ctx.Deadline(); !ok && c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+	address := c.address
+	if c.selector != nil {
+		var (
+			node selector.Node
+			done selector.DoneFunc
+		)
+		if node, done, err = c.selector.Select(ctx, selector.WithNodeFilter(c.opts.nodeFilters...)); err != nil {
+			return err
+		}
+		address = node.Address()
+		defer func() { done(ctx, selector.DoneInfo{Err: err}) }()
+	}
+	cc, err := c.conn(ctx, address)
 	if err != nil {
 		return err
 	}
+	tr.remoteAddr = cc.conn.RemoteAddr()
+	resp, err := cc.call(ctx, payload)
+	if err != nil {
+		return err
+	}
+	for k, v := range resp.Header {
+		tr.respHeader[k] = v.GetValues()
+	}
 	if resp.Type != int32(tcpproto.Response) {
 		return fmt.Errorf("unexpected payload type: %d", resp.Type)
 	}
-	if resp.Code != 0 {
-		return status.Error(codes.Code(resp.Code), "gnet: server returned error code")
-	}
 	respBody := &tcpproto.Body{}
 	if err := gproto.Unmarshal(resp.Body, respBody); err != nil {
+		if resp.Code != 0 {
+			return tcpproto.ToError(resp.Code, nil)
+		}
+		return err
+	}
+	if err := tcpproto.ToError(resp.Code, respBody.Error); err != nil {
 		return err
 	}
 	if reply == nil {
@@ -142,22 +384,273 @@ func (c *Client) roundTrip(ctx context.Context, payload *tcpproto.Payload, reply
 	return gproto.Unmarshal(respBody.Data, reply)
 }
 
-func readPayload(r io.Reader) (*tcpproto.Payload, error) {
-	var sizeBuf [4]byte
-	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
-		return nil, err
+// conn returns a live connection to address, dialing when needed.
+func (c *Client) conn(ctx context.Context, address string) (*clientConn, error) {
+	c.mu.Lock()
+	if c.closed.Load() {
+		c.mu.Unlock()
+		return nil, ErrClientClosed
 	}
-	size := binary.BigEndian.Uint32(sizeBuf[:])
-	if size == 0 {
-		return nil, fmt.Errorf("invalid payload size")
+	p, ok := c.pools[address]
+	if !ok {
+		p = newConnPool(c.opts.poolSize)
+		c.pools[address] = p
 	}
-	buf := make([]byte, size)
-	if _, err := io.ReadFull(r, buf); err != nil {
-		return nil, err
+	c.mu.Unlock()
+
+	s := p.slots[p.next.Add(1)%uint32(len(p.slots))]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed.Load() || p.closed.Load() {
+		return nil, ErrConnClosed
+	}
+	if s.conn != nil && !s.conn.isClosed() {
+		return s.conn, nil
 	}
-	p := &tcpproto.Payload{}
-	if err := gproto.Unmarshal(buf, p); err != nil {
+	dialer := &net.Dialer{Timeout: c.opts.timeout}
+	nc, err := dialer.DialContext(ctx, c.network, address)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "gnet client: dial %s: %v", address, err)
+	}
+	s.conn = newClientConn(nc, &c.opts, isDatagram(c.network), c.handlePush)
+	return s.conn, nil
+}
+
+// connPool is the round-robin connection pool of one address.
+type connPool struct {
+	slots  []*connSlot
+	next   atomic.Uint32
+	closed atomic.Bool
+}
+
+func newConnPool(size int) *connPool {
+	p := &connPool{slots: make([]*connSlot, size)}
+	for i := range p.slots {
+		p.slots[i] = &connSlot{}
+	}
+	return p
+}
+
+// close closes the connections of the pool, a slot dialing meanwhile sees
+// closed once it holds the slot lock, or has its new connection closed here.
+func (p *connPool) close(err error) {
+	p.closed.Store(true)
+	for _, s := range p.slots {
+		s.mu.Lock()
+		if s.conn != nil {
+			s.conn.close(err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+type connSlot struct {
+	mu   sync.Mutex
+	conn *clientConn
+}
+
+// clientConn is one multiplexed connection.
+type clientConn struct {
+	conn     net.Conn
+	opts     *clientOptions
+	datagram bool
+	wmu      sync.Mutex
+	mu       sync.Mutex
+	seq      int32
+	pending  map[int32]chan *tcpproto.Payload
+	err      error
+	done     chan struct{}
+	lastRecv atomic.Int64
+	push     func(p *tcpproto.Payload)
+}
+
+func newClientConn(conn net.Conn, opts *clientOptions, datagram bool, push func(p *tcpproto.Payload)) *clientConn {
+	cc := &clientConn{
+		conn:     conn,
+		opts:     opts,
+		datagram: datagram,
+		push:     push,
+		pending:  make(map[int32]chan *tcpproto.Payload),
+		done:     make(chan struct{}),
+	}
+	cc.lastRecv.Store(time.Now().UnixNano())
+	go cc.readLoop()
+	if opts.heartbeat > 0 {
+		go cc.heartbeatLoop()
+	}
+	return cc
+}
+
+func (cc *clientConn) isClosed() bool {
+	select {
+	case <-cc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection and fails every pending call.
+func (cc *clientConn) close(err error) {
+	cc.mu.Lock()
+	if cc.err != nil {
+		cc.mu.Unlock()
+		return
+	}
+	cc.err = err
+	pending := cc.pending
+	cc.pending = nil
+	close(cc.done)
+	cc.mu.Unlock()
+
+	_ = cc.conn.Close()
+	for _, ch := range pending {
+		close(ch)
+	}
+}
+
+func (cc *clientConn) call(ctx context.Context, p *tcpproto.Payload) (*tcpproto.Payload, error) {
+	cc.mu.Lock()
+	if cc.err != nil {
+		cc.mu.Unlock()
+		return nil, ErrConnClosed
+	}
+	// seq 0 is reserved for heartbeats
+	if cc.seq++; cc.seq <= 0 {
+		cc.seq = 1
+	}
+	seq := cc.seq
+	ch := make(chan *tcpproto.Payload, 1)
+	cc.pending[seq] = ch
+	cc.mu.Unlock()
+
+	req := &tcpproto.Payload{Op: p.Op, Place: p.Place, Type: p.Type, Seq: seq, Body: p.Body, Header: p.Header}
+	buf, err := cc.opts.codec.Marshal(req)
+	if err != nil {
+		// the connection is fine, only this request cannot be encoded
+		cc.mu.Lock()
+		delete(cc.pending, seq)
+		cc.mu.Unlock()
 		return nil, err
 	}
-	return p, nil
+	if err = cc.write(ctx, buf); err != nil {
+		cc.close(err)
+		return nil, ErrConnClosed
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrConnClosed
+		}
+		return resp, nil
+	case <-ctx.Done():
+		cc.mu.Lock()
+		delete(cc.pending, seq)
+		cc.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (cc *clientConn) write(ctx context.Context, buf []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(cc.opts.timeout)
+	}
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	if err := cc.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	_, err := cc.conn.Write(buf)
+	return err
+}
+
+func (cc *clientConn) readLoop() {
+	read := cc.readStream()
+	if cc.datagram {
+		read = cc.readDatagram()
+	}
+	for {
+		p := &tcpproto.Payload{}
+		if err := read(p); err != nil {
+			cc.close(err)
+			return
+		}
+		cc.lastRecv.Store(time.Now().UnixNano())
+		if p.Type == int32(tcpproto.Push) {
+			cc.push(p)
+			continue
+		}
+		if p.Type != int32(tcpproto.Response) {
+			continue
+		}
+		cc.mu.Lock()
+		ch, ok := cc.pending[p.Seq]
+		delete(cc.pending, p.Seq)
+		cc.mu.Unlock()
+		if ok {
+			ch <- p
+		}
+	}
+}
+
+func (cc *clientConn) readStream() func(p *tcpproto.Payload) error {
+	rr := bufio.NewReaderSize(cc.conn, readBufSize)
+	return func(p *tcpproto.Payload) error {
+		return tcpproto.ReadFrame(cc.opts.codec, rr, p)
+	}
+}
+
+// readDatagram decodes each datagram as one frame.
+func (cc *clientConn) readDatagram() func(p *tcpproto.Payload) error {
+	buf := make([]byte, maxDatagramSize)
+	return func(p *tcpproto.Payload) error {
+		for {
+			n, err := cc.conn.Read(buf)
+			if err != nil {
+				return err
+			}
+			if size, err := cc.opts.codec.FrameSize(buf[:n]); err != nil || size != n {
+				log.Warnf("[gnet] client drop invalid datagram from %s", cc.conn.RemoteAddr())
+				continue
+			}
+			if err = cc.opts.codec.Unmarshal(buf[:n], p); err != nil {
+				log.Warnf("[gnet] client unmarshal datagram error: %v", err)
+				continue
+			}
+			p.CloneBody()
+			return nil
+		}
+	}
+}
+
+func (cc *clientConn) heartbeatLoop() {
+	ping, err := cc.opts.codec.Marshal(&tcpproto.Payload{Type: int32(tcpproto.Ping)})
+	if err != nil {
+		cc.close(err)
+		return
+	}
+	ticker := time.NewTicker(cc.opts.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cc.done:
+			return
+		case <-ticker.C:
+			last := time.Unix(0, cc.lastRecv.Load())
+			if cc.opts.heartbeatTimeout > 0 && time.Since(last) > cc.opts.heartbeatTimeout {
+				log.Warnf("[gnet] client heartbeat timeout: %s", cc.conn.RemoteAddr())
+				cc.close(ErrConnClosed)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), cc.opts.heartbeat)
+			err = cc.write(ctx, ping)
+			cancel()
+			if err != nil {
+				cc.close(err)
+				return
+			}
+		}
+	}
 }
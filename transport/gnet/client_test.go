@@ -0,0 +1,232 @@
+package gnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
+)
+
+// stubServer echoes requests after a delay taken from the request value,
+// so replies may arrive out of order. Ops 2 drops the connection.
+func stubServer(t *testing.T) (string, *atomic.Int32, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var wmu sync.Mutex
+				for {
+					p := &tcpproto.Payload{}
+					if err := tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+						return
+					}
+					if p.Type == int32(tcpproto.Ping) {
+						p.Type = int32(tcpproto.Pong)
+						wmu.Lock()
+						_ = tcpproto.WriteFrame(DefaultCodec, conn, p)
+						wmu.Unlock()
+						continue
+					}
+					body := &tcpproto.Body{}
+					_ = gproto.Unmarshal(p.Body, body)
+					if body.Ops == 2 {
+						return
+					}
+					v := &wrapperspb.Int64Value{}
+					_ = gproto.Unmarshal(body.Data, v)
+					go func(p *tcpproto.Payload) {
+						time.Sleep(time.Duration(v.Value) * time.Millisecond)
+						p.Type = int32(tcpproto.Response)
+						wmu.Lock()
+						_ = tcpproto.WriteFrame(DefaultCodec, conn, p)
+						wmu.Unlock()
+					}(p)
+				}
+			}(conn)
+		}
+	}()
+	return lis.Addr().String(), accepted, func() { lis.Close() }
+}
+
+// stubSleep names the delayed echo of the stub server.
+var stubSleep = Operation("/test.Stub/Sleep")
+
+func TestClientMultiplex(t *testing.T) {
+	addr, accepted, stop := stubServer(t)
+	defer stop()
+
+	c := NewClient(WithEndpoint(addr), WithPoolSize(2))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			// later calls are answered first
+			delay := 40 - 2*i
+			reply := &wrapperspb.Int64Value{}
+			if err := c.Invoke(context.Background(), 1, wrapperspb.Int64(delay), reply, stubSleep); err != nil {
+				errs <- err
+				return
+			}
+			if reply.Value != delay {
+				errs <- fmt.Errorf("expect %d, got %d", delay, reply.Value)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("expect 2 connections, got %d", n)
+	}
+}
+
+func TestClientPoolCloseInFlight(t *testing.T) {
+	addr, _, stop := stubServer(t)
+	defer stop()
+
+	c := NewClient(WithEndpoint(addr), WithPoolSize(4))
+	defer c.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := c.Invoke(context.Background(), 1, wrapperspb.Int64(1), &wrapperspb.Int64Value{}, stubSleep)
+				if errors.Is(err, ErrConnClosed) {
+					return
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	// close the pool as a discovery update removing the node does
+	c.mu.Lock()
+	p := c.pools[addr]
+	c.mu.Unlock()
+	p.close(ErrConnClosed)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("expect %v, got %v", ErrConnClosed, err)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	addr, accepted, stop := stubServer(t)
+	defer stop()
+
+	c := NewClient(WithEndpoint(addr), WithHeartbeat(5*time.Millisecond, time.Second))
+	defer c.Close()
+
+	// the in-flight call fails with a retryable error when the server drops the connection
+	err := c.Invoke(context.Background(), 2, wrapperspb.Int64(0), nil, Operation("/test.Stub/Drop"))
+	if !errors.Is(err, ErrConnClosed) || status.Code(err) != codes.Unavailable {
+		t.Fatalf("expect %v, got %v", ErrConnClosed, err)
+	}
+
+	reply := &wrapperspb.Int64Value{}
+	if err = c.Invoke(context.Background(), 1, wrapperspb.Int64(1), reply, stubSleep); err != nil {
+		t.Fatal(err)
+	}
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("expect 2 connections, got %d", n)
+	}
+
+	// heartbeats keep the connection alive
+	time.Sleep(30 * time.Millisecond)
+	if err = c.Invoke(context.Background(), 1, wrapperspb.Int64(1), reply, stubSleep); err != nil {
+		t.Fatal(err)
+	}
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("expect 2 connections, got %d", n)
+	}
+	// the deprecated /ops/<ops> default still works
+	if err = c.Invoke(context.Background(), 1, wrapperspb.Int64(1), reply); err != nil {
+		t.Error(err)
+	}
+
+	_ = c.Close()
+	if err = c.Invoke(context.Background(), 1, wrapperspb.Int64(1), reply, stubSleep); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("expect %v, got %v", ErrClientClosed, err)
+	}
+}
+
+func TestClientOnPush(t *testing.T) {
+	addr := freeAddr(t)
+	opened := make(chan *Session, 2)
+	srv := NewServer(Address(addr))
+	srv.RegisterService(&testServiceDesc, &testServer{}, func(sess *Session) { opened <- sess }, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+	// drain the probe connection
+	<-opened
+
+	pushes := make(chan string, 1)
+	c := NewClient(WithEndpoint(addr))
+	defer c.Close()
+	c.OnPush(7, func(data []byte) {
+		v := &wrapperspb.StringValue{}
+		_ = gproto.Unmarshal(data, v)
+		pushes <- v.Value
+	})
+	// pushes arrive on the connections dialed by calls
+	if err := c.Invoke(context.Background(), 1, wrapperspb.String("ping"), &wrapperspb.StringValue{}, Operation("/test.Service/Echo")); err != nil {
+		t.Fatal(err)
+	}
+	sess := <-opened
+	if err := srv.Push(context.Background(), sess.ID(), 7, wrapperspb.String("hi")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-pushes:
+		if v != "hi" {
+			t.Errorf("expect hi, got %s", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push not received")
+	}
+
+	// removed handlers drop pushes
+	c.OnPush(7, nil)
+	if err := srv.Push(context.Background(), sess.ID(), 7, wrapperspb.String("bye")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-pushes:
+		t.Errorf("unexpected push %s", v)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,115 @@
+package gnet
+
+import (
+	"github.com/yola1107/kratos/v2/log"
+	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
+)
+
+// dispatch queues p on the session and starts a worker when none is running,
+// so requests of one connection are handled in order.
+func (s *Server) dispatch(sess *Session, p *tcpproto.Payload) {
+	sess.mu.Lock()
+	sess.queue = append(sess.queue, p)
+	if sess.running {
+		sess.mu.Unlock()
+		return
+	}
+	sess.running = true
+	sess.mu.Unlock()
+
+	if err := s.submit(func() { s.drain(sess) }); err != nil {
+		log.Warnf("[gnet] submit task error: %v, reject the queued requests of session %s", err, sess.id)
+		s.reject(sess)
+	}
+}
+
+// reject answers the queued requests of sess with ErrServerBusy, it runs on
+// the event loop when no worker can take them.
+func (s *Server) reject(sess *Session) {
+	sess.mu.Lock()
+	queue := sess.queue
+	sess.queue = nil
+	sess.running = false
+	sess.paused = false
+	sess.mu.Unlock()
+
+	for _, p := range queue {
+		var resp *tcpproto.Payload
+		var err error
+		if tcpproto.Pattern(p.Type) == tcpproto.Request {
+			resp, err = errorResponse(p, p.Op, ErrServerBusy)
+		} else {
+			resp, err = s.handlePayload(sess, sess.remote, p)
+		}
+		if resp == nil {
+			if err != nil {
+				log.Warnf("[gnet] reject payload error: %v", err)
+			}
+			continue
+		}
+		if err = sess.write(resp); err != nil {
+			log.Warnf("[gnet] write response error: %v", err)
+		}
+	}
+}
+
+// drain handles queued requests until the queue is empty.
+func (s *Server) drain(sess *Session) {
+	for {
+		sess.mu.Lock()
+		if len(sess.queue) == 0 || sess.Closed() {
+			sess.queue = nil
+			sess.running = false
+			resume := sess.paused
+			sess.paused = false
+			sess.mu.Unlock()
+			if resume {
+				s.resume(sess)
+			}
+			return
+		}
+		p := sess.queue[0]
+		sess.queue[0] = nil
+		sess.queue = sess.queue[1:]
+		resume := sess.paused && len(sess.queue) < s.maxPending
+		if resume {
+			sess.paused = false
+		}
+		sess.mu.Unlock()
+		if resume {
+			s.resume(sess)
+		}
+
+		resp, err := s.handlePayload(sess, sess.RemoteAddr(), p)
+		if err != nil {
+			log.Warnf("[gnet] handle payload error: %v", err)
+		}
+		if resp == nil {
+			continue
+		}
+		if err = sess.write(resp); err != nil {
+			log.Warnf("[gnet] write response error: %v", err)
+		}
+	}
+}
+
+// resume triggers OnTraffic to decode the frames left in the inbound buffer.
+func (s *Server) resume(sess *Session) {
+	if err := sess.conn.Wake(nil); err != nil {
+		log.Warnf("[gnet] wake session %s error: %v", sess.id, err)
+	}
+}
+
+// pause reports whether the offload queue of the session is full and marks it paused.
+func (sess *Session) pause(maxPending int) bool {
+	if maxPending <= 0 {
+		return false
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if len(sess.queue) < maxPending {
+		return false
+	}
+	sess.paused = true
+	return true
+}
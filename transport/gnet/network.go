@@ -0,0 +1,66 @@
+package gnet
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Endpoint schemes registered by the server and accepted by the client.
+const (
+	SchemeTCP  = "gnet"
+	SchemeUDP  = "gnet+udp"
+	SchemeUnix = "gnet+unix"
+)
+
+// isDatagram reports whether network is packet oriented, each datagram carries one Payload.
+func isDatagram(network string) bool {
+	return strings.HasPrefix(network, "udp")
+}
+
+// splitProtoAddr splits a gnet protoAddr like tcp://:3200 or unix:///tmp/gnet.sock.
+func splitProtoAddr(protoAddr string) (network, address string) {
+	network, address, ok := strings.Cut(protoAddr, "://")
+	if !ok {
+		return "tcp", protoAddr
+	}
+	return network, address
+}
+
+// schemeOf returns the endpoint scheme of network.
+func schemeOf(network string) string {
+	switch {
+	case network == "unix":
+		return SchemeUnix
+	case isDatagram(network):
+		return SchemeUDP
+	default:
+		return SchemeTCP
+	}
+}
+
+// parseEndpoint returns the dial network and address of a client endpoint.
+// examples:
+//   - 127.0.0.1:3200
+//   - gnet://127.0.0.1:3200, tcp://127.0.0.1:3200
+//   - gnet+udp://127.0.0.1:3200, udp://127.0.0.1:3200
+//   - gnet+unix:///tmp/gnet.sock, unix:///tmp/gnet.sock
+func parseEndpoint(endpoint string) (network, address string, err error) {
+	if !strings.Contains(endpoint, "://") {
+		return "tcp", endpoint, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case SchemeTCP, "tcp", "tcp4", "tcp6":
+		return "tcp", u.Host, nil
+	case SchemeUDP, "udp", "udp4", "udp6":
+		return "udp", u.Host, nil
+	case SchemeUnix, "unix":
+		return "unix", u.Host + u.Path, nil
+	default:
+		return "", "", fmt.Errorf("gnet: unsupported endpoint scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,101 @@
+package gnet
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		network  string
+		address  string
+	}{
+		{"127.0.0.1:3200", "tcp", "127.0.0.1:3200"},
+		{"gnet://127.0.0.1:3200", "tcp", "127.0.0.1:3200"},
+		{"gnet+udp://127.0.0.1:3200", "udp", "127.0.0.1:3200"},
+		{"udp://127.0.0.1:3200", "udp", "127.0.0.1:3200"},
+		{"gnet+unix:///tmp/gnet.sock", "unix", "/tmp/gnet.sock"},
+		{"unix:///tmp/gnet.sock", "unix", "/tmp/gnet.sock"},
+	}
+	for _, tt := range tests {
+		network, address, err := parseEndpoint(tt.endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network != tt.network || address != tt.address {
+			t.Errorf("%s: expect %s %s, got %s %s", tt.endpoint, tt.network, tt.address, network, address)
+		}
+	}
+	if _, _, err := parseEndpoint("ws://127.0.0.1:3200"); err == nil {
+		t.Error("expect unsupported scheme error")
+	}
+}
+
+// invokeUntilReady retries the echo call until the server answers.
+func invokeUntilReady(t *testing.T, c *Client) {
+	reply := &wrapperspb.StringValue{}
+	var err error
+	for i := 0; i < 50; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err = c.Invoke(ctx, 1, wrapperspb.String("echo"), reply, Operation("/test.Service/Echo"))
+		cancel()
+		if err == nil {
+			if reply.Value != "echo" {
+				t.Errorf("expect echo, got %v", reply.Value)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal(err)
+}
+
+func TestServerUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := pc.LocalAddr().String()
+	pc.Close()
+
+	srv := NewServer(Network("udp"), Address(addr))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != SchemeUDP {
+		t.Errorf("expect %s, got %s", SchemeUDP, u.Scheme)
+	}
+	go srv.Start(context.Background())
+	defer srv.Stop(context.Background())
+
+	c := NewClient(WithEndpoint(u.String()), WithHeartbeat(10*time.Millisecond, time.Second))
+	defer c.Close()
+	invokeUntilReady(t, c)
+}
+
+func TestServerUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gnet.sock")
+	srv := NewServer(Network("unix"), Address(path))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != SchemeUnix+"://"+path {
+		t.Errorf("expect %s://%s, got %s", SchemeUnix, path, u)
+	}
+	go srv.Start(context.Background())
+	defer srv.Stop(context.Background())
+
+	c := NewClient(WithEndpoint(u.String()))
+	defer c.Close()
+	invokeUntilReady(t, c)
+}
@@ -2,28 +2,35 @@ package gnet
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/panjf2000/ants/v2"
 	"github.com/panjf2000/gnet/v2"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	gproto "google.golang.org/protobuf/proto"
 
+	kerrors "github.com/yola1107/kratos/v2/errors"
 	ic "github.com/yola1107/kratos/v2/internal/context"
 	"github.com/yola1107/kratos/v2/internal/endpoint"
 	"github.com/yola1107/kratos/v2/internal/host"
 	"github.com/yola1107/kratos/v2/internal/matcher"
+	"github.com/yola1107/kratos/v2/library/work"
 	"github.com/yola1107/kratos/v2/log"
 	"github.com/yola1107/kratos/v2/middleware"
 	"github.com/yola1107/kratos/v2/transport"
+	"github.com/yola1107/kratos/v2/transport/session"
 	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
 )
 
+// ErrServerBusy answers the offloaded requests no worker can take.
+var ErrServerBusy = kerrors.ServiceUnavailable("SERVER_BUSY", "gnet: no worker available")
+
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
@@ -33,7 +40,9 @@ var (
 // ServerOption configures the gnet server.
 type ServerOption func(*Server)
 
-// Network sets the server network scheme (tcp, tcp4, tcp6, udp...).
+// Network sets the server network (tcp, tcp4, tcp6, udp, udp4, udp6, unix).
+// On udp each datagram carries one Payload and is handled on the event loop,
+// on unix the address is the socket path.
 func Network(network string) ServerOption {
 	return func(s *Server) {
 		s.network = network
@@ -73,6 +82,94 @@ func Middleware(m ...middleware.Middleware) ServerOption {
 	}
 }
 
+// Codec sets the wire codec, defaults to DefaultCodec.
+func Codec(c tcpproto.Codec) ServerOption {
+	return func(s *Server) {
+		s.codec = c
+	}
+}
+
+// IdleTimeout closes sessions that receive nothing within d, zero disables it.
+func IdleTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idleTimeout = d
+	}
+}
+
+// WorkLoop offloads request handling from the event loop to loop,
+// responses are written back with AsyncWrite. The event loop never waits for
+// a worker, requests arriving while the server keeps every worker of loop busy
+// are answered with ErrServerBusy.
+func WorkLoop(loop work.Loop) ServerOption {
+	return func(s *Server) {
+		s.submit = bounded(func() int { return loop.Monitor().Capacity }, func(task func()) error {
+			loop.Post(task)
+			return nil
+		})
+	}
+}
+
+// WorkPool offloads request handling from the event loop to an ants pool,
+// responses are written back with AsyncWrite. The event loop never waits for
+// a worker, requests arriving while the server keeps every worker of pool busy
+// are answered with ErrServerBusy. Create a pool shared with other submitters
+// with ants.WithNonblocking(true), so losing the race for its last worker fails
+// instead of blocking.
+func WorkPool(pool *ants.Pool) ServerOption {
+	return func(s *Server) {
+		s.submit = bounded(pool.Cap, pool.Submit)
+	}
+}
+
+// bounded wraps submit to fail with ErrServerBusy instead of waiting for a
+// worker once size tasks run, a size below one is unbounded.
+func bounded(size func() int, submit func(task func()) error) func(task func()) error {
+	var running atomic.Int32
+	return func(task func()) error {
+		n := size()
+		if n <= 0 {
+			return submit(task)
+		}
+		if int(running.Add(1)) > n {
+			running.Add(-1)
+			return ErrServerBusy
+		}
+		err := submit(func() {
+			defer running.Add(-1)
+			task()
+		})
+		if err != nil {
+			running.Add(-1)
+		}
+		return err
+	}
+}
+
+// MaxPending bounds the offloaded requests queued per connection, decoding of a
+// connection is paused while its queue is full. Defaults to 128.
+func MaxPending(n int) ServerOption {
+	return func(s *Server) {
+		s.maxPending = n
+	}
+}
+
+// MaxInbound bounds the bytes buffered for a paused connection. gnet keeps
+// reading while decoding is paused, a connection buffering more is closed.
+// Defaults to twice the max frame size of the codec.
+func MaxInbound(n int) ServerOption {
+	return func(s *Server) {
+		s.maxInbound = n
+	}
+}
+
+// SessionHook adds hooks called with every session opened and closed,
+// after the hooks of the registered services. Either may be nil.
+func SessionHook(onOpen, onClose session.Hook) ServerOption {
+	return func(s *Server) {
+		s.AddSessionHook(onOpen, onClose)
+	}
+}
+
 // Options forwards raw gnet options.
 func Options(opts ...gnet.Option) ServerOption {
 	return func(s *Server) {
@@ -93,8 +190,17 @@ type Server struct {
 	timeout    time.Duration
 	middleware matcher.Matcher
 	opts       []gnet.Option
-
-	srv *service
+	codec      tcpproto.Codec
+
+	datagram    bool
+	idleTimeout time.Duration
+	submit      func(task func()) error
+	maxPending  int
+	maxInbound  int
+	sessions    sessionManager
+	openHooks   []session.Hook
+	closeHooks  []session.Hook
+	services    *serviceRegistry
 }
 
 // NewServer creates a gnet server with options.
@@ -105,12 +211,22 @@ func NewServer(opts ...ServerOption) *Server {
 		address:    ":3200",
 		timeout:    time.Second,
 		middleware: matcher.New(),
-		srv:        &service{md: make(map[int32]*MethodDesc)},
+		codec:      DefaultCodec,
+		maxPending: 128,
+		services:   newServiceRegistry(),
 	}
 	for _, o := range opts {
 		o(s)
 	}
+	if s.maxInbound <= 0 {
+		s.maxInbound = 2 * s.codec.MaxFrameSize()
+	}
 	s.protoAddr = s.buildProtoAddr()
+	network, _ := splitProtoAddr(s.protoAddr)
+	s.datagram = isDatagram(network)
+	if s.idleTimeout > 0 {
+		s.opts = append(s.opts, gnet.WithTicker(true))
+	}
 	return s
 }
 
@@ -123,9 +239,25 @@ func (s *Server) Use(selector string, m ...middleware.Middleware) {
 	s.middleware.Add(selector, m...)
 }
 
-// RegisterService registers a service with the gnet server.
-func (s *Server) RegisterService(sd *ServiceDesc, srv interface{}) {
-	s.srv.register(sd, srv)
+// RegisterService registers a service with the gnet server, several services
+// may be registered as long as their Ops are globally unique. onOpen and onClose
+// are called on the event loop when a session opens or closes.
+// It panics with the registration error on a duplicate service or conflicting Ops.
+func (s *Server) RegisterService(sd *ServiceDesc, srv interface{}, onOpen, onClose func(*Session)) {
+	if err := s.services.Register(sd.ServiceName, sd.HandlerType, srv, sd.Methods, onOpen, onClose); err != nil {
+		panic(fmt.Errorf("gnet: Server.RegisterService: %w", err))
+	}
+}
+
+// AddSessionHook adds hooks like the SessionHook option, it must be called
+// before the server starts, e.g. by the generated socket registrations.
+func (s *Server) AddSessionHook(onOpen, onClose session.Hook) {
+	if onOpen != nil {
+		s.openHooks = append(s.openHooks, onOpen)
+	}
+	if onClose != nil {
+		s.closeHooks = append(s.closeHooks, onClose)
+	}
 }
 
 // Endpoint returns a real address to registry endpoint.
@@ -152,53 +284,174 @@ func (s *Server) Stop(ctx context.Context) error {
 	return gnet.Stop(ctx, s.protoAddr)
 }
 
+// Session returns the open session of id.
+func (s *Server) Session(id string) (*Session, bool) {
+	return s.sessions.get(id)
+}
+
+// Sessions returns the number of open sessions.
+func (s *Server) Sessions() int {
+	return int(s.sessions.count.Load())
+}
+
+// Push pushes msg to the session of id.
+func (s *Server) Push(ctx context.Context, id string, cmd int32, msg gproto.Message) error {
+	sess, ok := s.sessions.get(id)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return sess.Push(cmd, msg)
+}
+
+// Broadcast pushes msg to all sessions, it returns the first push error.
+func (s *Server) Broadcast(ctx context.Context, cmd int32, msg gproto.Message) error {
+	p, err := pushPayload(cmd, msg)
+	if err != nil {
+		return err
+	}
+	var first error
+	s.sessions.forEach(func(sess *Session) {
+		if err := sess.write(p); err != nil && first == nil {
+			first = err
+		}
+	})
+	return first
+}
+
+// Kick closes the session of id, the close callbacks run once the connection exits.
+func (s *Server) Kick(id string) error {
+	sess, ok := s.sessions.get(id)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return sess.Close("kicked")
+}
+
+// OnOpen is triggered when a connection is opened.
+func (s *Server) OnOpen(c gnet.Conn) (out []byte, action gnet.Action) {
+	sess := newSession(c, s.codec)
+	c.SetContext(sess)
+	s.sessions.add(sess)
+	log.Infof("[gnet] session open: id=%s remote=%s sessions=%d", sess.id, sess.remote, s.Sessions())
+	for _, srv := range s.services.Services {
+		if srv.OnOpen != nil {
+			safeCall(func() { srv.OnOpen(sess) })
+		}
+	}
+	for _, fn := range s.openHooks {
+		safeCall(func() { fn(sess) })
+	}
+	return nil, gnet.None
+}
+
+// OnClose is triggered when a connection is closed.
+func (s *Server) OnClose(c gnet.Conn, err error) (action gnet.Action) {
+	sess, ok := c.Context().(*Session)
+	if !ok {
+		return gnet.None
+	}
+	sess.closed.Store(true)
+	for _, srv := range s.services.Services {
+		if srv.OnClose != nil {
+			safeCall(func() { srv.OnClose(sess) })
+		}
+	}
+	for _, fn := range s.closeHooks {
+		safeCall(func() { fn(sess) })
+	}
+	s.sessions.delete(sess)
+	log.Infof("[gnet] session closed: id=%s err=%v sessions=%d", sess.id, err, s.Sessions())
+	return gnet.None
+}
+
+// OnTick closes idle sessions when IdleTimeout is set.
+func (s *Server) OnTick() (delay time.Duration, action gnet.Action) {
+	if s.idleTimeout <= 0 {
+		return time.Hour, gnet.None
+	}
+	now := time.Now()
+	s.sessions.forEach(func(sess *Session) {
+		if now.Sub(sess.LastActive()) > s.idleTimeout {
+			_ = sess.Close("idle timeout")
+		}
+	})
+	return min(s.idleTimeout/2, time.Second), gnet.None
+}
+
 // OnTraffic is triggered when data is available.
 func (s *Server) OnTraffic(c gnet.Conn) (action gnet.Action) {
+	if s.datagram {
+		return s.onDatagram(c)
+	}
+	sess, _ := c.Context().(*Session)
+	if sess != nil {
+		sess.touch()
+	}
 	for {
-		if c.InboundBuffered() < 4 {
+		if s.submit != nil && sess != nil && sess.pause(s.maxPending) {
+			if n := c.InboundBuffered(); n > s.maxInbound {
+				log.Warnf("[gnet] session %s buffered %d bytes over %d while paused, closing", sess.id, n, s.maxInbound)
+				return gnet.Close
+			}
+			// resumed by Wake once the queue drains
 			return gnet.None
 		}
-		header, err := c.Peek(4)
+		hs := s.codec.HeaderSize()
+		if c.InboundBuffered() < hs {
+			return gnet.None
+		}
+		header, err := c.Peek(hs)
 		if err != nil {
 			log.Warnf("[gnet] peek header error: %v", err)
 			return gnet.Close
 		}
-		size := int(binary.BigEndian.Uint32(header))
-		if size <= 0 {
-			log.Warnf("[gnet] invalid frame size=%d", size)
+		size, err := s.codec.FrameSize(header)
+		if err != nil {
+			log.Warnf("[gnet] invalid frame header: %v", err)
 			return gnet.Close
 		}
-		if c.InboundBuffered() < 4+size {
+		if c.InboundBuffered() < size {
 			return gnet.None
 		}
-		if _, err = c.Discard(4); err != nil {
-			log.Warnf("[gnet] discard header error: %v", err)
-			return gnet.Close
-		}
 		frame, err := c.Peek(size)
 		if err != nil {
 			log.Warnf("[gnet] peek frame error: %v", err)
 			return gnet.Close
 		}
 		p := &tcpproto.Payload{}
-		if err := gproto.Unmarshal(frame, p); err != nil {
+		err = s.codec.Unmarshal(frame, p)
+		// the inbound buffer is reused after Discard
+		p.CloneBody()
+		if _, derr := c.Discard(size); derr != nil {
+			log.Warnf("[gnet] discard frame error: %v", derr)
+			return gnet.Close
+		}
+		if err != nil {
 			log.Warnf("[gnet] unmarshal payload error: %v", err)
-			_, _ = c.Discard(size)
+			out, err := badPayload(s.codec, p, err)
+			if err != nil {
+				log.Warnf("[gnet] encode payload error: %v", err)
+				continue
+			}
+			if _, err = c.Write(out); err != nil {
+				log.Warnf("[gnet] write response error: %v", err)
+				return gnet.Close
+			}
 			continue
 		}
-		if _, err = c.Discard(size); err != nil {
-			log.Warnf("[gnet] discard frame error: %v", err)
-			return gnet.Close
+		if s.submit != nil && sess != nil {
+			s.dispatch(sess, p)
+			continue
 		}
 
-		resp, err := s.handlePayload(c, p)
+		resp, err := s.handlePayload(sess, c.RemoteAddr(), p)
 		if err != nil {
 			log.Warnf("[gnet] handle payload error: %v", err)
 		}
 		if resp == nil {
 			continue
 		}
-		out, err := encodePayload(resp)
+		out, err := encodeResponse(s.codec, resp)
 		if err != nil {
 			log.Warnf("[gnet] encode payload error: %v", err)
 			continue
@@ -210,12 +463,65 @@ func (s *Server) OnTraffic(c gnet.Conn) (action gnet.Action) {
 	}
 }
 
-func (s *Server) handlePayload(_ gnet.Conn, p *tcpproto.Payload) (*tcpproto.Payload, error) {
+// onDatagram handles one datagram and writes the response back to its sender.
+func (s *Server) onDatagram(c gnet.Conn) gnet.Action {
+	buf, err := c.Next(-1)
+	if err != nil {
+		log.Warnf("[gnet] read datagram error: %v", err)
+		return gnet.None
+	}
+	size, err := s.codec.FrameSize(buf)
+	if err == nil && size != len(buf) {
+		err = tcpproto.ErrProtoPackLen
+	}
+	if err != nil {
+		log.Warnf("[gnet] invalid datagram from %s: %v", c.RemoteAddr(), err)
+		return gnet.None
+	}
+	p := &tcpproto.Payload{}
+	if err = s.codec.Unmarshal(buf, p); err != nil {
+		log.Warnf("[gnet] unmarshal payload error: %v", err)
+		out, err := badPayload(s.codec, p, err)
+		if err != nil {
+			log.Warnf("[gnet] encode payload error: %v", err)
+			return gnet.None
+		}
+		if _, err = c.Write(out); err != nil {
+			log.Warnf("[gnet] write response error: %v", err)
+		}
+		return gnet.None
+	}
+	// the read buffer is shared by the event loop
+	p.CloneBody()
+
+	resp, err := s.handlePayload(nil, c.RemoteAddr(), p)
+	if err != nil {
+		log.Warnf("[gnet] handle payload error: %v", err)
+	}
+	if resp == nil {
+		return gnet.None
+	}
+	out, err := encodeResponse(s.codec, resp)
+	if err != nil {
+		log.Warnf("[gnet] encode payload error: %v", err)
+		return gnet.None
+	}
+	if _, err = c.Write(out); err != nil {
+		log.Warnf("[gnet] write response error: %v", err)
+	}
+	return gnet.None
+}
+
+func (s *Server) handlePayload(sess *Session, remote net.Addr, p *tcpproto.Payload) (*tcpproto.Payload, error) {
 	ctx, cancel := ic.Merge(context.Background(), s.baseCtx)
 	defer cancel()
+	if sess != nil {
+		ctx = NewSessionContext(ctx, sess)
+	}
 
 	tr := &Transport{
-		reqHeader:  headerCarrier{},
+		remoteAddr: remote,
+		reqHeader:  headerFromPayload(p.Header),
 		respHeader: headerCarrier{},
 	}
 	if s.endpoint != nil {
@@ -233,6 +539,7 @@ func (s *Server) handlePayload(_ gnet.Conn, p *tcpproto.Payload) (*tcpproto.Payl
 		p.Type = int32(tcpproto.Pong)
 		p.Code = 0
 		p.Body = nil
+		p.Header = nil
 		return p, nil
 	case tcpproto.Request:
 		return s.operate(ctx, p)
@@ -244,45 +551,91 @@ func (s *Server) handlePayload(_ gnet.Conn, p *tcpproto.Payload) (*tcpproto.Payl
 func (s *Server) operate(ctx context.Context, p *tcpproto.Payload) (*tcpproto.Payload, error) {
 	reqBody := &tcpproto.Body{}
 	if err := gproto.Unmarshal(p.Body, reqBody); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "failed to unmarshal body: %v", err)
+		return errorResponse(p, 0, kerrors.BadRequest("", fmt.Sprintf("failed to unmarshal body: %v", err)))
 	}
-	md, ok := s.srv.md[reqBody.Ops]
+	md, ok := s.services.Methods[reqBody.Ops]
 	if !ok {
-		p.Type = int32(tcpproto.Response)
-		p.Place = tcpproto.PlaceServer
-		p.Code = int32(codes.Unimplemented)
-		p.Body = nil
-		return p, status.Errorf(codes.Unimplemented, "Unimplemented Ops=%d", reqBody.Ops)
+		return errorResponse(p, reqBody.Ops, kerrors.New(http.StatusNotImplemented, "", fmt.Sprintf("Unimplemented Ops=%d", reqBody.Ops)))
 	}
-	fullMethod := fmt.Sprintf("/%s/%s", s.srv.name, md.MethodName)
+	fullMethod := fmt.Sprintf("/%s/%s", md.Service.Name, md.Name)
 	tr := transportContext(ctx)
 	if tr != nil {
 		tr.operation = fullMethod
 	}
 
-	reply, errCode := md.Handler(s.srv.server, ctx, reqBody.Data, s.unaryServerInterceptor())
-	st, _ := status.FromError(errCode)
+	reply, errCode := md.Desc.Handler(md.Service.Server, ctx, reqBody.Data, s.unaryServerInterceptor())
+	body := &tcpproto.Body{Ops: reqBody.Ops, Data: reply}
+	p.Code, body.Error = tcpproto.FromError(errCode)
 
-	respBody, marshalErr := gproto.Marshal(&tcpproto.Body{Ops: reqBody.Ops, Data: reply})
+	respBody, marshalErr := gproto.Marshal(body)
 	if marshalErr != nil {
 		return nil, marshalErr
 	}
 
 	p.Type = int32(tcpproto.Response)
 	p.Place = tcpproto.PlaceServer
-	p.Code = int32(st.Code())
 	p.Body = respBody
+	p.Header = nil
+	if tr != nil {
+		p.Header = tr.respHeader.payloadHeader()
+	}
 	return p, errCode
 }
 
+// errorResponse turns p into a response carrying err, so the caller is
+// answered instead of waiting for its timeout.
+func errorResponse(p *tcpproto.Payload, ops int32, err error) (*tcpproto.Payload, error) {
+	body := &tcpproto.Body{Ops: ops}
+	p.Code, body.Error = tcpproto.FromError(err)
+	respBody, marshalErr := gproto.Marshal(body)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	p.Type = int32(tcpproto.Response)
+	p.Place = tcpproto.PlaceServer
+	p.Body = respBody
+	p.Header = nil
+	return p, err
+}
+
+// badPayload encodes the answer to a frame that fails to decode, for the op
+// and seq decoded before the failure.
+func badPayload(codec tcpproto.Codec, p *tcpproto.Payload, err error) ([]byte, error) {
+	resp, err := errorResponse(p, p.Op, kerrors.BadRequest("", fmt.Sprintf("failed to unmarshal payload: %v", err)))
+	if resp == nil {
+		return nil, err
+	}
+	return encodeResponse(codec, resp)
+}
+
+// encodeResponse encodes resp. A response the codec cannot encode, e.g. one
+// with reply headers in the LayoutHeader layout, is answered with the error.
+func encodeResponse(codec tcpproto.Codec, resp *tcpproto.Payload) ([]byte, error) {
+	out, err := codec.Marshal(resp)
+	if err == nil || tcpproto.Pattern(resp.Type) != tcpproto.Response {
+		return out, err
+	}
+	log.Warnf("[gnet] encode response error: %v", err)
+	resp, err = errorResponse(resp, resp.Op, kerrors.InternalServer("", fmt.Sprintf("failed to encode reply: %v", err)))
+	if resp == nil {
+		return nil, err
+	}
+	return codec.Marshal(resp)
+}
+
 func (s *Server) listenAndEndpoint() error {
 	if s.endpoint == nil {
-		addr, err := host.Extract(s.address, nil)
+		network, address := splitProtoAddr(s.protoAddr)
+		if network == "unix" {
+			s.endpoint = &url.URL{Scheme: SchemeUnix, Path: address}
+			return s.err
+		}
+		addr, err := host.Extract(address, nil)
 		if err != nil {
 			s.err = err
 			return err
 		}
-		s.endpoint = endpoint.NewEndpoint(endpoint.Scheme("gnet", false), addr)
+		s.endpoint = endpoint.NewEndpoint(schemeOf(network), addr)
 	}
 	return s.err
 }
@@ -314,8 +667,8 @@ func (s *Server) unaryServerInterceptor() UnaryServerInterceptor {
 		reply, err := h(ctx, req)
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || strings.HasPrefix(err.Error(), "panic:") {
-				st, _ := status.FromError(err)
-				log.Errorf("[gnet] unary method=[%s] unexpected err. st.Code=%d(%v) st.Message=%v", info.FullMethod, st.Code(), st.Code(), err)
+				e := kerrors.FromError(err)
+				log.Errorf("[gnet] unary method=[%s] unexpected err. code=%d reason=%s message=%v", info.FullMethod, e.Code, e.Reason, err)
 			}
 			return nil, err
 		}
@@ -327,15 +680,13 @@ func (s *Server) unaryServerInterceptor() UnaryServerInterceptor {
 	}
 }
 
-func encodePayload(p *tcpproto.Payload) ([]byte, error) {
-	body, err := gproto.Marshal(p)
-	if err != nil {
-		return nil, err
-	}
-	buf := make([]byte, 4+len(body))
-	binary.BigEndian.PutUint32(buf[:4], uint32(len(body)))
-	copy(buf[4:], body)
-	return buf, nil
+func safeCall(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[gnet] session callback panic: %v", r)
+		}
+	}()
+	fn()
 }
 
 func transportContext(ctx context.Context) *Transport {
@@ -0,0 +1,613 @@
+package gnet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	kerrors "github.com/yola1107/kratos/v2/errors"
+	"github.com/yola1107/kratos/v2/library/work"
+	"github.com/yola1107/kratos/v2/metadata"
+	"github.com/yola1107/kratos/v2/middleware"
+	mmd "github.com/yola1107/kratos/v2/middleware/metadata"
+	"github.com/yola1107/kratos/v2/transport"
+	"github.com/yola1107/kratos/v2/transport/session"
+	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
+)
+
+type testService interface{}
+
+type testServer struct{}
+
+var testServiceDesc = ServiceDesc{
+	ServiceName: "test.Service",
+	HandlerType: (*testService)(nil),
+	Methods: []MethodDesc{
+		{
+			Ops:        1,
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+				return data, nil
+			},
+		},
+	},
+}
+
+func freeAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+// startTestServer runs srv in the background and waits until it accepts connections.
+func startTestServer(t *testing.T, srv *Server, addr string) {
+	go func() {
+		if err := srv.Start(context.Background()); err != nil {
+			t.Error(err)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server not started")
+}
+
+func TestServerRegisterDuplicate(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "duplicate service registration") {
+			t.Errorf("expect a duplicate registration panic, got %v", err)
+		}
+	}()
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+}
+
+func TestServerSession(t *testing.T) {
+	addr := freeAddr(t)
+	opened := make(chan *Session, 2)
+	closed := make(chan *Session, 2)
+	srv := NewServer(Address(addr), IdleTimeout(100*time.Millisecond))
+	srv.RegisterService(&testServiceDesc, &testServer{},
+		func(sess *Session) { opened <- sess },
+		func(sess *Session) { closed <- sess },
+	)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+	// drain the probe connection
+	<-opened
+	<-closed
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var sess *Session
+	select {
+	case sess = <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("open callback not called")
+	}
+	sess.Set("uid", 1)
+	if v, ok := sess.Get("uid"); !ok || v != 1 {
+		t.Errorf("expect 1, got %v", v)
+	}
+
+	if err = srv.Push(context.Background(), sess.ID(), 7, wrapperspb.String("hi")); err != nil {
+		t.Fatal(err)
+	}
+	p := &tcpproto.Payload{}
+	if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+		t.Fatal(err)
+	}
+	body := &tcpproto.Body{}
+	v := &wrapperspb.StringValue{}
+	_ = gproto.Unmarshal(p.Body, body)
+	_ = gproto.Unmarshal(body.Data, v)
+	if p.Type != int32(tcpproto.Push) || p.Op != 7 || v.Value != "hi" {
+		t.Errorf("unexpected push %v %v", p, v)
+	}
+
+	// no traffic closes the session
+	select {
+	case c := <-closed:
+		if c.ID() != sess.ID() || !c.Closed() {
+			t.Errorf("expect closed session %s, got %s", sess.ID(), c.ID())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle session not closed")
+	}
+	if err = srv.Push(context.Background(), sess.ID(), 7, wrapperspb.String("hi")); err != ErrSessionNotFound {
+		t.Errorf("expect %v, got %v", ErrSessionNotFound, err)
+	}
+}
+
+func TestServerKick(t *testing.T) {
+	addr := freeAddr(t)
+	closed := make(chan string, 2)
+	srv := NewServer(Address(addr))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, func(sess *Session) { closed <- sess.ID() })
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+	<-closed
+
+	c := NewClient(WithEndpoint(addr))
+	defer c.Close()
+	reply := &wrapperspb.StringValue{}
+	if err := c.Invoke(context.Background(), 1, wrapperspb.String("echo"), reply, Operation("/test.Service/Echo")); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "echo" {
+		t.Errorf("expect echo, got %v", reply.Value)
+	}
+	if n := srv.Sessions(); n != 1 {
+		t.Fatalf("expect 1 session, got %d", n)
+	}
+	var id string
+	srv.sessions.forEach(func(sess *Session) { id = sess.ID() })
+	if err := srv.Kick(id); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-closed:
+		if got != id {
+			t.Errorf("expect %s, got %s", id, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("kicked session not closed")
+	}
+}
+
+func TestServerWorkPool(t *testing.T) {
+	block := make(chan struct{})
+	desc := ServiceDesc{
+		ServiceName: "test.Work",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{
+				Ops:        1,
+				MethodName: "Sleep",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					v := &wrapperspb.Int64Value{}
+					_ = gproto.Unmarshal(data, v)
+					time.Sleep(time.Duration(v.Value) * time.Millisecond)
+					return data, nil
+				},
+			},
+			{
+				Ops:        2,
+				MethodName: "Block",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					<-block
+					return data, nil
+				},
+			},
+		},
+	}
+	pool, err := ants.NewPool(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Release()
+
+	addr := freeAddr(t)
+	srv := NewServer(Address(addr), WorkPool(pool), MaxPending(2), Timeout(5*time.Second))
+	srv.RegisterService(&desc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+
+	// a blocked handler does not stall other connections on the event loop
+	blocked, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocked.Close()
+	if err = writeRequest(blocked, 2, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// pipelined requests are answered in order even though earlier ones are slower
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	const n = 10
+	for i := 1; i <= n; i++ {
+		if err = writeRequest(conn, 1, int32(i), int64(n-i)*2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for i := 1; i <= n; i++ {
+		p := &tcpproto.Payload{}
+		if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Seq != int32(i) || p.Code != 0 {
+			t.Fatalf("expect seq %d, got %d code %d", i, p.Seq, p.Code)
+		}
+	}
+
+	close(block)
+	p := &tcpproto.Payload{}
+	_ = blocked.SetReadDeadline(time.Now().Add(time.Second))
+	if err = tcpproto.ReadFrame(DefaultCodec, blocked, p); err != nil || p.Seq != 1 {
+		t.Fatalf("expect blocked response, got %v %v", p, err)
+	}
+}
+
+func TestServerWorkBusy(t *testing.T) {
+	entered, block := make(chan struct{}, 1), make(chan struct{})
+	desc := ServiceDesc{
+		ServiceName: "test.Work",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{
+				Ops:        1,
+				MethodName: "Block",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					entered <- struct{}{}
+					<-block
+					return data, nil
+				},
+			},
+		},
+	}
+	pool, err := ants.NewPool(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Release()
+	loop := work.NewLoop(work.WithSize(1))
+	if err = loop.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer loop.Stop()
+
+	for name, opt := range map[string]ServerOption{"work pool": WorkPool(pool), "work loop": WorkLoop(loop)} {
+		t.Run(name, func(t *testing.T) {
+			addr := freeAddr(t)
+			srv := NewServer(Address(addr), opt, Timeout(5*time.Second))
+			srv.RegisterService(&desc, &testServer{}, nil, nil)
+			startTestServer(t, srv, addr)
+			defer srv.Stop(context.Background())
+
+			blocked, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer blocked.Close()
+			if err = writeRequest(blocked, 1, 1, 0); err != nil {
+				t.Fatal(err)
+			}
+			<-entered
+
+			// the only worker is taken, the event loop answers busy instead of waiting
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if err = writeRequest(conn, 1, 2, 0); err != nil {
+				t.Fatal(err)
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			p := &tcpproto.Payload{}
+			if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Seq != 2 || p.Code != 503 {
+				t.Fatalf("expect seq 2 code 503, got seq %d code %d", p.Seq, p.Code)
+			}
+
+			block <- struct{}{}
+			_ = blocked.SetReadDeadline(time.Now().Add(time.Second))
+			if err = tcpproto.ReadFrame(DefaultCodec, blocked, p); err != nil || p.Seq != 1 || p.Code != 0 {
+				t.Fatalf("expect blocked response, got %v %v", p, err)
+			}
+		})
+	}
+}
+
+func TestServerMaxInbound(t *testing.T) {
+	block := make(chan struct{})
+	desc := ServiceDesc{
+		ServiceName: "test.Work",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{
+				Ops:        1,
+				MethodName: "Block",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					<-block
+					return data, nil
+				},
+			},
+		},
+	}
+	pool, err := ants.NewPool(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Release()
+
+	addr := freeAddr(t)
+	srv := NewServer(Address(addr), WorkPool(pool), MaxPending(1), MaxInbound(1024), Timeout(5*time.Second))
+	srv.RegisterService(&desc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+	defer close(block)
+
+	// a client flooding a paused connection is closed instead of buffered without bound
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(3 * time.Second))
+	for i := 1; i <= 1000; i++ {
+		if err = writeRequest(conn, 1, int32(i), 0); err != nil {
+			break
+		}
+	}
+	if _, err = conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) && !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("expect connection closed by server, got %v", err)
+	}
+}
+
+func writeRequest(conn net.Conn, ops, seq int32, v int64) error {
+	data, _ := gproto.Marshal(wrapperspb.Int64(v))
+	body, _ := gproto.Marshal(&tcpproto.Body{Ops: ops, Data: data})
+	return tcpproto.WriteFrame(DefaultCodec, conn, &tcpproto.Payload{Op: ops, Type: int32(tcpproto.Request), Seq: seq, Body: body})
+}
+
+func TestServerTransportHeader(t *testing.T) {
+	pool, err := ants.NewPool(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Release()
+	// offloaded handlers read the remote address off the event loop
+	for name, opts := range map[string][]ServerOption{
+		"event loop": nil,
+		"work pool":  {WorkPool(pool)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			type seen struct {
+				operation string
+				remote    net.Addr
+				md        string
+			}
+			got := make(chan seen, 1)
+			desc := ServiceDesc{
+				ServiceName: "test.Meta",
+				HandlerType: (*testService)(nil),
+				Methods: []MethodDesc{
+					{
+						Ops:        1,
+						MethodName: "Echo",
+						Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+							info := &UnaryServerInfo{Server: srv, FullMethod: "/test.Meta/Echo"}
+							return interceptor(ctx, data, info, func(ctx context.Context, req interface{}) ([]byte, error) {
+								tr, _ := transport.FromServerContext(ctx)
+								md, _ := metadata.FromServerContext(ctx)
+								got <- seen{tr.Operation(), tr.(Transporter).RemoteAddr(), md.Get("x-md-global-foo")}
+								tr.ReplyHeader().Set("x-reply", "ok")
+								return data, nil
+							})
+						},
+					},
+				},
+			}
+			addr := freeAddr(t)
+			srv := NewServer(append(opts, Address(addr), Middleware(mmd.Server()))...)
+			srv.RegisterService(&desc, &testServer{}, nil, nil)
+			startTestServer(t, srv, addr)
+			defer srv.Stop(context.Background())
+
+			var replyHeader, operation string
+			c := NewClient(WithEndpoint(addr), WithMiddleware(mmd.Client(), func(h middleware.Handler) middleware.Handler {
+				return func(ctx context.Context, req any) (any, error) {
+					reply, err := h(ctx, req)
+					tr, _ := transport.FromClientContext(ctx)
+					operation, replyHeader = tr.Operation(), tr.ReplyHeader().Get("x-reply")
+					return reply, err
+				}
+			}))
+			defer c.Close()
+
+			ctx := metadata.AppendToClientContext(context.Background(), "x-md-global-foo", "bar")
+			if err := c.Invoke(ctx, 1, wrapperspb.String("x"), nil, Operation("/test.Meta/Echo")); err != nil {
+				t.Fatal(err)
+			}
+			s := <-got
+			if s.operation != "/test.Meta/Echo" || s.remote == nil || s.md != "bar" {
+				t.Errorf("unexpected server transport %+v", s)
+			}
+			if operation != "/test.Meta/Echo" || replyHeader != "ok" {
+				t.Errorf("unexpected client transport %s %s", operation, replyHeader)
+			}
+		})
+	}
+}
+
+func TestServerTransportHeaderLayout(t *testing.T) {
+	desc := ServiceDesc{
+		ServiceName: "test.Meta",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{
+				Ops:        1,
+				MethodName: "Echo",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					tr, _ := transport.FromServerContext(ctx)
+					tr.ReplyHeader().Set("x-reply", "ok")
+					return data, nil
+				},
+			},
+		},
+	}
+	// the fixed header layout has no room for headers
+	codec := tcpproto.NewCodec()
+	addr := freeAddr(t)
+	srv := NewServer(Address(addr), Codec(codec))
+	srv.RegisterService(&desc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+
+	c := NewClient(WithEndpoint(addr), WithCodec(codec))
+	defer c.Close()
+
+	ctx := metadata.AppendToClientContext(context.Background(), "x-md-global-foo", "bar")
+	err := c.Invoke(ctx, 1, wrapperspb.String("x"), nil, Operation("/test.Meta/Echo"))
+	if !errors.Is(err, tcpproto.ErrProtoHeader) {
+		t.Errorf("expect %v, got %v", tcpproto.ErrProtoHeader, err)
+	}
+	err = c.Invoke(context.Background(), 1, wrapperspb.String("x"), nil, Operation("/test.Meta/Echo"))
+	if kerrors.Code(err) != http.StatusInternalServerError {
+		t.Errorf("expect the reply header encode error, got %v", err)
+	}
+}
+
+func TestServerBadPayload(t *testing.T) {
+	addr := freeAddr(t)
+	srv := NewServer(Address(addr))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// op and seq decode, the truncated varint after them does not
+	data, _ := gproto.Marshal(&tcpproto.Payload{Op: 1, Type: int32(tcpproto.Request), Seq: 9})
+	data = append(data, 0x08, 0xff)
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	if _, err = conn.Write(append(frame, data...)); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	p := &tcpproto.Payload{}
+	if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+		t.Fatal(err)
+	}
+	body := &tcpproto.Body{}
+	_ = gproto.Unmarshal(p.Body, body)
+	if p.Seq != 9 || p.Type != int32(tcpproto.Response) || kerrors.Code(tcpproto.ToError(p.Code, body.Error)) != http.StatusBadRequest {
+		t.Errorf("expect a bad request response for seq 9, got %v", p)
+	}
+
+	// the connection stays usable
+	if err = writeRequest(conn, 1, 10, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Seq != 10 || p.Code != 0 {
+		t.Errorf("expect the response of seq 10, got %v", p)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	desc := ServiceDesc{
+		ServiceName: "test.Fail",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{
+				Ops:        1,
+				MethodName: "Fail",
+				Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+					return nil, kerrors.NotFound("TABLE_NOT_FOUND", "table not found").WithMetadata(map[string]string{"table": "7"})
+				},
+			},
+		},
+	}
+	addr := freeAddr(t)
+	srv := NewServer(Address(addr))
+	srv.RegisterService(&desc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+
+	c := NewClient(WithEndpoint(addr))
+	defer c.Close()
+
+	e := kerrors.FromError(c.Invoke(context.Background(), 1, wrapperspb.String("x"), nil, Operation("/test.Fail/Fail")))
+	if e.Code != 404 || e.Reason != "TABLE_NOT_FOUND" || e.Message != "table not found" || e.Metadata["table"] != "7" {
+		t.Errorf("expect the structured error, got %v", e)
+	}
+	if err := c.Invoke(context.Background(), 9, wrapperspb.String("x"), nil, Operation("/test.Fail/Unknown")); kerrors.Code(err) != 501 {
+		t.Errorf("expect code 501, got %v", err)
+	}
+
+	// a body that fails to unmarshal is answered with a bad request
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = tcpproto.WriteFrame(DefaultCodec, conn, &tcpproto.Payload{Type: int32(tcpproto.Request), Seq: 3, Body: []byte{0xff}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	p := &tcpproto.Payload{}
+	if err = tcpproto.ReadFrame(DefaultCodec, conn, p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Seq != 3 || p.Code != 400 {
+		t.Errorf("expect seq 3 code 400, got seq %d code %d", p.Seq, p.Code)
+	}
+}
+
+func TestServerSessionHook(t *testing.T) {
+	addr := freeAddr(t)
+	opened := make(chan session.Session, 2)
+	closed := make(chan session.Session, 2)
+	srv := NewServer(Address(addr), SessionHook(
+		func(s session.Session) { opened <- s },
+		func(s session.Session) { closed <- s },
+	))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	startTestServer(t, srv, addr)
+	defer srv.Stop(context.Background())
+
+	select {
+	case s := <-opened:
+		if _, ok := s.(*Session); !ok {
+			t.Errorf("expect *Session, got %T", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("open hook not called")
+	}
+	select {
+	case s := <-closed:
+		if !s.Closed() {
+			t.Error("expect session closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close hook not called")
+	}
+}
@@ -2,9 +2,8 @@ package gnet
 
 import (
 	"context"
-	"reflect"
 
-	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/transport/internal/ops"
 )
 
 // ServiceDesc describes a gnet service and its methods.
@@ -23,27 +22,10 @@ type MethodDesc struct {
 
 type methodHandler func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error)
 
-type service struct {
-	server interface{}
-	name   string
-	md     map[int32]*MethodDesc
-}
+type serviceRegistry = ops.Registry[MethodDesc, func(*Session)]
 
-func (s *service) register(sd *ServiceDesc, ss interface{}) {
-	ht := reflect.TypeOf(sd.HandlerType).Elem()
-	st := reflect.TypeOf(ss)
-	if !st.Implements(ht) {
-		log.Fatalf("gnet: Server.RegisterService found the handler of type %v that does not satisfy %v", st, ht)
-	}
-	if s.server != nil {
-		log.Fatalf("gnet: Server.RegisterService found duplicate service registration for %q", sd.ServiceName)
-	}
-	s.server = ss
-	s.name = sd.ServiceName
-	for i := range sd.Methods {
-		d := &sd.Methods[i]
-		s.md[d.Ops] = d
-	}
+func newServiceRegistry() *serviceRegistry {
+	return ops.NewRegistry[MethodDesc, func(*Session)](func(d *MethodDesc) (int32, string) { return d.Ops, d.MethodName })
 }
 
 // UnaryServerInfo provides information about current call.
@@ -0,0 +1,168 @@
+package gnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/panjf2000/gnet/v2"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/transport/session"
+	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
+)
+
+var (
+	// ErrSessionNotFound is returned when no session has the given id.
+	ErrSessionNotFound = errors.New("gnet: session not found")
+	// ErrSessionClosed is returned when writing to a closed session.
+	ErrSessionClosed = errors.New("gnet: session closed")
+)
+
+var _ session.Session = (*Session)(nil)
+
+// NewSessionContext returns a new context with the session.
+func NewSessionContext(ctx context.Context, sess *Session) context.Context {
+	return session.NewContext(ctx, sess)
+}
+
+// FromSessionContext returns the session of the current request.
+func FromSessionContext(ctx context.Context) (*Session, bool) {
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	sess, ok := s.(*Session)
+	return sess, ok
+}
+
+// Session is a client connection of the gnet server.
+type Session struct {
+	id      string
+	conn    gnet.Conn
+	remote  net.Addr // read on open, conn is only safe to use on its event loop
+	codec   tcpproto.Codec
+	attrs   sync.Map
+	lastAct atomic.Int64
+	closed  atomic.Bool
+
+	// offloaded requests, handled in order by one worker at a time
+	mu      sync.Mutex
+	queue   []*tcpproto.Payload
+	running bool
+	paused  bool
+}
+
+func newSession(conn gnet.Conn, codec tcpproto.Codec) *Session {
+	s := &Session{
+		id:     uuid.NewString(),
+		conn:   conn,
+		remote: conn.RemoteAddr(),
+		codec:  codec,
+	}
+	s.touch()
+	return s
+}
+
+func (s *Session) ID() string            { return s.id }
+func (s *Session) Closed() bool          { return s.closed.Load() }
+func (s *Session) LastActive() time.Time { return time.Unix(0, s.lastAct.Load()) }
+func (s *Session) RemoteAddr() net.Addr  { return s.remote }
+func (s *Session) GetRemoteIP() string   { return s.remote.String() }
+
+// Get returns the attribute of key.
+func (s *Session) Get(key string) (any, bool) { return s.attrs.Load(key) }
+
+// Set sets the attribute of key.
+func (s *Session) Set(key string, value any) { s.attrs.Store(key, value) }
+
+// Delete deletes the attribute of key.
+func (s *Session) Delete(key string) { s.attrs.Delete(key) }
+
+// Push sends msg to the client as a push of cmd, it's safe to call from any goroutine.
+func (s *Session) Push(cmd int32, msg gproto.Message) error {
+	p, err := pushPayload(cmd, msg)
+	if err != nil {
+		return err
+	}
+	return s.write(p)
+}
+
+// Close closes the connection, the close callbacks run once gnet reports the close.
+func (s *Session) Close(reason string) error {
+	if s.Closed() {
+		return nil
+	}
+	log.Infof("[gnet] closing session: id=%s, reason=%s", s.id, reason)
+	return s.conn.Close()
+}
+
+func (s *Session) write(p *tcpproto.Payload) error {
+	if s.Closed() {
+		return ErrSessionClosed
+	}
+	buf, err := encodeResponse(s.codec, p)
+	if err != nil {
+		return err
+	}
+	return s.conn.AsyncWrite(buf, nil)
+}
+
+func (s *Session) touch() {
+	s.lastAct.Store(time.Now().UnixNano())
+}
+
+func pushPayload(cmd int32, msg gproto.Message) (*tcpproto.Payload, error) {
+	data, err := gproto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	body, err := gproto.Marshal(&tcpproto.Body{Ops: cmd, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return &tcpproto.Payload{
+		Op:    cmd,
+		Place: tcpproto.PlaceServer,
+		Type:  int32(tcpproto.Push),
+		Body:  body,
+	}, nil
+}
+
+// sessionManager holds open sessions keyed by id.
+type sessionManager struct {
+	count    atomic.Int32
+	sessions sync.Map // id -> *Session
+}
+
+func (m *sessionManager) add(sess *Session) {
+	if _, loaded := m.sessions.LoadOrStore(sess.id, sess); !loaded {
+		m.count.Add(1)
+	}
+}
+
+func (m *sessionManager) delete(sess *Session) {
+	if _, loaded := m.sessions.LoadAndDelete(sess.id); loaded {
+		m.count.Add(-1)
+	}
+}
+
+func (m *sessionManager) get(id string) (*Session, bool) {
+	v, ok := m.sessions.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Session), true
+}
+
+func (m *sessionManager) forEach(fn func(*Session)) {
+	m.sessions.Range(func(_, v any) bool {
+		fn(v.(*Session))
+		return true
+	})
+}
@@ -1,13 +1,35 @@
 package gnet
 
-import "github.com/yola1107/kratos/v2/transport"
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/yola1107/kratos/v2/transport"
+	tcpproto "github.com/yola1107/kratos/v2/transport/tcp/proto"
+)
+
+// DefaultCodec is the 4-byte big-endian length prefixed protobuf Payload codec.
+var DefaultCodec = tcpproto.NewCodec(
+	tcpproto.WithLayout(tcpproto.LayoutProtobuf),
+	tcpproto.WithByteOrder(binary.BigEndian),
+	tcpproto.WithMaxBodySize(4<<20),
+)
 
 var _ transport.Transporter = (*Transport)(nil)
 
+// Transporter is gnet Transporter
+type Transporter interface {
+	transport.Transporter
+	RemoteAddr() net.Addr
+}
+
+var _ Transporter = (*Transport)(nil)
+
 // Transport implements transport.Transporter for gnet.
 type Transport struct {
 	endpoint   string
 	operation  string
+	remoteAddr net.Addr
 	reqHeader  headerCarrier
 	respHeader headerCarrier
 }
@@ -27,6 +49,9 @@ func (t *Transport) RequestHeader() transport.Header { return t.reqHeader }
 // ReplyHeader returns the reply header carrier.
 func (t *Transport) ReplyHeader() transport.Header { return t.respHeader }
 
+// RemoteAddr returns the peer address, the server address on the client side.
+func (t *Transport) RemoteAddr() net.Addr { return t.remoteAddr }
+
 type headerCarrier map[string][]string
 
 func (h headerCarrier) Get(key string) string {
@@ -55,3 +80,24 @@ func (h headerCarrier) Keys() []string {
 func (h headerCarrier) Values(key string) []string {
 	return h[key]
 }
+
+// headerFromPayload copies the payload header section into a carrier.
+func headerFromPayload(h map[string]*tcpproto.HeaderValues) headerCarrier {
+	hc := make(headerCarrier, len(h))
+	for k, v := range h {
+		hc[k] = append([]string(nil), v.GetValues()...)
+	}
+	return hc
+}
+
+// payloadHeader converts the carrier into the payload header section, nil when empty.
+func (h headerCarrier) payloadHeader() map[string]*tcpproto.HeaderValues {
+	if len(h) == 0 {
+		return nil
+	}
+	ph := make(map[string]*tcpproto.HeaderValues, len(h))
+	for k, v := range h {
+		ph[k] = &tcpproto.HeaderValues{Values: v}
+	}
+	return ph
+}
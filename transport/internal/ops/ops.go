@@ -0,0 +1,75 @@
+// Package ops holds the services registered with the socket transport servers,
+// their methods are keyed by globally unique ops.
+package ops
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Service is a registered service, H is the session callback type of the transport.
+type Service[H any] struct {
+	Name    string
+	Server  interface{}
+	OnOpen  H // 连接建立回调
+	OnClose H // 连接关闭回调
+}
+
+// Method binds a method desc M of the transport to the service it was registered with.
+type Method[M, H any] struct {
+	Desc    *M
+	Name    string
+	Service *Service[H]
+}
+
+// Registry holds the registered services and their methods.
+type Registry[M, H any] struct {
+	// key returns the ops and name of a method desc.
+	key      func(*M) (int32, string)
+	Services []*Service[H]
+	Methods  map[int32]*Method[M, H]
+}
+
+// NewRegistry creates a registry, key returns the ops and name of a method desc.
+func NewRegistry[M, H any](key func(*M) (int32, string)) *Registry[M, H] {
+	return &Registry[M, H]{key: key, Methods: make(map[int32]*Method[M, H])}
+}
+
+// Register registers the service name served by ss with its methods, ss must
+// implement the interface handlerType points to. Nothing is registered when
+// the name is taken or an ops conflicts.
+func (r *Registry[M, H]) Register(name string, handlerType, ss interface{}, methods []M, onOpen, onClose H) error {
+	ht := reflect.TypeOf(handlerType).Elem()
+	st := reflect.TypeOf(ss)
+	if !st.Implements(ht) {
+		return fmt.Errorf("found the handler of type %v that does not satisfy %v", st, ht)
+	}
+	for _, srv := range r.Services {
+		if srv.Name == name {
+			return fmt.Errorf("found duplicate service registration for %q", name)
+		}
+	}
+	seen := make(map[int32]string, len(methods))
+	for i := range methods {
+		ops, method := r.key(&methods[i])
+		if m, ok := r.Methods[ops]; ok {
+			return fmt.Errorf("found conflicting ops %d: %s/%s already registered by %s/%s", ops, name, method, m.Service.Name, m.Name)
+		}
+		if other, ok := seen[ops]; ok {
+			return fmt.Errorf("found conflicting ops %d: %s/%s and %s/%s", ops, name, method, name, other)
+		}
+		seen[ops] = method
+	}
+	srv := &Service[H]{
+		Name:    name,
+		Server:  ss,
+		OnOpen:  onOpen,
+		OnClose: onClose,
+	}
+	for i := range methods {
+		ops, method := r.key(&methods[i])
+		r.Methods[ops] = &Method[M, H]{Desc: &methods[i], Name: method, Service: srv}
+	}
+	r.Services = append(r.Services, srv)
+	return nil
+}
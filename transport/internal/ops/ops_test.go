@@ -0,0 +1,45 @@
+package ops
+
+import (
+	"strings"
+	"testing"
+)
+
+type testService interface{}
+
+type testMethod struct {
+	ops  int32
+	name string
+}
+
+func testKey(m *testMethod) (int32, string) { return m.ops, m.name }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry[testMethod, func()](testKey)
+	if err := r.Register("test.A", (*testService)(nil), struct{}{}, []testMethod{{1, "One"}, {2, "Two"}}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("test.B", (*testService)(nil), struct{}{}, []testMethod{{3, "Three"}}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Methods[3]; m == nil || m.Service.Name != "test.B" || m.Name != "Three" || m.Desc.ops != 3 {
+		t.Errorf("expect test.B/Three, got %+v", m)
+	}
+
+	if err := r.Register("test.B", (*testService)(nil), struct{}{}, []testMethod{{4, "Four"}}, nil, nil); err == nil {
+		t.Error("expect duplicate service registration")
+	}
+	err := r.Register("test.C", (*testService)(nil), struct{}{}, []testMethod{{4, "Four"}, {2, "Dup"}}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "test.A/Two") {
+		t.Errorf("expect conflict with test.A/Two, got %v", err)
+	}
+	if _, ok := r.Methods[4]; ok || len(r.Services) != 2 {
+		t.Error("failed registration must not modify the registry")
+	}
+	if err = r.Register("test.D", (*testService)(nil), struct{}{}, []testMethod{{9, "X"}, {9, "Y"}}, nil, nil); err == nil {
+		t.Error("expect conflicting ops within one service")
+	}
+	if err = r.Register("test.E", (*interface{ Foo() })(nil), struct{}{}, nil, nil, nil); err == nil {
+		t.Error("expect the handler not to satisfy the interface")
+	}
+}
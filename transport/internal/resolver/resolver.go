@@ -0,0 +1,140 @@
+// Package resolver resolves discovery:/// endpoints of the socket transport clients.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yola1107/kratos/v2/internal/endpoint"
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/selector/wrr"
+)
+
+// Scheme is the scheme of a discovery endpoint, discovery:///<service>.
+const Scheme = "discovery"
+
+// ParseTarget returns the service name of a discovery endpoint.
+func ParseTarget(ep string) (service string, ok bool) {
+	if !strings.HasPrefix(ep, Scheme+"://") {
+		return "", false
+	}
+	u, err := url.Parse(ep)
+	if err != nil || len(u.Path) <= 1 {
+		return "", false
+	}
+	return u.Path[1:], true
+}
+
+// Resolver watches a service and applies the instances registered with
+// scheme to a rebalancer.
+type Resolver struct {
+	service    string
+	scheme     string
+	watcher    registry.Watcher
+	rebalancer selector.Rebalancer
+}
+
+// New watches service until ctx is done or the resolver is closed, ctx should
+// live as long as the client. When timeout is positive it waits at most
+// timeout for the first nodes.
+func New(ctx context.Context, discovery registry.Discovery, service, scheme string, rebalancer selector.Rebalancer, timeout time.Duration) (*Resolver, error) {
+	watcher, err := discovery.Watch(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	r := &Resolver{
+		service:    service,
+		scheme:     scheme,
+		watcher:    watcher,
+		rebalancer: rebalancer,
+	}
+	if timeout > 0 {
+		done := make(chan error, 1)
+		go func() {
+			for {
+				services, err := watcher.Next()
+				if err != nil {
+					done <- err
+					return
+				}
+				if r.update(services) {
+					done <- nil
+					return
+				}
+			}
+		}()
+		timer := time.NewTimer(timeout)
+		select {
+		case err = <-done:
+		case <-timer.C:
+			log.Errorf("%s client watch service %v reaching context deadline!", scheme, service)
+			err = context.DeadlineExceeded
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		timer.Stop()
+		if err != nil {
+			if stopErr := watcher.Stop(); stopErr != nil {
+				log.Errorf("failed to %s client watch stop: %v, error: %+v", scheme, service, stopErr)
+			}
+			return nil, err
+		}
+	}
+	go func() {
+		for {
+			services, err := watcher.Next()
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				log.Errorf("%s client watch service %v got unexpected error:=%v", scheme, service, err)
+				time.Sleep(time.Second)
+				continue
+			}
+			r.update(services)
+		}
+	}()
+	return r, nil
+}
+
+func (r *Resolver) update(services []*registry.ServiceInstance) bool {
+	nodes := make([]selector.Node, 0, len(services))
+	for _, ins := range services {
+		ept, err := endpoint.ParseEndpoint(ins.Endpoints, r.scheme)
+		if err != nil {
+			log.Errorf("Failed to parse (%v) discovery endpoint: %v error %v", r.service, ins.Endpoints, err)
+			continue
+		}
+		if ept == "" {
+			continue
+		}
+		nodes = append(nodes, selector.NewNode(r.scheme, ept, ins))
+	}
+	if len(nodes) == 0 {
+		log.Warnf("[%s resolver]Zero endpoint found,refused to write,set: %s", r.scheme, r.service)
+		return false
+	}
+	r.rebalancer.Apply(nodes)
+	return true
+}
+
+// Close stops watching.
+func (r *Resolver) Close() error {
+	return r.watcher.Stop()
+}
+
+// NewSelector builds a selector from b, the global selector or wrr.
+func NewSelector(b selector.Builder) selector.Selector {
+	if b == nil {
+		b = selector.GlobalSelector()
+	}
+	if b == nil {
+		b = wrr.NewBuilder()
+	}
+	return b.Build()
+}
@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+)
+
+type fakeDiscovery struct {
+	instances []*registry.ServiceInstance
+	updates   chan []*registry.ServiceInstance
+}
+
+func (d *fakeDiscovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return d.instances, nil
+}
+
+func (d *fakeDiscovery) Watch(ctx context.Context, _ string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &fakeWatcher{ctx: ctx, cancel: cancel, instances: d.instances, updates: d.updates}, nil
+}
+
+// fakeWatcher returns the instances once, then the updates until its ctx is done.
+type fakeWatcher struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	instances []*registry.ServiceInstance
+	updates   chan []*registry.ServiceInstance
+	sent      bool
+}
+
+func (w *fakeWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if !w.sent {
+		w.sent = true
+		return w.instances, nil
+	}
+	select {
+	case instances := <-w.updates:
+		return instances, nil
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	}
+}
+
+func (w *fakeWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+type rebalancerFunc func([]selector.Node)
+
+func (f rebalancerFunc) Apply(nodes []selector.Node) { f(nodes) }
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		ep      string
+		service string
+		ok      bool
+	}{
+		{"discovery:///game", "game", true},
+		{"discovery:///", "", false},
+		{"127.0.0.1:9000", "", false},
+		{"tcp://127.0.0.1:9000", "", false},
+	}
+	for _, tt := range tests {
+		service, ok := ParseTarget(tt.ep)
+		if service != tt.service || ok != tt.ok {
+			t.Errorf("%s: expect (%v, %v), got (%v, %v)", tt.ep, tt.service, tt.ok, service, ok)
+		}
+	}
+}
+
+func TestResolverScheme(t *testing.T) {
+	d := &fakeDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "game", Endpoints: []string{"tcp://127.0.0.1:1", "ws://127.0.0.1:2"}},
+		{ID: "2", Name: "game", Endpoints: []string{"gnet://127.0.0.1:3"}},
+		{ID: "3", Name: "game", Endpoints: []string{"grpc://127.0.0.1:4"}},
+	}}
+	for scheme, want := range map[string]string{
+		"tcp":  "127.0.0.1:1",
+		"ws":   "127.0.0.1:2",
+		"gnet": "127.0.0.1:3",
+	} {
+		applied := make(chan []selector.Node, 1)
+		r, err := New(context.Background(), d, "game", scheme, rebalancerFunc(func(nodes []selector.Node) { applied <- nodes }), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes := <-applied
+		if len(nodes) != 1 || nodes[0].Address() != want || nodes[0].Scheme() != scheme {
+			t.Errorf("%s: expect %s, got %v", scheme, want, nodes)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestResolverBlockTimeout(t *testing.T) {
+	d := &fakeDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "game", Endpoints: []string{"grpc://127.0.0.1:1"}},
+	}}
+	if _, err := New(context.Background(), d, "game", "tcp", rebalancerFunc(func([]selector.Node) {}), 50*time.Millisecond); err == nil {
+		t.Error("expect error without tcp endpoints")
+	}
+}
+
+func TestResolverWatchAfterTimeout(t *testing.T) {
+	d := &fakeDiscovery{
+		instances: []*registry.ServiceInstance{{ID: "1", Name: "game", Endpoints: []string{"tcp://127.0.0.1:1"}}},
+		updates:   make(chan []*registry.ServiceInstance),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	applied := make(chan []selector.Node, 1)
+	r, err := New(ctx, d, "game", "tcp", rebalancerFunc(func(nodes []selector.Node) { applied <- nodes }), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	<-applied
+
+	// updates after the initial wait are still applied
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case d.updates <- []*registry.ServiceInstance{{ID: "2", Name: "game", Endpoints: []string{"tcp://127.0.0.1:2"}}}:
+	case <-time.After(time.Second):
+		t.Fatal("watcher stopped after the initial wait")
+	}
+	select {
+	case nodes := <-applied:
+		if len(nodes) != 1 || nodes[0].Address() != "127.0.0.1:2" {
+			t.Errorf("expect 127.0.0.1:2, got %v", nodes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("update not applied")
+	}
+
+	// the watch ends with ctx
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case d.updates <- nil:
+		t.Error("watcher still running after ctx done")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,47 @@
+// Package session defines the client connection of the socket servers, so
+// code pushing to players does not depend on websocket, tcp or gnet.
+package session
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Session is a client connection of a websocket, tcp or gnet server.
+type Session interface {
+	// ID is the unique id of the connection.
+	ID() string
+	// RemoteAddr is the address of the client.
+	RemoteAddr() net.Addr
+	// Push sends msg to the client as a push of cmd, it's safe to call from any goroutine.
+	Push(cmd int32, msg proto.Message) error
+	// Close closes the connection, the close hooks run once the connection exits.
+	Close(reason string) error
+	// Closed reports whether the connection is closed.
+	Closed() bool
+
+	// Get returns the attribute of key.
+	Get(key string) (any, bool)
+	// Set sets the attribute of key.
+	Set(key string, value any)
+	// Delete deletes the attribute of key.
+	Delete(key string)
+}
+
+// Hook is called when a session opens or closes.
+type Hook func(Session)
+
+type sessionKey struct{}
+
+// NewContext returns a new context with the session.
+func NewContext(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session of the current request.
+func FromContext(ctx context.Context) (s Session, ok bool) {
+	s, ok = ctx.Value(sessionKey{}).(Session)
+	return
+}
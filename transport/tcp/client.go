@@ -1,205 +1,672 @@
 package tcp
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/yola1107/kratos/v2/internal/endpoint"
+	"github.com/yola1107/kratos/v2/library/xgo"
 	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/transport/internal/resolver"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bufio"
 	"github.com/yola1107/kratos/v2/transport/tcp/proto"
-	gb "google.golang.org/protobuf/proto"
+	gproto "google.golang.org/protobuf/proto"
 )
 
-type RespMsgHandle func(data []byte, code int32)
-type PushMsgHandle func(data []byte)
+var (
+	ErrClosedRequest = errors.New("client: session not established")
+	ErrClientClosed  = errors.New("client: closed")
+	ErrSendFull      = errors.New("client: send queue full")
+)
+
+// readBufSize is the session reader buffer size, larger frames are copied.
+const readBufSize = 8 << 10
+
+type PushHandler func(data []byte)
+type ResponseHandler func(data []byte, code int32)
+
+// ClientOption is tcp client option.
+type ClientOption func(*clientOptions)
+
+// WithEndpoint with client endpoint, host:port, tcp://host:port or
+// discovery:///<service> together with WithDiscovery.
+func WithEndpoint(endpoint string) ClientOption {
+	return func(o *clientOptions) { o.endpoint = endpoint }
+}
+
+// WithTimeout with dial and call timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) { o.timeout = timeout }
+}
+
+// WithToken with authentication token.
+func WithToken(token string) ClientOption {
+	return func(o *clientOptions) { o.token = token }
+}
+
+// WithTLSConfig with TLS config.
+func WithTLSConfig(c *tls.Config) ClientOption {
+	return func(o *clientOptions) { o.tlsConf = c }
+}
+
+// WithCodec with wire codec, defaults to proto.DefaultCodec.
+func WithCodec(c proto.Codec) ClientOption {
+	return func(o *clientOptions) { o.codec = c }
+}
+
+// WithHeartbeat with ping interval and the idle timeout after which the connection is closed.
+func WithHeartbeat(interval, timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.pingInterval = interval
+		o.readDeadline = timeout
+	}
+}
+
+// WithSendChanSize with send queue size.
+func WithSendChanSize(size int) ClientOption {
+	return func(o *clientOptions) { o.sendChanSize = size }
+}
+
+// WithConnectFunc with connection callback.
+func WithConnectFunc(fn func(*Session)) ClientOption {
+	return func(o *clientOptions) { o.connectFunc = fn }
+}
 
-type ClientConfig struct {
-	Addr           string
-	PushHandlers   map[int32]PushMsgHandle
-	RespHandlers   map[int32]RespMsgHandle
-	DisconnectFunc func()
-	Token          string
+// WithDisconnectFunc with disconnection callback.
+func WithDisconnectFunc(fn func(*Session)) ClientOption {
+	return func(o *clientOptions) { o.disconnectFunc = fn }
 }
 
+// WithPushHandler with push message handlers.
+func WithPushHandler(handler map[int32]PushHandler) ClientOption {
+	return func(o *clientOptions) { o.pushHandler = handler }
+}
+
+// WithResponseHandler with response message handlers used by Request.
+func WithResponseHandler(handler map[int32]ResponseHandler) ClientOption {
+	return func(o *clientOptions) { o.responseHandler = handler }
+}
+
+// WithRetryPolicy with reconnection policy, defaults to 5 attempts starting 3s apart.
+// maxAttempt < 0 retries until the client context is done and 0 disables reconnection.
+func WithRetryPolicy(delay time.Duration, maxAttempt int32) ClientOption {
+	return func(o *clientOptions) {
+		o.retryDelay = delay
+		o.retryMaxAttempt = maxAttempt
+	}
+}
+
+// WithDiscovery with client discovery.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) { o.discovery = d }
+}
+
+// WithNodeFilter with select filters.
+func WithNodeFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *clientOptions) { o.nodeFilters = filters }
+}
+
+// WithSelector with node selector builder (p2c, wrr, random), defaults to the global selector.
+func WithSelector(b selector.Builder) ClientOption {
+	return func(o *clientOptions) { o.selector = b }
+}
+
+// clientOptions is tcp client options
+type clientOptions struct {
+	ctx             context.Context
+	tlsConf         *tls.Config
+	codec           proto.Codec
+	timeout         time.Duration
+	endpoint        string
+	token           string
+	connectFunc     func(*Session)
+	disconnectFunc  func(*Session)
+	pushHandler     map[int32]PushHandler
+	responseHandler map[int32]ResponseHandler
+	pingInterval    time.Duration
+	readDeadline    time.Duration
+	sendChanSize    int
+	retryDelay      time.Duration
+	retryMaxAttempt int32
+	discovery       registry.Discovery
+	nodeFilters     []selector.NodeFilter
+	selector        selector.Builder
+}
+
+// Client is a tcp client with the same surface as websocket.Client.
 type Client struct {
-	pushChan       chan *proto.Payload
-	closeChan      chan bool
-	pushHandlers   map[int32]PushMsgHandle
-	respHandlers   map[int32]RespMsgHandle
-	disconnectFunc func()
-	reqOps         sync.Map
-}
-
-func NewTcpClient(conf *ClientConfig) (c *Client, err error) {
-	c = &Client{
-		pushChan:       make(chan *proto.Payload, 100),
-		closeChan:      make(chan bool),
-		pushHandlers:   conf.PushHandlers,
-		respHandlers:   conf.RespHandlers,
-		disconnectFunc: conf.DisconnectFunc,
-		reqOps:         sync.Map{},
-	}
-	conn, err := net.Dial("tcp", conf.Addr)
+	opts     *clientOptions
+	addr     string
+	selector selector.Selector
+	r        *resolver.Resolver
+	ctx      context.Context
+	cancel   context.CancelFunc
+	seq      int32
+	reqPool  sync.Map // seq -> *call
+	pushes   sync.Map // command -> PushHandler
+	session  atomic.Pointer[Session]
+	closed   atomic.Bool
+	mu       sync.Mutex // serializes Reconnect
+}
+
+type call struct {
+	ops  int32
+	done chan *proto.Payload // nil for Request
+}
+
+// NewClient creates a tcp client by options and dials the endpoint.
+func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
+	options := &clientOptions{
+		ctx:             ctx,
+		endpoint:        "127.0.0.1:3101",
+		codec:           proto.DefaultCodec,
+		timeout:         2 * time.Second,
+		pushHandler:     make(map[int32]PushHandler),
+		responseHandler: make(map[int32]ResponseHandler),
+		pingInterval:    5 * time.Second,
+		readDeadline:    15 * time.Second,
+		sendChanSize:    100,
+		retryDelay:      3 * time.Second,
+		retryMaxAttempt: 5,
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	c := &Client{
+		opts: options,
+		addr: strings.TrimPrefix(options.endpoint, "tcp://"),
+	}
+	for command, handler := range options.pushHandler {
+		c.pushes.Store(command, handler)
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	if service, ok := resolver.ParseTarget(options.endpoint); ok {
+		if options.discovery == nil {
+			c.cancel()
+			return nil, fmt.Errorf("[tcp client] discovery is required by endpoint %s", options.endpoint)
+		}
+		c.selector = resolver.NewSelector(options.selector)
+		r, err := resolver.New(c.ctx, options.discovery, service, endpoint.Scheme("tcp", options.tlsConf != nil), c.selector, options.timeout)
+		if err != nil {
+			c.cancel()
+			return nil, fmt.Errorf("[tcp client] new resolver failed: %w", err)
+		}
+		c.r = r
+	}
+	if err := c.Reconnect(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// IsAlive returns true if the client is connected
+func (c *Client) IsAlive() bool {
+	if c == nil {
+		return false
+	}
+	s := c.session.Load()
+	return s != nil && !s.Closed()
+}
+
+// GetSession returns the current connection, nil when disconnected.
+func (c *Client) GetSession() *Session {
+	return c.session.Load()
+}
+
+// Reconnect closes the current connection and dials again with exponential backoff.
+// The session callbacks run without the reconnect lock held, so they may call
+// Reconnect or Close themselves.
+func (c *Client) Reconnect() error {
+	if old := c.session.Swap(nil); old != nil {
+		old.Close(false, "reconnect")
+	}
+	sess, prev, err := c.redial()
+	if prev != nil {
+		prev.Close(false, "reconnect")
+	}
 	if err != nil {
-		log.Errorf("net.Dial(%s) error(%v)", conf.Addr, err)
-		return
+		return err
+	}
+	sess.start()
+	return nil
+}
+
+// redial dials until it connects or gives up and stores the new session. It
+// returns the session a concurrent Reconnect stored meanwhile, for the caller
+// to close once the lock is released.
+func (c *Client) redial() (sess, prev *Session, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for attempt := int32(1); ; attempt++ {
+		if c.closed.Load() {
+			return nil, nil, ErrClientClosed
+		}
+		conn, err := c.dial()
+		if err == nil {
+			sess = newSession(c, conn)
+			prev = c.session.Swap(sess)
+			if c.closed.Load() {
+				// Close ran during the dial and found no session to close.
+				c.session.CompareAndSwap(sess, nil)
+				sess.cancel()
+				_ = conn.Close()
+				return nil, prev, ErrClientClosed
+			}
+			return sess, prev, nil
+		}
+		if c.opts.retryMaxAttempt >= 0 && attempt >= c.opts.retryMaxAttempt {
+			return nil, nil, fmt.Errorf("reconnect failed after %d attempts: %w", attempt, err)
+		}
+		delay := c.calculateBackoff(attempt)
+		log.Warnf("tcp reconnect attempt %d failed, retrying in %v: %v", attempt, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return nil, nil, fmt.Errorf("reconnect cancelled: %w", c.ctx.Err())
+		}
 	}
-	wr := bufio.NewWriter(conn)
-	rd := bufio.NewReader(conn)
-	go c.handles(conn, rd)
-	go c.dispatch(conn, wr)
-	if conf.Token != "" {
-		c.auth(conf.Token)
+}
+
+func (c *Client) dial() (conn net.Conn, err error) {
+	addr := c.addr
+	if c.selector != nil {
+		var (
+			node selector.Node
+			done selector.DoneFunc
+		)
+		if node, done, err = c.selector.Select(c.ctx, selector.WithNodeFilter(c.opts.nodeFilters...)); err != nil {
+			return nil, err
+		}
+		addr = node.Address()
+		defer func() { done(c.ctx, selector.DoneInfo{Err: err}) }()
 	}
-	go c.sendHeart()
-	go func() {
-		for {
-			cc := <-c.closeChan
-			log.Infof("client close conn")
-			if cc {
-				c.disconnectFunc()
-				if err := conn.Close(); err != nil {
-					log.Errorf("close err %v", err)
-				}
+	dialer := &net.Dialer{Timeout: c.opts.timeout}
+	if c.opts.tlsConf != nil {
+		return (&tls.Dialer{NetDialer: dialer, Config: c.opts.tlsConf}).DialContext(c.ctx, "tcp", addr)
+	}
+	return dialer.DialContext(c.ctx, "tcp", addr)
+}
+
+// calculateBackoff computes exponential backoff delay
+func (c *Client) calculateBackoff(attempt int32) time.Duration {
+	backoff := float64(c.opts.retryDelay) * math.Pow(1.5, float64(attempt-1))
+	return time.Duration(backoff * (0.9 + 0.2*rand.Float64()))
+}
+
+func (c *Client) nextSeq() int32 {
+	seq := atomic.AddInt32(&c.seq, 1)
+	if seq >= math.MaxInt32-1 {
+		atomic.StoreInt32(&c.seq, 1)
+		seq = 1
+	}
+	return seq
+}
+
+func (c *Client) send(ops int32, msg gproto.Message, cl *call) (int32, error) {
+	sess := c.session.Load()
+	if sess == nil || sess.Closed() {
+		return 0, ErrClosedRequest
+	}
+	data, err := gproto.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	body, err := gproto.Marshal(&proto.Body{Ops: ops, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	seq := c.nextSeq()
+	c.reqPool.Store(seq, cl)
+	if err = sess.send(&proto.Payload{
+		Op:    ops,
+		Place: proto.PlaceClient,
+		Type:  int32(proto.Request),
+		Seq:   seq,
+		Body:  body,
+	}); err != nil {
+		c.reqPool.Delete(seq)
+		return 0, err
+	}
+	return seq, nil
+}
+
+// Request sends a request, the response is delivered to the ResponseHandler of command.
+func (c *Client) Request(command int32, msg gproto.Message) error {
+	_, err := c.send(command, msg, &call{ops: command})
+	return err
+}
+
+// Call sends a request and waits for its response until ctx is done.
+func (c *Client) Call(ctx context.Context, ops int32, req, reply gproto.Message) error {
+	if _, ok := ctx.Deadline(); !ok && c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+	cl := &call{ops: ops, done: make(chan *proto.Payload, 1)}
+	seq, err := c.send(ops, req, cl)
+	if err != nil {
+		return err
+	}
+	select {
+	case p := <-cl.done:
+		if p == nil {
+			return ErrClosedRequest
+		}
+		body := &proto.Body{}
+		if err := gproto.Unmarshal(p.Body, body); err != nil {
+			if p.Code != 0 {
+				return proto.ToError(p.Code, nil)
 			}
+			return err
 		}
-	}()
-	return
+		if err := proto.ToError(p.Code, body.Error); err != nil {
+			return err
+		}
+		if reply == nil {
+			return nil
+		}
+		return gproto.Unmarshal(body.Data, reply)
+	case <-ctx.Done():
+		c.reqPool.Delete(seq)
+		return ctx.Err()
+	}
 }
 
-func (c *Client) auth(token string) (err error) {
-	p := &proto.Payload{
-		Type: int32(proto.Request),
-		Body: []byte(token),
+// OnPush sets the handler of the pushes of command, replacing the handler
+// of WithPushHandler. A nil handler removes it.
+func (c *Client) OnPush(command int32, handler PushHandler) {
+	if handler == nil {
+		c.pushes.Delete(command)
+		return
 	}
-	c.pushChan <- p
-	return
+	c.pushes.Store(command, handler)
 }
 
-func (c *Client) Request(command int32, msg gb.Message) (err error) {
-	var data []byte
-	if data, err = gb.Marshal(msg); err != nil {
+// Close closes the client, stops reconnection and fails pending calls.
+func (c *Client) Close(msg ...string) {
+	if !c.closed.CompareAndSwap(false, true) {
 		return
 	}
-	body := &proto.Body{
-		PlayerId: 0,
-		Ops:      command,
-		Data:     data,
+	c.cancel()
+	if c.r != nil {
+		_ = c.r.Close()
 	}
-	var pData []byte
-	if pData, err = gb.Marshal(body); err != nil {
-		return
+	reason := "client closed"
+	if len(msg) > 0 {
+		reason += ": " + strings.Join(msg, "; ")
 	}
-	p := &proto.Payload{
-		Place: 0,
-		Type:  int32(proto.Request),
-		Body:  pData,
-		Op:    command,
+	if s := c.session.Swap(nil); s != nil {
+		s.Close(true, reason)
 	}
-	c.pushChan <- p
-	return
+	c.failPending()
 }
 
-func (c *Client) Close() {
-	c.closeChan <- true
+func (c *Client) failPending() {
+	c.reqPool.Range(func(key, _ any) bool {
+		// only the side that removes the call may complete it, DispatchMessage may race here
+		if value, ok := c.reqPool.LoadAndDelete(key); ok {
+			if cl := value.(*call); cl.done != nil {
+				cl.done <- nil
+			}
+		}
+		return true
+	})
 }
 
-func (c *Client) sendHeart() {
-	for {
-		p := &proto.Payload{
-			Place: 0,
-			Type:  int32(proto.Ping),
+// OnSessionOpen 连接成功回调
+func (c *Client) OnSessionOpen(sess *Session) {
+	if c.opts.token != "" {
+		if err := c.auth(sess); err != nil {
+			log.Warnf("tcp client auth failed: %v", err)
 		}
-		c.pushChan <- p
-		time.Sleep(time.Second * 5)
+	}
+	if c.opts.connectFunc != nil {
+		safeCall(func() { c.opts.connectFunc(sess) })
 	}
 }
 
-func (c *Client) handles(conn net.Conn, rd *bufio.Reader) {
-	for {
-		p := &proto.Payload{}
-		if err := p.ReadTCP(rd); err != nil {
-			log.Errorf("ReadTCP err %v", err)
-			c.closeChan <- true
-			break
+// OnSessionClose handles connection close and auto-reconnect
+func (c *Client) OnSessionClose(sess *Session) {
+	// false when the session was replaced by Reconnect or Close
+	current := c.session.CompareAndSwap(sess, nil)
+	c.failPending()
+	if c.opts.disconnectFunc != nil {
+		safeCall(func() { c.opts.disconnectFunc(sess) })
+	}
+	if !current || c.closed.Load() || c.opts.retryMaxAttempt == 0 {
+		return
+	}
+	go func() {
+		if err := c.Reconnect(); err != nil && !errors.Is(err, ErrClientClosed) {
+			log.Warnf("reconnect failed: %v", err)
 		}
+	}()
+}
 
-		// log.Infof("recv. p={op:%d place:%d type:%d seq:%d code:%d body:%+v}", p.Op, p.Place, p.Type, p.Seq, p.Code, p.Body)
+func (c *Client) auth(sess *Session) error {
+	body, err := gproto.Marshal(&proto.Body{Ops: proto.AuthOps, Data: []byte(c.opts.token)})
+	if err != nil {
+		return err
+	}
+	return sess.send(&proto.Payload{Place: proto.PlaceClient, Type: int32(proto.Request), Body: body})
+}
 
-		switch p.Type {
-		case int32(proto.Pong):
+// DispatchMessage handles incoming messages
+func (c *Client) DispatchMessage(sess *Session, p *proto.Payload) error {
+	switch p.Type {
+	case int32(proto.Pong):
+	case int32(proto.Ping):
+		return sess.send(&proto.Payload{Place: proto.PlaceClient, Type: int32(proto.Pong)})
+	case int32(proto.Push):
+		body := &proto.Body{}
+		if err := gproto.Unmarshal(p.Body, body); err != nil {
+			return err
+		}
+		if v, ok := c.pushes.Load(body.Ops); ok {
+			safeCall(func() { v.(PushHandler)(body.Data) })
+		}
+	case int32(proto.Response):
+		v, ok := c.reqPool.LoadAndDelete(p.Seq)
+		if !ok {
+			return nil
+		}
+		cl := v.(*call)
+		if cl.done != nil {
+			p.CloneBody()
+			cl.done <- p
+			return nil
+		}
+		handler, ok := c.opts.responseHandler[cl.ops]
+		if !ok {
+			return nil
+		}
+		body := &proto.Body{}
+		if err := gproto.Unmarshal(p.Body, body); err != nil {
+			return err
+		}
+		safeCall(func() { handler(body.Data, p.Code) })
+	default:
+		log.Warnf("client handles unknown payload.Type: %v", p.Type)
+	}
+	return nil
+}
 
-		case int32(proto.Push):
-			body := &proto.Body{}
-			if err := gb.Unmarshal(p.Body, body); err != nil {
-				log.Errorf("proto type %d Unmarshal err %v", p.Type, err)
-				continue
-			}
-			handle, ok := c.pushHandlers[body.Ops]
-			if !ok {
-				log.Errorf("pushHandlers ops %d func is not exist", body.Ops)
-				continue
+// Session is a client side tcp connection.
+type Session struct {
+	id        string
+	conn      net.Conn
+	c         *Client
+	ctx       context.Context
+	cancel    context.CancelFunc
+	sendChan  chan *proto.Payload
+	lastAct   atomic.Int64
+	closed    atomic.Bool
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newSession(c *Client, conn net.Conn) *Session {
+	ctx, cancel := context.WithCancel(c.ctx)
+	s := &Session{
+		id:       uuid.NewString(),
+		conn:     conn,
+		c:        c,
+		ctx:      ctx,
+		cancel:   cancel,
+		sendChan: make(chan *proto.Payload, c.opts.sendChanSize),
+	}
+	s.lastAct.Store(time.Now().UnixNano())
+	return s
+}
+
+// start runs the loops before the open callbacks, so connectFunc may make calls.
+func (s *Session) start() {
+	s.wg.Add(3)
+	go s.readLoop()
+	go s.writeLoop()
+	go s.heartbeat()
+	s.c.OnSessionOpen(s)
+}
+
+func (s *Session) ID() string            { return s.id }
+func (s *Session) Closed() bool          { return s.closed.Load() }
+func (s *Session) LastActive() time.Time { return time.Unix(0, s.lastAct.Load()) }
+func (s *Session) GetRemoteIP() string   { return s.conn.RemoteAddr().String() }
+
+func (s *Session) send(p *proto.Payload) error {
+	if s.Closed() {
+		return ErrClosedRequest
+	}
+	select {
+	case s.sendChan <- p:
+		return nil
+	case <-s.ctx.Done():
+		return ErrClosedRequest
+	default:
+		return ErrSendFull
+	}
+}
+
+func (s *Session) readLoop() {
+	defer s.wg.Done()
+	defer xgo.RecoverFromError(nil)
+	defer s.Close(false)
+
+	rd := bufio.NewReaderSize(s.conn, readBufSize)
+	for !s.Closed() {
+		p := &proto.Payload{}
+		if err := proto.ReadFrame(s.c.opts.codec, rd, p); err != nil {
+			if !s.Closed() {
+				log.Warnf("sessionID=%q read error: %v", s.id, err)
 			}
-			handle(body.Data)
+			return
+		}
+		s.lastAct.Store(time.Now().UnixNano())
+		if err := s.c.DispatchMessage(s, p); err != nil {
+			log.Warnf("sessionID=%q dispatch error: %v", s.id, err)
+		}
+	}
+}
 
-		case int32(proto.Response):
-			ops, ok := c.reqOps.Load(p.Seq)
-			if !ok {
-				log.Errorf("reqOps seq %d is not exist", p.Seq)
-				continue
+func (s *Session) writeLoop() {
+	defer s.wg.Done()
+	defer s.Close(false)
+
+	wr := bufio.NewWriter(s.conn)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case p := <-s.sendChan:
+			if err := proto.WriteFrame(s.c.opts.codec, wr, p); err != nil {
+				log.Warnf("sessionID=%q write error: %v", s.id, err)
+				return
 			}
-			c.reqOps.Delete(p.Seq)
-			handle, ok := c.respHandlers[ops.(int32)]
-			if !ok {
-				log.Errorf("respHandlers ops %d func is not exist", ops)
+			// only hungry flush
+			if len(s.sendChan) > 0 {
 				continue
 			}
-			body := &proto.Body{}
-			if err := gb.Unmarshal(p.Body, body); err != nil {
-				log.Errorf("proto type %d Unmarshal err %v", p.Type, err)
-				continue
+			if s.c.opts.timeout > 0 {
+				_ = s.conn.SetWriteDeadline(time.Now().Add(s.c.opts.timeout))
+			}
+			if err := wr.Flush(); err != nil {
+				log.Warnf("sessionID=%q flush error: %v", s.id, err)
+				return
 			}
-			handle(body.Data, p.Code)
-
-		default:
-			log.Warnf("client handles unknown payload.Type: %v", p.Type)
 		}
 	}
 }
 
-func (c *Client) dispatch(conn net.Conn, wr *bufio.Writer) {
-	seq := int32(0)
+func (s *Session) heartbeat() {
+	defer s.wg.Done()
+	if s.c.opts.pingInterval <= 0 {
+		<-s.ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(s.c.opts.pingInterval)
+	defer ticker.Stop()
 	for {
-		p := <-c.pushChan
-		p.Seq = seq
-
-		switch p.Type {
-		case int32(proto.Ping):
-			if err := p.WriteTCPHeart(wr); err != nil {
-				log.Errorf("WriteTCPHeart err %v", err)
-				c.closeChan <- true
-				break
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.c.opts.readDeadline > 0 && time.Since(s.LastActive()) > s.c.opts.readDeadline {
+				log.Warnf("sessionID=%q heartbeat timeout", s.id)
+				s.Close(true, "Heartbeat Timeout")
+				return
 			}
-
-		case int32(proto.Request):
-			if err := p.WriteTCP(wr); err != nil {
-				log.Errorf("WriteTCP err %v", err)
-				c.closeChan <- true
-				break
+			if err := s.send(&proto.Payload{Place: proto.PlaceClient, Type: int32(proto.Ping)}); err != nil {
+				log.Warnf("sessionID=%q heartbeat error: %v", s.id, err)
 			}
-			c.reqOps.Store(p.Seq, p.Op)
-
-		default:
-			log.Warnf("client dispatch unknown payload.Type: %v", p.Type)
 		}
+	}
+}
 
-		if err := wr.Flush(); err != nil {
-			log.Errorf("Flush error(%v)", err)
-			c.closeChan <- true
-			break
+// Close closes the connection and stops its goroutines.
+func (s *Session) Close(force bool, msg ...string) bool {
+	closed := false
+	s.closeOnce.Do(func() {
+		closed = true
+		s.closed.Store(true)
+		s.cancel()
+		_ = s.conn.Close()
+		s.c.OnSessionClose(s)
+		reason := "Normal Close"
+		if force {
+			reason = "Force Close"
 		}
-		seq += 1
-		seq %= 65535
+		if len(msg) > 0 {
+			reason += ": " + strings.Join(msg, "; ")
+		}
+		log.Infof("tcp client session closed: id=%s, reason=%s", s.id, reason)
+	})
+	return closed
+}
+
+// Wait blocks until all goroutines of the session exit.
+func (s *Session) Wait() {
+	s.wg.Wait()
+}
+
+// safeCall 用于安全调用回调，避免panic导致崩溃
+func safeCall(fn func()) {
+	defer xgo.RecoverFromError(nil)
+	if fn != nil {
+		fn()
 	}
 }
@@ -0,0 +1,332 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kerrors "github.com/yola1107/kratos/v2/errors"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/transport/tcp/proto"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoServer replies to requests with the same body, pushes it back on ops 2
+// and fails ops 3 and 4.
+func echoServer(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					p := &proto.Payload{}
+					if err := proto.ReadFrame(proto.DefaultCodec, conn, p); err != nil {
+						return
+					}
+					switch p.Type {
+					case int32(proto.Ping):
+						p.Type = int32(proto.Pong)
+					case int32(proto.Request):
+						body := &proto.Body{}
+						_ = gproto.Unmarshal(p.Body, body)
+						if body.Ops == 2 {
+							push := &proto.Payload{Type: int32(proto.Push), Body: p.Body}
+							_ = proto.WriteFrame(proto.DefaultCodec, conn, push)
+						}
+						if body.Ops == 3 {
+							p.Code = 5
+						}
+						if body.Ops == 4 {
+							p.Code, body.Error = proto.FromError(kerrors.NotFound("TABLE_NOT_FOUND", "table not found").WithMetadata(map[string]string{"table": "7"}))
+							p.Body, _ = gproto.Marshal(body)
+						}
+						p.Type = int32(proto.Response)
+					}
+					if err := proto.WriteFrame(proto.DefaultCodec, conn, p); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return lis.Addr().String(), func() { lis.Close() }
+}
+
+func TestClientCall(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	pushed := make(chan string, 1)
+	c, err := NewClient(context.Background(),
+		WithEndpoint(addr),
+		WithRetryPolicy(time.Millisecond, 0),
+		WithPushHandler(map[int32]PushHandler{
+			2: func(data []byte) {
+				v := &wrapperspb.StringValue{}
+				_ = gproto.Unmarshal(data, v)
+				pushed <- v.Value
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	reply := &wrapperspb.StringValue{}
+	if err = c.Call(context.Background(), 1, wrapperspb.String("hello"), reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "hello" {
+		t.Errorf("expect %v, got %v", "hello", reply.Value)
+	}
+
+	if err = c.Call(context.Background(), 2, wrapperspb.String("push"), nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-pushed:
+		if v != "push" {
+			t.Errorf("expect %v, got %v", "push", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("push not received")
+	}
+
+	if err = c.Call(context.Background(), 3, wrapperspb.String("err"), nil); kerrors.Code(err) != 5 {
+		t.Errorf("expect error code 5, got %v", err)
+	}
+
+	err = c.Call(context.Background(), 4, wrapperspb.String("err"), nil)
+	e := kerrors.FromError(err)
+	if e.Code != 404 || e.Reason != "TABLE_NOT_FOUND" || e.Message != "table not found" || e.Metadata["table"] != "7" {
+		t.Errorf("expect the structured error, got %v", err)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	c, err := NewClient(context.Background(), WithEndpoint(addr), WithHeartbeat(time.Millisecond, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := c.GetSession()
+	c.Close()
+	c.Close()
+
+	done := make(chan struct{})
+	go func() {
+		sess.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("session goroutines did not exit")
+	}
+	if c.IsAlive() {
+		t.Error("expect client closed")
+	}
+	if err = c.Call(context.Background(), 1, wrapperspb.String("x"), nil); !errors.Is(err, ErrClosedRequest) {
+		t.Errorf("expect %v, got %v", ErrClosedRequest, err)
+	}
+}
+
+func TestClientConnectFuncRead(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	pushed := make(chan struct{}, 1)
+	received := make(chan bool, 1)
+	c, err := NewClient(context.Background(),
+		WithEndpoint(addr),
+		WithPushHandler(map[int32]PushHandler{2: func([]byte) { pushed <- struct{}{} }}),
+		WithConnectFunc(func(sess *Session) {
+			// the session is already reading when connectFunc runs
+			body, _ := gproto.Marshal(&proto.Body{Ops: 2})
+			_ = sess.send(&proto.Payload{Place: proto.PlaceClient, Type: int32(proto.Request), Body: body})
+			select {
+			case <-pushed:
+				received <- true
+			case <-time.After(time.Second):
+				received <- false
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !<-received {
+		t.Error("expect the push read while connectFunc runs")
+	}
+}
+
+func TestClientClosePending(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	c, err := NewClient(context.Background(), WithEndpoint(addr), WithSendChanSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// responses racing with Close complete each call exactly once
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = c.Call(context.Background(), 1, wrapperspb.String("x"), nil)
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close blocked on pending calls")
+	}
+}
+
+func TestClientDialFailed(t *testing.T) {
+	c, err := NewClient(context.Background(), WithEndpoint("127.0.0.1:1"), WithRetryPolicy(time.Millisecond, 1))
+	if err == nil || c != nil {
+		t.Errorf("expect nil client and error, got %v %v", c, err)
+	}
+}
+
+func TestClientDialCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewClient(ctx, WithEndpoint("127.0.0.1:1"), WithRetryPolicy(time.Millisecond, -1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expect %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("unlimited retry ignored the context")
+	}
+}
+
+func TestClientReconnectInCallback(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	var first atomic.Bool
+	reconnected := make(chan error, 1)
+	c, err := NewClient(context.Background(),
+		WithEndpoint(addr),
+		WithConnectFunc(func(sess *Session) {
+			// the reconnect lock is released before the open callbacks run
+			if first.CompareAndSwap(false, true) {
+				reconnected <- sess.c.Reconnect()
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	select {
+	case err = <-reconnected:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reconnect from connectFunc deadlocked")
+	}
+	if !c.IsAlive() {
+		t.Error("expect the reconnected session alive")
+	}
+	if err = c.Call(context.Background(), 1, wrapperspb.String("x"), nil); err != nil {
+		t.Error(err)
+	}
+}
+
+type staticDiscovery []*registry.ServiceInstance
+
+func (d staticDiscovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return d, nil
+}
+
+func (d staticDiscovery) Watch(ctx context.Context, _ string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &staticWatcher{ctx: ctx, cancel: cancel, instances: d}, nil
+}
+
+type staticWatcher struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	instances []*registry.ServiceInstance
+	sent      bool
+}
+
+func (w *staticWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if !w.sent {
+		w.sent = true
+		return w.instances, nil
+	}
+	<-w.ctx.Done()
+	return nil, w.ctx.Err()
+}
+
+func (w *staticWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+func TestClientDiscovery(t *testing.T) {
+	addr, stop := echoServer(t)
+	defer stop()
+
+	d := staticDiscovery{
+		{ID: "ws", Name: "game", Endpoints: []string{"ws://127.0.0.1:1"}},
+		{ID: "tcp", Name: "game", Endpoints: []string{"tcp://" + addr}},
+	}
+	c, err := NewClient(context.Background(),
+		WithEndpoint("discovery:///game"),
+		WithDiscovery(d),
+		WithRetryPolicy(time.Millisecond, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	reply := &wrapperspb.StringValue{}
+	if err = c.Call(context.Background(), 1, wrapperspb.String("hello"), reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "hello" {
+		t.Errorf("expect %v, got %v", "hello", reply.Value)
+	}
+
+	if _, err = NewClient(context.Background(), WithEndpoint("discovery:///game")); err == nil {
+		t.Error("expect error without discovery")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/channel"
+	"github.com/yola1107/kratos/v2/transport/tcp/proto"
 )
 
 // Bucket is a channel holder.
@@ -68,6 +69,18 @@ func (b *Bucket) Channel(key string) (ch *channel.Channel) {
 	return
 }
 
+// Broadcast push a message to all channels in the bucket, returns the first push error.
+func (b *Bucket) Broadcast(p *proto.Payload) (err error) {
+	b.cLock.RLock()
+	for _, ch := range b.chs {
+		if e := ch.Push(p); e != nil && err == nil {
+			err = e
+		}
+	}
+	b.cLock.RUnlock()
+	return
+}
+
 // IPCount get ip count.
 func (b *Bucket) IPCount() (res map[string]struct{}) {
 	var (
@@ -340,6 +340,9 @@ func (b *Reader) ReadLine() (line []byte, isPrefix bool, err error) {
 // Buffered returns the number of bytes that can be read from the current buffer.
 func (b *Reader) Buffered() int { return b.w - b.r }
 
+// Size returns the size of the underlying buffer in bytes.
+func (b *Reader) Size() int { return len(b.buf) }
+
 // buffered output
 
 // Writer implements buffering for an io.Writer object.
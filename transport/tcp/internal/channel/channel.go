@@ -1,6 +1,7 @@
 package channel
 
 import (
+	"net"
 	"sync"
 
 	"github.com/yola1107/kratos/v2/log"
@@ -22,6 +23,7 @@ type Channel struct {
 	Mid   int64
 	Key   string
 	IP    string
+	Conn  net.Conn
 	mutex sync.RWMutex
 }
 
@@ -58,3 +60,11 @@ func (c *Channel) Signal() {
 func (c *Channel) Close() {
 	c.signal <- proto.ProtoFinish
 }
+
+// Kick close the underlying connection, the reader exits and releases the channel.
+func (c *Channel) Kick() error {
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn.Close()
+}
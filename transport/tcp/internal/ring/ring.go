@@ -1,20 +1,24 @@
 package ring
 
 import (
+	"sync/atomic"
+
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/errors"
 	"github.com/yola1107/kratos/v2/transport/tcp/proto"
 )
 
 // Ring ring proto buffer.
+// One goroutine writes with Set/SetAdv and another reads with Get/GetAdv,
+// the indexes are atomic so the slots are published between them.
 type Ring struct {
 	// read
-	rp   uint64
+	rp   atomic.Uint64
 	num  uint64
 	mask uint64
 	// TODO split cacheline, many cpu cache line size is 64
 	// pad [40]byte
 	// write
-	wp   uint64
+	wp   atomic.Uint64
 	data []proto.Payload
 }
 
@@ -46,36 +50,38 @@ func (r *Ring) init(num uint64) {
 
 // Get get a proto from ring.
 func (r *Ring) Get() (proto *proto.Payload, err error) {
-	if r.rp == r.wp {
+	rp := r.rp.Load()
+	if rp == r.wp.Load() {
 		return nil, errors.ErrRingEmpty
 	}
-	proto = &r.data[r.rp&r.mask]
+	proto = &r.data[rp&r.mask]
 	return
 }
 
 // GetAdv incr read index.
 func (r *Ring) GetAdv() {
-	r.rp++
+	r.rp.Add(1)
 }
 
 // Set get a proto to write.
 func (r *Ring) Set() (proto *proto.Payload, err error) {
-	if r.wp-r.rp >= r.num {
+	wp := r.wp.Load()
+	if wp-r.rp.Load() >= r.num {
 		return nil, errors.ErrRingFull
 	}
-	proto = &r.data[r.wp&r.mask]
+	proto = &r.data[wp&r.mask]
 	return
 }
 
 // SetAdv incr write index.
 func (r *Ring) SetAdv() {
-	r.wp++
+	r.wp.Add(1)
 }
 
 // Reset reset ring.
 func (r *Ring) Reset() {
-	r.rp = 0
-	r.wp = 0
+	r.rp.Store(0)
+	r.wp.Store(0)
 	// prevent pad compiler optimization
 	// r.pad = [40]byte{}
 }
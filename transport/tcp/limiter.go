@@ -0,0 +1,121 @@
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrTooManyConns is returned when the server holds MaxConns connections.
+	ErrTooManyConns = errors.New("tcp: too many connections")
+	// ErrTooManyConnsPerIP is returned when an ip holds MaxConnsPerIP connections.
+	ErrTooManyConnsPerIP = errors.New("tcp: too many connections from ip")
+	// ErrAcceptRate is returned when an ip connects faster than AcceptRate.
+	ErrAcceptRate = errors.New("tcp: connection rate exceeded")
+)
+
+const limiterSweepInterval = time.Minute
+
+// connLimiter limits total connections, connections per ip and the accept rate per ip.
+// A zero value of any limit disables it.
+type connLimiter struct {
+	maxConns int
+	maxPerIP int
+	rate     rate.Limit
+	burst    int
+
+	mu        sync.Mutex
+	total     int
+	ips       map[string]*ipLimit
+	lastSweep time.Time
+}
+
+type ipLimit struct {
+	conns   int
+	limiter *rate.Limiter
+}
+
+func newConnLimiter(c *Limit) *connLimiter {
+	l := &connLimiter{
+		maxConns:  c.MaxConns,
+		maxPerIP:  c.MaxConnsPerIP,
+		rate:      rate.Limit(c.AcceptRate),
+		burst:     c.AcceptBurst,
+		ips:       make(map[string]*ipLimit),
+		lastSweep: time.Now(),
+	}
+	if l.rate > 0 && l.burst <= 0 {
+		l.burst = 1
+	}
+	return l
+}
+
+// acquire reserves a connection slot for ip, release must be called when the connection closes.
+func (l *connLimiter) acquire(ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > limiterSweepInterval {
+		l.sweep(now)
+	}
+	if l.maxConns > 0 && l.total >= l.maxConns {
+		return ErrTooManyConns
+	}
+	il := l.ips[ip]
+	if il == nil {
+		il = &ipLimit{}
+		if l.rate > 0 {
+			il.limiter = rate.NewLimiter(l.rate, l.burst)
+		}
+		l.ips[ip] = il
+	}
+	if l.maxPerIP > 0 && il.conns >= l.maxPerIP {
+		return ErrTooManyConnsPerIP
+	}
+	if il.limiter != nil && !il.limiter.AllowN(now, 1) {
+		return ErrAcceptRate
+	}
+	il.conns++
+	l.total++
+	return nil
+}
+
+func (l *connLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if il := l.ips[ip]; il != nil {
+		il.conns--
+		if il.conns <= 0 && il.limiter == nil {
+			delete(l.ips, ip)
+		}
+	}
+}
+
+// sweep drops idle ips whose rate limiter is full again.
+func (l *connLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for ip, il := range l.ips {
+		if il.conns > 0 {
+			continue
+		}
+		if il.limiter == nil || il.limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.ips, ip)
+		}
+	}
+}
+
+// count returns the total connections and connections of ip.
+func (l *connLimiter) count(ip string) (total, perIP int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if il := l.ips[ip]; il != nil {
+		perIP = il.conns
+	}
+	return l.total, perIP
+}
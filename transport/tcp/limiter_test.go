@@ -0,0 +1,93 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConnLimiter(t *testing.T) {
+	l := newConnLimiter(&Limit{MaxConns: 3, MaxConnsPerIP: 2})
+	if err := l.acquire("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire("1.1.1.1"); !errors.Is(err, ErrTooManyConnsPerIP) {
+		t.Errorf("expect %v, got %v", ErrTooManyConnsPerIP, err)
+	}
+	if err := l.acquire("2.2.2.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire("3.3.3.3"); !errors.Is(err, ErrTooManyConns) {
+		t.Errorf("expect %v, got %v", ErrTooManyConns, err)
+	}
+	l.release("1.1.1.1")
+	if total, perIP := l.count("1.1.1.1"); total != 2 || perIP != 1 {
+		t.Errorf("expect 2 1, got %d %d", total, perIP)
+	}
+	if err := l.acquire("3.3.3.3"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnLimiterRate(t *testing.T) {
+	l := newConnLimiter(&Limit{AcceptRate: 1, AcceptBurst: 2})
+	for i := 0; i < 2; i++ {
+		if err := l.acquire("1.1.1.1"); err != nil {
+			t.Fatal(err)
+		}
+		l.release("1.1.1.1")
+	}
+	if err := l.acquire("1.1.1.1"); !errors.Is(err, ErrAcceptRate) {
+		t.Errorf("expect %v, got %v", ErrAcceptRate, err)
+	}
+	if err := l.acquire("2.2.2.2"); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
+
+func TestServerMaxConnsPerIP(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"), MaxConnsPerIP(1))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(context.Background(), WithEndpoint(u.Host), WithRetryPolicy(time.Millisecond, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err = c.Call(context.Background(), 1, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second connection from the same ip is closed by the server
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) && !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("expect connection closed by server, got %v", err)
+	}
+
+	// accept loop exits once the listener is closed
+	if err = srv.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = net.DialTimeout("tcp", u.Host, 100*time.Millisecond); err == nil {
+		t.Error("expect dial error after stop")
+	}
+}
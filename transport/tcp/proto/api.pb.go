@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.2
+// 	protoc-gen-go v1.36.6
 // 	protoc        v3.6.1
 // source: tcp/proto/api.proto
 
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -20,29 +21,33 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type Body struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PlayerId      int64                  `protobuf:"varint,1,opt,name=playerId,proto3" json:"playerId,omitempty"` // 玩家ID
-	Ops           int32                  `protobuf:"varint,2,opt,name=ops,proto3" json:"ops,omitempty"`           // 操作码
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`          // 额外的数据
+type Payload struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Op            int32                    `protobuf:"varint,1,opt,name=op,proto3" json:"op,omitempty"`                                                                                  // 操作类型
+	Place         int32                    `protobuf:"varint,2,opt,name=place,proto3" json:"place,omitempty"`                                                                            // 占位，无用
+	Type          int32                    `protobuf:"varint,3,opt,name=type,proto3" json:"type,omitempty"`                                                                              // 消息类型：0=Push, 1=Request, 2=Response, 3=Ping, 4=Pong, 5=Sub, 6=Unsub, 7=Pub
+	Seq           int32                    `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`                                                                                // 序列号，回包需对应
+	Code          int32                    `protobuf:"varint,5,opt,name=code,proto3" json:"code,omitempty"`                                                                              // 错误码，回包参数
+	Body          []byte                   `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`                                                                               // 包体
+	Header        map[string]*HeaderValues `protobuf:"bytes,7,rep,name=header,proto3" json:"header,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 元数据，仅 LayoutProtobuf 编码
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Body) Reset() {
-	*x = Body{}
+func (x *Payload) Reset() {
+	*x = Payload{}
 	mi := &file_tcp_proto_api_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Body) String() string {
+func (x *Payload) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Body) ProtoMessage() {}
+func (*Payload) ProtoMessage() {}
 
-func (x *Body) ProtoReflect() protoreflect.Message {
+func (x *Payload) ProtoReflect() protoreflect.Message {
 	mi := &file_tcp_proto_api_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -54,58 +59,81 @@ func (x *Body) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Body.ProtoReflect.Descriptor instead.
-func (*Body) Descriptor() ([]byte, []int) {
+// Deprecated: Use Payload.ProtoReflect.Descriptor instead.
+func (*Payload) Descriptor() ([]byte, []int) {
 	return file_tcp_proto_api_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *Body) GetPlayerId() int64 {
+func (x *Payload) GetOp() int32 {
 	if x != nil {
-		return x.PlayerId
+		return x.Op
 	}
 	return 0
 }
 
-func (x *Body) GetOps() int32 {
+func (x *Payload) GetPlace() int32 {
 	if x != nil {
-		return x.Ops
+		return x.Place
 	}
 	return 0
 }
 
-func (x *Body) GetData() []byte {
+func (x *Payload) GetType() int32 {
 	if x != nil {
-		return x.Data
+		return x.Type
+	}
+	return 0
+}
+
+func (x *Payload) GetSeq() int32 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Payload) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Payload) GetBody() []byte {
+	if x != nil {
+		return x.Body
 	}
 	return nil
 }
 
-type Payload struct {
+func (x *Payload) GetHeader() map[string]*HeaderValues {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+type HeaderValues struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Op            int32                  `protobuf:"varint,1,opt,name=op,proto3" json:"op,omitempty"`       // 操作类型
-	Place         int32                  `protobuf:"varint,2,opt,name=place,proto3" json:"place,omitempty"` // 占位，无用
-	Type          int32                  `protobuf:"varint,3,opt,name=type,proto3" json:"type,omitempty"`   // 消息类型：Push、Request、Response、Ping、Pong、Sub、Unsub、Pub
-	Seq           int32                  `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`     // 序列号，回包需对应
-	Code          int32                  `protobuf:"varint,5,opt,name=code,proto3" json:"code,omitempty"`   // 错误码，回包参数
-	Body          []byte                 `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`    // 包体
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Payload) Reset() {
-	*x = Payload{}
+func (x *HeaderValues) Reset() {
+	*x = HeaderValues{}
 	mi := &file_tcp_proto_api_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Payload) String() string {
+func (x *HeaderValues) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Payload) ProtoMessage() {}
+func (*HeaderValues) ProtoMessage() {}
 
-func (x *Payload) ProtoReflect() protoreflect.Message {
+func (x *HeaderValues) ProtoReflect() protoreflect.Message {
 	mi := &file_tcp_proto_api_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -117,98 +145,209 @@ func (x *Payload) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Payload.ProtoReflect.Descriptor instead.
-func (*Payload) Descriptor() ([]byte, []int) {
+// Deprecated: Use HeaderValues.ProtoReflect.Descriptor instead.
+func (*HeaderValues) Descriptor() ([]byte, []int) {
 	return file_tcp_proto_api_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *Payload) GetOp() int32 {
+func (x *HeaderValues) GetValues() []string {
 	if x != nil {
-		return x.Op
+		return x.Values
 	}
-	return 0
+	return nil
 }
 
-func (x *Payload) GetPlace() int32 {
+type Body struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      int64                  `protobuf:"varint,1,opt,name=playerId,proto3" json:"playerId,omitempty"` // 玩家ID
+	Ops           int32                  `protobuf:"varint,2,opt,name=ops,proto3" json:"ops,omitempty"`           // 操作码
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`          // 额外的数据
+	Error         *Error                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`        // 错误详情，Payload.code 非 0 时携带，固定包头编码也可用
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Body) Reset() {
+	*x = Body{}
+	mi := &file_tcp_proto_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Body) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Body) ProtoMessage() {}
+
+func (x *Body) ProtoReflect() protoreflect.Message {
+	mi := &file_tcp_proto_api_proto_msgTypes[2]
 	if x != nil {
-		return x.Place
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *Payload) GetType() int32 {
+// Deprecated: Use Body.ProtoReflect.Descriptor instead.
+func (*Body) Descriptor() ([]byte, []int) {
+	return file_tcp_proto_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Body) GetPlayerId() int64 {
 	if x != nil {
-		return x.Type
+		return x.PlayerId
 	}
 	return 0
 }
 
-func (x *Payload) GetSeq() int32 {
+func (x *Body) GetOps() int32 {
 	if x != nil {
-		return x.Seq
+		return x.Ops
 	}
 	return 0
 }
 
-func (x *Payload) GetCode() int32 {
+func (x *Body) GetData() []byte {
 	if x != nil {
-		return x.Code
+		return x.Data
 	}
-	return 0
+	return nil
 }
 
-func (x *Payload) GetBody() []byte {
+func (x *Body) GetError() *Error {
 	if x != nil {
-		return x.Body
+		return x.Error
 	}
 	return nil
 }
 
-var File_tcp_proto_api_proto protoreflect.FileDescriptor
+// Error 响应的错误详情，与 Payload.code 一起还原为 errors.Error
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`                                                                               // 错误原因，如 TABLE_NOT_FOUND
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`                                                                             // 错误信息
+	Metadata      map[string]string      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 错误元数据
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_tcp_proto_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_tcp_proto_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_tcp_proto_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Error) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
 
-var file_tcp_proto_api_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x74, 0x63, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x61, 0x70, 0x69, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x22, 0x48, 0x0a, 0x04, 0x42, 0x6f, 0x64, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x79,
-	0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x79,
-	0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6f, 0x70, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x03, 0x6f, 0x70, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x7d, 0x0a, 0x07, 0x50, 0x61,
-	0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x02, 0x6f, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74,
-	0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
-	0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x73, 0x65,
-	0x71, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x06, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x42, 0x11, 0x5a, 0x0f, 0x74, 0x63, 0x70,
-	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Error) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
 }
 
+var File_tcp_proto_api_proto protoreflect.FileDescriptor
+
+const file_tcp_proto_api_proto_rawDesc = "" +
+	"\n" +
+	"\x13tcp/proto/api.proto\x12\tapi.proto\"\x89\x02\n" +
+	"\aPayload\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\x05R\x02op\x12\x14\n" +
+	"\x05place\x18\x02 \x01(\x05R\x05place\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\x05R\x04type\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\x05R\x03seq\x12\x12\n" +
+	"\x04code\x18\x05 \x01(\x05R\x04code\x12\x12\n" +
+	"\x04body\x18\x06 \x01(\fR\x04body\x126\n" +
+	"\x06header\x18\a \x03(\v2\x1e.api.proto.Payload.HeaderEntryR\x06header\x1aR\n" +
+	"\vHeaderEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.api.proto.HeaderValuesR\x05value:\x028\x01\"&\n" +
+	"\fHeaderValues\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"p\n" +
+	"\x04Body\x12\x1a\n" +
+	"\bplayerId\x18\x01 \x01(\x03R\bplayerId\x12\x10\n" +
+	"\x03ops\x18\x02 \x01(\x05R\x03ops\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12&\n" +
+	"\x05error\x18\x04 \x01(\v2\x10.api.proto.ErrorR\x05error\"\xb2\x01\n" +
+	"\x05Error\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12:\n" +
+	"\bmetadata\x18\x03 \x03(\v2\x1e.api.proto.Error.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x11Z\x0ftcp/proto;protob\x06proto3"
+
 var (
 	file_tcp_proto_api_proto_rawDescOnce sync.Once
-	file_tcp_proto_api_proto_rawDescData = file_tcp_proto_api_proto_rawDesc
+	file_tcp_proto_api_proto_rawDescData []byte
 )
 
 func file_tcp_proto_api_proto_rawDescGZIP() []byte {
 	file_tcp_proto_api_proto_rawDescOnce.Do(func() {
-		file_tcp_proto_api_proto_rawDescData = protoimpl.X.CompressGZIP(file_tcp_proto_api_proto_rawDescData)
+		file_tcp_proto_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tcp_proto_api_proto_rawDesc), len(file_tcp_proto_api_proto_rawDesc)))
 	})
 	return file_tcp_proto_api_proto_rawDescData
 }
 
-var file_tcp_proto_api_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_tcp_proto_api_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_tcp_proto_api_proto_goTypes = []any{
-	(*Body)(nil),    // 0: api.proto.Body
-	(*Payload)(nil), // 1: api.proto.Payload
+	(*Payload)(nil),      // 0: api.proto.Payload
+	(*HeaderValues)(nil), // 1: api.proto.HeaderValues
+	(*Body)(nil),         // 2: api.proto.Body
+	(*Error)(nil),        // 3: api.proto.Error
+	nil,                  // 4: api.proto.Payload.HeaderEntry
+	nil,                  // 5: api.proto.Error.MetadataEntry
 }
 var file_tcp_proto_api_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4, // 0: api.proto.Payload.header:type_name -> api.proto.Payload.HeaderEntry
+	3, // 1: api.proto.Body.error:type_name -> api.proto.Error
+	5, // 2: api.proto.Error.metadata:type_name -> api.proto.Error.MetadataEntry
+	1, // 3: api.proto.Payload.HeaderEntry.value:type_name -> api.proto.HeaderValues
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_tcp_proto_api_proto_init() }
@@ -220,9 +359,9 @@ func file_tcp_proto_api_proto_init() {
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_tcp_proto_api_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tcp_proto_api_proto_rawDesc), len(file_tcp_proto_api_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -231,7 +370,6 @@ func file_tcp_proto_api_proto_init() {
 		MessageInfos:      file_tcp_proto_api_proto_msgTypes,
 	}.Build()
 	File_tcp_proto_api_proto = out.File
-	file_tcp_proto_api_proto_rawDesc = nil
 	file_tcp_proto_api_proto_goTypes = nil
 	file_tcp_proto_api_proto_depIdxs = nil
 }
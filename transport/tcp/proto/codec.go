@@ -0,0 +1,322 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/yola1107/kratos/v2/transport/tcp/internal/bufio"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+// Layout is the frame layout of a Codec.
+type Layout int
+
+const (
+	// LayoutHeader is the fixed binary header followed by the raw body:
+	// packLen(4) headerLen(2) op(4) place(4) type(4) seq(4) code(4) body.
+	// It does not carry Payload.Header, encoding headers fails with ErrProtoHeader.
+	LayoutHeader Layout = iota
+	// LayoutProtobuf is a length prefix followed by the protobuf encoded Payload.
+	LayoutProtobuf
+)
+
+// ErrProtoLayout proto layout error
+var ErrProtoLayout = errors.New("codec: unknown frame layout")
+
+// ErrProtoHeader is returned when encoding a Payload with headers in the
+// LayoutHeader layout, which has no room for them.
+var ErrProtoHeader = errors.New("codec: the header layout cannot carry payload headers")
+
+// DefaultCodec is the fixed header, little-endian codec with a 4 KiB body limit.
+var DefaultCodec = NewCodec()
+
+// Codec encodes and decodes Payload frames on a byte stream.
+type Codec interface {
+	// HeaderSize returns the number of bytes needed to compute the frame size.
+	HeaderSize() int
+	// FrameSize returns the total frame size described by header.
+	FrameSize(header []byte) (int, error)
+	// MaxFrameSize returns the largest frame accepted by the codec.
+	MaxFrameSize() int
+	// Unmarshal decodes a complete frame into p. p.Body may alias frame.
+	Unmarshal(frame []byte, p *Payload) error
+	// Marshal encodes p as a complete frame.
+	Marshal(p *Payload) ([]byte, error)
+}
+
+// CodecOption is a codec option.
+type CodecOption func(o *codecOptions)
+
+type codecOptions struct {
+	layout      Layout
+	maxBodySize int32
+	byteOrder   binary.ByteOrder
+}
+
+// WithLayout with frame layout.
+func WithLayout(l Layout) CodecOption {
+	return func(o *codecOptions) { o.layout = l }
+}
+
+// WithMaxBodySize with max body size.
+func WithMaxBodySize(size int32) CodecOption {
+	return func(o *codecOptions) { o.maxBodySize = size }
+}
+
+// WithByteOrder with byte order of the frame header.
+func WithByteOrder(order binary.ByteOrder) CodecOption {
+	return func(o *codecOptions) { o.byteOrder = order }
+}
+
+// NewCodec new a codec, defaults to the fixed header little-endian layout.
+func NewCodec(opts ...CodecOption) Codec {
+	o := codecOptions{
+		layout:      LayoutHeader,
+		maxBodySize: MaxBodySize,
+		byteOrder:   binary.LittleEndian,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.layout {
+	case LayoutProtobuf:
+		return &protobufCodec{maxBodySize: o.maxBodySize, order: o.byteOrder}
+	default:
+		return &headerCodec{maxBodySize: o.maxBodySize, order: o.byteOrder}
+	}
+}
+
+// headerCodec is the fixed binary header layout.
+type headerCodec struct {
+	maxBodySize int32
+	order       binary.ByteOrder
+}
+
+func (c *headerCodec) HeaderSize() int {
+	return _rawHeaderSize
+}
+
+func (c *headerCodec) MaxFrameSize() int {
+	return int(c.maxBodySize) + _rawHeaderSize
+}
+
+func (c *headerCodec) FrameSize(header []byte) (int, error) {
+	if len(header) < _rawHeaderSize {
+		return 0, ErrProtoHeaderLen
+	}
+	packLen := int32(c.order.Uint32(header[_packOffset:_headerOffset]))
+	headerLen := int16(c.order.Uint16(header[_headerOffset:_opOffset]))
+	if headerLen != _rawHeaderSize {
+		return 0, ErrProtoHeaderLen
+	}
+	if packLen < _rawHeaderSize || packLen > c.maxBodySize+_rawHeaderSize {
+		return 0, ErrProtoPackLen
+	}
+	return int(packLen), nil
+}
+
+func (c *headerCodec) Unmarshal(frame []byte, p *Payload) error {
+	packLen, err := c.FrameSize(frame)
+	if err != nil {
+		return err
+	}
+	if len(frame) < packLen {
+		return ErrProtoPackLen
+	}
+	p.Op = int32(c.order.Uint32(frame[_opOffset:_placeOffset]))      // [6:10]
+	p.Place = int32(c.order.Uint32(frame[_placeOffset:_typeOffset])) // [10:14]
+	p.Type = int32(c.order.Uint32(frame[_typeOffset:_seqOffset]))    // [14:18]
+	p.Seq = int32(c.order.Uint32(frame[_seqOffset:_codeOffset]))     // [18:22]
+	p.Code = int32(c.order.Uint32(frame[_codeOffset:]))              // [22:26]
+	if packLen > _rawHeaderSize {
+		p.Body = frame[_rawHeaderSize:packLen]
+	} else {
+		p.Body = nil
+	}
+	return nil
+}
+
+func (c *headerCodec) Marshal(p *Payload) ([]byte, error) {
+	if len(p.Header) > 0 {
+		return nil, ErrProtoHeader
+	}
+	if int32(len(p.Body)) > c.maxBodySize {
+		return nil, ErrProtoPackLen
+	}
+	buf := make([]byte, _rawHeaderSize+len(p.Body))
+	c.putHeader(buf, p)
+	copy(buf[_rawHeaderSize:], p.Body)
+	return buf, nil
+}
+
+// WriteTo encodes the header in the writer buffer and copies the body after it.
+func (c *headerCodec) WriteTo(wr *bufio.Writer, p *Payload) error {
+	if len(p.Header) > 0 {
+		return ErrProtoHeader
+	}
+	if int32(len(p.Body)) > c.maxBodySize {
+		return ErrProtoPackLen
+	}
+	buf, err := wr.Peek(_rawHeaderSize)
+	if err != nil {
+		return err
+	}
+	c.putHeader(buf, p)
+	if len(p.Body) > 0 {
+		_, err = wr.Write(p.Body)
+	}
+	return err
+}
+
+func (c *headerCodec) putHeader(buf []byte, p *Payload) {
+	c.order.PutUint32(buf[_packOffset:], uint32(_rawHeaderSize+len(p.Body))) // [0:4]
+	c.order.PutUint16(buf[_headerOffset:], uint16(_rawHeaderSize))           // [4:6]
+	c.order.PutUint32(buf[_opOffset:], uint32(p.Op))                         // [6:10]
+	c.order.PutUint32(buf[_placeOffset:], uint32(p.Place))                   // [10:14]
+	c.order.PutUint32(buf[_typeOffset:], uint32(p.Type))                     // [14:18]
+	c.order.PutUint32(buf[_seqOffset:], uint32(p.Seq))                       // [18:22]
+	c.order.PutUint32(buf[_codeOffset:], uint32(p.Code))                     // [22:26]
+}
+
+// protobufCodec is the length prefixed protobuf layout.
+type protobufCodec struct {
+	maxBodySize int32
+	order       binary.ByteOrder
+}
+
+func (c *protobufCodec) HeaderSize() int {
+	return _packSize
+}
+
+func (c *protobufCodec) MaxFrameSize() int {
+	return int(c.maxBodySize) + _packSize
+}
+
+func (c *protobufCodec) FrameSize(header []byte) (int, error) {
+	if len(header) < _packSize {
+		return 0, ErrProtoHeaderLen
+	}
+	size := c.order.Uint32(header[:_packSize])
+	if size == 0 || size > uint32(c.maxBodySize) {
+		return 0, ErrProtoPackLen
+	}
+	return int(size) + _packSize, nil
+}
+
+func (c *protobufCodec) Unmarshal(frame []byte, p *Payload) error {
+	n, err := c.FrameSize(frame)
+	if err != nil {
+		return err
+	}
+	if len(frame) < n {
+		return ErrProtoPackLen
+	}
+	return gproto.Unmarshal(frame[_packSize:n], p)
+}
+
+func (c *protobufCodec) Marshal(p *Payload) ([]byte, error) {
+	size := gproto.Size(p)
+	if int32(size) > c.maxBodySize {
+		return nil, ErrProtoPackLen
+	}
+	buf := make([]byte, _packSize, _packSize+size)
+	buf, err := gproto.MarshalOptions{UseCachedSize: true}.MarshalAppend(buf, p)
+	if err != nil {
+		return nil, err
+	}
+	c.order.PutUint32(buf[:_packSize], uint32(len(buf)-_packSize))
+	return buf, nil
+}
+
+// WriteTo marshals p in the writer buffer, frames larger than the buffer are
+// marshaled apart and written through.
+func (c *protobufCodec) WriteTo(wr *bufio.Writer, p *Payload) error {
+	size := gproto.Size(p)
+	if int32(size) > c.maxBodySize {
+		return ErrProtoPackLen
+	}
+	buf, err := wr.Peek(_packSize + size)
+	if errors.Is(err, bufio.ErrBufferFull) {
+		if buf, err = c.Marshal(p); err != nil {
+			return err
+		}
+		_, err = wr.Write(buf)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	c.order.PutUint32(buf[:_packSize], uint32(size))
+	_, err = gproto.MarshalOptions{UseCachedSize: true}.MarshalAppend(buf[_packSize:_packSize], p)
+	return err
+}
+
+// ReadFrame reads one frame from r and decodes it into p.
+// When r is a *bufio.Reader a frame that fits its buffer is not copied and
+// p.Body aliases the reader buffer until the next read, larger frames are copied.
+func ReadFrame(c Codec, r io.Reader, p *Payload) error {
+	if rr, ok := r.(*bufio.Reader); ok {
+		header, err := rr.Peek(c.HeaderSize())
+		if err != nil {
+			return err
+		}
+		n, err := c.FrameSize(header)
+		if err != nil {
+			return err
+		}
+		if n > rr.Size() {
+			frame := make([]byte, n)
+			if _, err = io.ReadFull(rr, frame); err != nil {
+				return err
+			}
+			return c.Unmarshal(frame, p)
+		}
+		frame, err := rr.Pop(n)
+		if err != nil {
+			return err
+		}
+		return c.Unmarshal(frame, p)
+	}
+	header := make([]byte, c.HeaderSize())
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	n, err := c.FrameSize(header)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, n)
+	copy(frame, header)
+	if _, err = io.ReadFull(r, frame[len(header):]); err != nil {
+		return err
+	}
+	return c.Unmarshal(frame, p)
+}
+
+// WriteFrame encodes p and writes it to w.
+// When w is a *bufio.Writer the frame is encoded in its buffer without an
+// intermediate copy.
+func WriteFrame(c Codec, w io.Writer, p *Payload) error {
+	if wr, ok := w.(*bufio.Writer); ok {
+		if fw, ok := c.(interface {
+			WriteTo(wr *bufio.Writer, p *Payload) error
+		}); ok {
+			return fw.WriteTo(wr, p)
+		}
+	}
+	buf, err := c.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// CloneBody detaches p.Body from the buffer it was decoded from.
+func (p *Payload) CloneBody() {
+	if p.Body != nil {
+		p.Body = bytes.Clone(p.Body)
+	}
+}
@@ -0,0 +1,154 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/yola1107/kratos/v2/transport/tcp/internal/bufio"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codecs := map[string]Codec{
+		"default":         DefaultCodec,
+		"header-big":      NewCodec(WithByteOrder(binary.BigEndian), WithMaxBodySize(1<<16)),
+		"protobuf-big":    NewCodec(WithLayout(LayoutProtobuf), WithByteOrder(binary.BigEndian), WithMaxBodySize(1<<16)),
+		"protobuf-little": NewCodec(WithLayout(LayoutProtobuf)),
+	}
+	for name, c := range codecs {
+		t.Run(name, func(t *testing.T) {
+			in := &Payload{Op: 1, Place: PlaceServer, Type: int32(Response), Seq: 7, Code: 3, Body: []byte("hello")}
+			buf := &bytes.Buffer{}
+			if err := WriteFrame(c, buf, in); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteFrame(c, buf, &Payload{Type: int32(Ping)}); err != nil {
+				t.Fatal(err)
+			}
+			rr := bufio.NewReaderSize(buf, c.MaxFrameSize())
+			out := &Payload{}
+			if err := ReadFrame(c, rr, out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Op != in.Op || out.Place != in.Place || out.Type != in.Type || out.Seq != in.Seq || out.Code != in.Code || !bytes.Equal(out.Body, in.Body) {
+				t.Errorf("expect %v, got %v", in, out)
+			}
+			if err := ReadFrame(c, rr, out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Type != int32(Ping) || len(out.Body) != 0 {
+				t.Errorf("expect empty ping, got %v", out)
+			}
+		})
+	}
+}
+
+func TestCodecMaxBodySize(t *testing.T) {
+	small := NewCodec(WithMaxBodySize(8))
+	if _, err := small.Marshal(&Payload{Body: make([]byte, 9)}); !errors.Is(err, ErrProtoPackLen) {
+		t.Errorf("expect %v, got %v", ErrProtoPackLen, err)
+	}
+	large := NewCodec(WithMaxBodySize(1 << 16))
+	frame, err := large.Marshal(&Payload{Body: make([]byte, 1<<13)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DefaultCodec.FrameSize(frame); !errors.Is(err, ErrProtoPackLen) {
+		t.Errorf("expect %v, got %v", ErrProtoPackLen, err)
+	}
+	if err = ReadFrame(large, bytes.NewReader(frame), &Payload{}); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
+
+func TestCodecByteOrderMismatch(t *testing.T) {
+	big := NewCodec(WithByteOrder(binary.BigEndian))
+	frame, err := big.Marshal(&Payload{Body: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DefaultCodec.FrameSize(frame); err == nil {
+		t.Error("expect error for mismatched byte order")
+	}
+}
+
+func TestReadFrameLargerThanBuffer(t *testing.T) {
+	c := NewCodec(WithMaxBodySize(1 << 16))
+	in := &Payload{Op: 1, Body: bytes.Repeat([]byte("x"), 1<<13)}
+	buf := &bytes.Buffer{}
+	if err := WriteFrame(c, buf, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFrame(c, buf, &Payload{Type: int32(Ping)}); err != nil {
+		t.Fatal(err)
+	}
+	rr := bufio.NewReaderSize(buf, 1<<10)
+	out := &Payload{}
+	if err := ReadFrame(c, rr, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Op != in.Op || !bytes.Equal(out.Body, in.Body) {
+		t.Errorf("expect op %d with %d bytes, got op %d with %d bytes", in.Op, len(in.Body), out.Op, len(out.Body))
+	}
+	if err := ReadFrame(c, rr, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Type != int32(Ping) {
+		t.Errorf("expect ping, got %v", out)
+	}
+}
+
+func TestWriteFrameBuffered(t *testing.T) {
+	codecs := map[string]Codec{
+		"header":   NewCodec(WithMaxBodySize(1 << 16)),
+		"protobuf": NewCodec(WithLayout(LayoutProtobuf), WithMaxBodySize(1<<16)),
+	}
+	for name, c := range codecs {
+		t.Run(name, func(t *testing.T) {
+			// the second frame does not fit the writer buffer
+			for _, in := range []*Payload{
+				{Op: 1, Type: int32(Request), Seq: 2, Body: []byte("hello")},
+				{Op: 1, Type: int32(Request), Seq: 3, Body: bytes.Repeat([]byte("x"), 1<<12)},
+			} {
+				expect, err := c.Marshal(in)
+				if err != nil {
+					t.Fatal(err)
+				}
+				buf := &bytes.Buffer{}
+				wr := bufio.NewWriterSize(buf, 1<<10)
+				if err = WriteFrame(c, wr, in); err != nil {
+					t.Fatal(err)
+				}
+				if err = wr.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), expect) {
+					t.Errorf("expect the frame of Marshal for seq %d", in.Seq)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFrameAllocs(t *testing.T) {
+	wr := bufio.NewWriterSize(io.Discard, 1<<12)
+	p := &Payload{Op: 1, Type: int32(Push), Body: []byte("hello")}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = WriteFrame(DefaultCodec, wr, p)
+	})
+	if allocs != 0 {
+		t.Errorf("expect no allocation per frame, got %v", allocs)
+	}
+}
+
+func TestCodecHeaderLayoutHeaders(t *testing.T) {
+	p := &Payload{Type: int32(Request), Header: map[string]*HeaderValues{"x-md-global-foo": {Values: []string{"bar"}}}}
+	if _, err := DefaultCodec.Marshal(p); !errors.Is(err, ErrProtoHeader) {
+		t.Errorf("expect %v, got %v", ErrProtoHeader, err)
+	}
+	if err := WriteFrame(DefaultCodec, bufio.NewWriter(io.Discard), p); !errors.Is(err, ErrProtoHeader) {
+		t.Errorf("expect %v, got %v", ErrProtoHeader, err)
+	}
+}
@@ -0,0 +1,30 @@
+package proto
+
+import (
+	"github.com/yola1107/kratos/v2/errors"
+)
+
+// FromError returns the response code and error details of err, 0 and nil if err is nil.
+func FromError(err error) (int32, *Error) {
+	if err == nil {
+		return 0, nil
+	}
+	e := errors.FromError(err)
+	code := e.Code
+	if code == 0 {
+		code = errors.UnknownCode
+	}
+	return code, &Error{Reason: e.Reason, Message: e.Message, Metadata: e.Metadata}
+}
+
+// ToError rebuilds the error of a response from its code and error details, nil if code is 0.
+// A response without details, e.g. from an older server, keeps only the code.
+func ToError(code int32, e *Error) error {
+	if code == 0 {
+		return nil
+	}
+	if e == nil {
+		return errors.New(int(code), "", "server returned error code")
+	}
+	return errors.New(int(code), e.Reason, e.Message).WithMetadata(e.Metadata)
+}
@@ -1,7 +1,6 @@
 package proto
 
 import (
-	"encoding/binary"
 	"errors"
 
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bufio"
@@ -22,7 +21,6 @@ const (
 	_seqSize       = 4
 	_codeSize      = 4
 	_rawHeaderSize = _packSize + _headerSize + _opSize + _placeSize + _typeSize + _seqSize + _codeSize
-	_maxPackSize   = MaxBodySize + int32(_rawHeaderSize)
 	// offset
 	_packOffset   = 0
 	_headerOffset = _packOffset + _packSize
@@ -47,78 +45,19 @@ var (
 	ProtoFinish = &Payload{Op: OpProtoFinish}
 )
 
+// ReadTCP read a proto from tcp reader with the DefaultCodec.
 func (p *Payload) ReadTCP(rr *bufio.Reader) (err error) {
-	var (
-		bodyLen   int
-		headerLen int16
-		packLen   int32
-		buf       []byte
-	)
-	if buf, err = rr.Pop(_rawHeaderSize); err != nil {
-		return
-	}
-	packLen = int32(binary.LittleEndian.Uint32(buf[_packOffset:_headerOffset])) // [0:4] 包长度=headLen+bodyLen
-	headerLen = int16(binary.LittleEndian.Uint16(buf[_headerOffset:_opOffset])) // [4:6]
-	p.Op = int32(binary.LittleEndian.Uint32(buf[_opOffset:_placeOffset]))       // [6:10]
-	p.Place = int32(binary.LittleEndian.Uint32(buf[_placeOffset:_typeOffset]))  // [10:14]
-	p.Type = int32(binary.LittleEndian.Uint32(buf[_typeOffset:_seqOffset]))     // [14:18]
-	p.Seq = int32(binary.LittleEndian.Uint32(buf[_seqOffset:_codeOffset]))      // [18:22]
-	p.Code = int32(binary.LittleEndian.Uint32(buf[_codeOffset:]))               // [22:]
-
-	if packLen > _maxPackSize {
-		return ErrProtoPackLen
-	}
-	if headerLen != _rawHeaderSize {
-		return ErrProtoHeaderLen
-	}
-	if bodyLen = int(packLen - int32(headerLen)); bodyLen > 0 {
-		p.Body, err = rr.Pop(bodyLen)
-	} else {
-		p.Body = nil
-	}
-	return
+	return ReadFrame(DefaultCodec, rr, p)
 }
 
+// WriteTCP write a proto to tcp writer with the DefaultCodec.
 func (p *Payload) WriteTCP(wr *bufio.Writer) (err error) {
-	var (
-		buf     []byte
-		packLen int32
-	)
-	packLen = _rawHeaderSize + int32(len(p.Body))
-	if buf, err = wr.Peek(_rawHeaderSize); err != nil {
-		return
-	}
-	// 按顺序写入固定长度字段
-	binary.LittleEndian.PutUint32(buf[_packOffset:], uint32(packLen))          // [0:4]
-	binary.LittleEndian.PutUint16(buf[_headerOffset:], uint16(_rawHeaderSize)) // [4:6]
-	binary.LittleEndian.PutUint32(buf[_opOffset:], uint32(p.Op))               // [6:10]
-	binary.LittleEndian.PutUint32(buf[_placeOffset:], uint32(p.Place))         // [10:14]
-	binary.LittleEndian.PutUint32(buf[_typeOffset:], uint32(p.Type))           // [14:18]
-	binary.LittleEndian.PutUint32(buf[_seqOffset:], uint32(p.Seq))             // [18:22]
-	binary.LittleEndian.PutUint32(buf[_codeOffset:], uint32(p.Code))           // [22:]
-	if p.Body != nil {
-		_, err = wr.Write(p.Body)
-	}
-	return
+	return WriteFrame(DefaultCodec, wr, p)
 }
 
+// WriteTCPHeart write a heartbeat proto without body with the DefaultCodec.
 func (p *Payload) WriteTCPHeart(wr *bufio.Writer) (err error) {
-	var (
-		buf     []byte
-		packLen int32
-	)
-	packLen = _rawHeaderSize
-	if buf, err = wr.Peek(_rawHeaderSize); err != nil {
-		return
-	}
-	binary.LittleEndian.PutUint32(buf[_packOffset:], uint32(packLen))          // [0:4]
-	binary.LittleEndian.PutUint16(buf[_headerOffset:], uint16(_rawHeaderSize)) // [4:6]
-	binary.LittleEndian.PutUint32(buf[_opOffset:], uint32(p.Op))               // [6:10]
-	binary.LittleEndian.PutUint32(buf[_placeOffset:], uint32(p.Place))         // [10:14]
-	binary.LittleEndian.PutUint32(buf[_typeOffset:], uint32(p.Type))           // [14:18]
-	binary.LittleEndian.PutUint32(buf[_seqOffset:], uint32(p.Seq))             // [18:22]
-	binary.LittleEndian.PutUint32(buf[_codeOffset:], uint32(p.Code))           // [22:]
-	return
+	return WriteFrame(DefaultCodec, wr, &Payload{Op: p.Op, Place: p.Place, Type: p.Type, Seq: p.Seq, Code: p.Code})
 }
 
 // // ReadWebsocket read a proto from websocket connection.
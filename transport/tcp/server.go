@@ -7,25 +7,26 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"net/http"
 	"net/url"
-	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	kerrors "github.com/yola1107/kratos/v2/errors"
 	"github.com/yola1107/kratos/v2/internal/endpoint"
 	"github.com/yola1107/kratos/v2/internal/host"
 	"github.com/yola1107/kratos/v2/internal/matcher"
 	"github.com/yola1107/kratos/v2/log"
 	"github.com/yola1107/kratos/v2/middleware"
 	"github.com/yola1107/kratos/v2/transport"
+	"github.com/yola1107/kratos/v2/transport/session"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bucket"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/round"
 	xtime "github.com/yola1107/kratos/v2/transport/tcp/internal/time"
 	"github.com/yola1107/kratos/v2/transport/tcp/proto"
 	"github.com/zhenjl/cityhash"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	gproto "google.golang.org/protobuf/proto"
 )
 
@@ -34,6 +35,9 @@ var (
 	_ transport.Endpointer = (*Server)(nil)
 )
 
+// ErrSessionNotFound is returned when no connection is bound to the key.
+var ErrSessionNotFound = errors.New("tcp: session not found")
+
 // ServerConfig defines the configuration for the TCP server
 type ServerConfig struct {
 	TCP       *TCP
@@ -41,7 +45,7 @@ type ServerConfig struct {
 	Protocol  *Protocol
 	Auth      *Auth
 	Bucket    *Bucket
-	ChanSize  *ChanSize
+	Limit     *Limit
 }
 
 type TCP struct {
@@ -85,47 +89,12 @@ type Bucket struct {
 	Channel int
 }
 
-type ChanSize struct {
-	Push       int
-	Close      int
-	Disconnect int
-}
-
-// PushData represents data to be pushed to a client
-type PushData struct {
-	Mid  string
-	Ops  int32
-	Data []byte
-}
-
-// ChanList holds the communication channels for the server
-type ChanList struct {
-	PushChan       chan *PushData
-	CloseChan      chan string
-	DisconnectChan chan string
-}
-
-// MethodDesc describes an RPC method
-type MethodDesc struct {
-	Ops        int32
-	MethodName string
-	Handler    methodHandler
-}
-
-type methodHandler func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error)
-
-// ServiceDesc describes a service and its methods
-type ServiceDesc struct {
-	ServiceName string
-	// The pointer to the service interface. Used to check whether the user
-	// provided implementation satisfies the interface requirements.
-	HandlerType interface{}
-	Methods     []MethodDesc
-}
-
-type service struct {
-	server interface{}
-	md     map[int32]*MethodDesc
+// Limit is connection limit config, zero disables a limit.
+type Limit struct {
+	MaxConns      int
+	MaxConnsPerIP int
+	AcceptRate    float64 // accepted connections per second per ip
+	AcceptBurst   int
 }
 
 // ServerOption is TCP server option.
@@ -159,6 +128,35 @@ func Timeout(timeout time.Duration) ServerOption {
 	}
 }
 
+// Codec with server wire codec, defaults to proto.DefaultCodec.
+func Codec(c proto.Codec) ServerOption {
+	return func(s *Server) {
+		s.codec = c
+	}
+}
+
+// MaxConns with max total connections.
+func MaxConns(n int) ServerOption {
+	return func(s *Server) {
+		s.c.Limit.MaxConns = n
+	}
+}
+
+// MaxConnsPerIP with max connections from one ip.
+func MaxConnsPerIP(n int) ServerOption {
+	return func(s *Server) {
+		s.c.Limit.MaxConnsPerIP = n
+	}
+}
+
+// AcceptRate with accepted connections per second per ip and its burst.
+func AcceptRate(r float64, burst int) ServerOption {
+	return func(s *Server) {
+		s.c.Limit.AcceptRate = r
+		s.c.Limit.AcceptBurst = burst
+	}
+}
+
 // Middleware with server middleware.
 func Middleware(m ...middleware.Middleware) ServerOption {
 	return func(o *Server) {
@@ -166,6 +164,14 @@ func Middleware(m ...middleware.Middleware) ServerOption {
 	}
 }
 
+// SessionHook adds hooks called with every session opened and closed,
+// after the hooks of the registered services. Either may be nil.
+func SessionHook(onOpen, onClose session.Hook) ServerOption {
+	return func(o *Server) {
+		o.AddSessionHook(onOpen, onClose)
+	}
+}
+
 // Server is an TCP server wrapper.
 type Server struct {
 	network    string
@@ -176,17 +182,20 @@ type Server struct {
 	endpoint   *url.URL
 	timeout    time.Duration
 	middleware matcher.Matcher
-
-	c              *ServerConfig
-	round          *round.Round
-	buckets        []*bucket.Bucket
-	bucketIdx      uint32
-	serverID       string
-	unaryInts      []UnaryServerInterceptor
-	m              *service
-	pushChan       chan *PushData
-	closeChan      chan string
-	disconnectChan chan string
+	codec      proto.Codec
+
+	c         *ServerConfig
+	round     *round.Round
+	buckets   []*bucket.Bucket
+	bucketIdx uint32
+	serverID  string
+	unaryInts []UnaryServerInterceptor
+	limiter   *connLimiter
+	services  *serviceRegistry
+
+	sessions   sync.Map // key -> *ServerSession
+	openHooks  []session.Hook
+	closeHooks []session.Hook
 }
 
 // NewServer creates an TCP server by options.
@@ -196,6 +205,8 @@ func NewServer(opts ...ServerOption) *Server {
 		address:    ":3101",
 		timeout:    1 * time.Second,
 		middleware: matcher.New(),
+		codec:      proto.DefaultCodec,
+		services:   newServiceRegistry(),
 		c: &ServerConfig{
 			TCP: &TCP{
 				Sndbuf:       4096,
@@ -227,11 +238,7 @@ func NewServer(opts ...ServerOption) *Server {
 				Size:    32,
 				Channel: 1024,
 			},
-			ChanSize: &ChanSize{
-				Push:       2048,
-				Close:      1024,
-				Disconnect: 1024,
-			},
+			Limit: &Limit{},
 		},
 	}
 	for _, o := range opts {
@@ -250,6 +257,9 @@ func NewServer(opts ...ServerOption) *Server {
 		TimerSize:    s.c.Protocol.TimerSize,
 	})
 
+	// init limiter
+	s.limiter = newConnLimiter(s.c.Limit)
+
 	// init bucket
 	s.buckets = make([]*bucket.Bucket, s.c.Bucket.Size)
 	s.bucketIdx = uint32(s.c.Bucket.Size)
@@ -257,21 +267,16 @@ func NewServer(opts ...ServerOption) *Server {
 		s.buckets[i] = bucket.NewBucket(s.c.Bucket.Channel)
 	}
 
-	// init chan
-	s.pushChan = make(chan *PushData, s.c.ChanSize.Push)
-	s.closeChan = make(chan string, s.c.ChanSize.Close)
-	s.disconnectChan = make(chan string, s.c.ChanSize.Disconnect)
-
 	s.Use(s.unaryServerInterceptor())
 	return s
 }
 
 // Start starts the TCP server
 func (s *Server) Start(ctx context.Context) error {
-	log.Infof("[TCP] server listening on: %s", s.lis.Addr().String())
 	if err := s.listenAndEndpoint(); err != nil {
 		return err
 	}
+	log.Infof("[TCP] server listening on: %s", s.lis.Addr().String())
 	err := s.StartTCP(runtime.NumCPU())
 	return err
 }
@@ -279,6 +284,10 @@ func (s *Server) Start(ctx context.Context) error {
 // Stop gracefully shuts down the server
 func (s *Server) Stop(ctx context.Context) error {
 	log.Infof("[TCP] server stopping")
+	if s.lis != nil {
+		// accept goroutines exit on net.ErrClosed
+		return s.lis.Close()
+	}
 	return nil
 }
 
@@ -288,6 +297,15 @@ func (s *Server) GetBucket(subKey string) *bucket.Bucket {
 	return s.buckets[idx]
 }
 
+// UseMiddleware uses a service middleware with selector.
+// selector examples:
+//   - '/*'
+//   - '/helloworld.v1.Greeter/*'
+//   - '/helloworld.v1.Greeter/SayHello'
+func (s *Server) UseMiddleware(selector string, m ...middleware.Middleware) {
+	s.middleware.Add(selector, m...)
+}
+
 // Use adds interceptors to the server
 func (s *Server) Use(handlers ...UnaryServerInterceptor) *Server {
 	if len(s.unaryInts)+len(handlers) > math.MaxInt8/2 {
@@ -326,72 +344,84 @@ func (s *Server) listenAndEndpoint() error {
 	return s.err
 }
 
-// RegisterService registers a service with the server
-func (s *Server) RegisterService(sd *ServiceDesc, ss interface{}) *ChanList {
-	ht := reflect.TypeOf(sd.HandlerType).Elem()
-	st := reflect.TypeOf(ss)
-	if !st.Implements(ht) {
-		log.Fatalf("tcp: Server.RegisterService found the handler of type %v that does not satisfy %v", st, ht)
-	}
-	if s.m != nil {
-		log.Fatalf("tcp: Server.RegisterService found duplicate service registration for %q", sd.ServiceName)
-	}
-	srv := &service{
-		server: ss,
-		md:     make(map[int32]*MethodDesc),
-	}
-	for i := range sd.Methods {
-		d := &sd.Methods[i]
-		srv.md[d.Ops] = d
-	}
-	s.m = srv
-	cl := &ChanList{
-		PushChan:       s.pushChan,
-		CloseChan:      s.closeChan,
-		DisconnectChan: s.disconnectChan,
-	}
-	// close
-	go func() {
-		for {
-			mid := <-s.closeChan
-			if channel := s.GetBucket(mid).Channel(mid); channel != nil {
-				channel.Close()
-			}
-		}
-	}()
-	// push
-	go func() {
-		for {
-			pd := <-s.pushChan
-			s.PushByChannel(pd.Mid, pd.Ops, pd.Data)
+// Session returns the session of key.
+func (s *Server) Session(key string) (*ServerSession, bool) {
+	v, ok := s.sessions.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*ServerSession), true
+}
+
+// Push pushes msg to the connection of key.
+func (s *Server) Push(ctx context.Context, key string, ops int32, msg gproto.Message) error {
+	ch := s.GetBucket(key).Channel(key)
+	if ch == nil {
+		return ErrSessionNotFound
+	}
+	p, err := pushPayload(ops, msg)
+	if err != nil {
+		return err
+	}
+	return ch.Push(p)
+}
+
+// Broadcast pushes msg to all connections, it returns the first push error.
+func (s *Server) Broadcast(ctx context.Context, ops int32, msg gproto.Message) error {
+	p, err := pushPayload(ops, msg)
+	if err != nil {
+		return err
+	}
+	var first error
+	for _, b := range s.buckets {
+		if err = b.Broadcast(p); err != nil && first == nil {
+			first = err
 		}
-	}()
-	return cl
+	}
+	return first
+}
+
+// PushToBucket pushes msg to all connections held by the bucket idx.
+func (s *Server) PushToBucket(ctx context.Context, idx int, ops int32, msg gproto.Message) error {
+	if idx < 0 || idx >= len(s.buckets) {
+		return fmt.Errorf("tcp: bucket index %d out of range [0, %d)", idx, len(s.buckets))
+	}
+	p, err := pushPayload(ops, msg)
+	if err != nil {
+		return err
+	}
+	return s.buckets[idx].Broadcast(p)
 }
 
-// PushByChannel pushes data to a specific channel
-func (s *Server) PushByChannel(sessionID string, ops int32, data []byte) {
-	ch := s.GetBucket(sessionID).Channel(sessionID)
+// Buckets returns the bucket count.
+func (s *Server) Buckets() int {
+	return len(s.buckets)
+}
+
+// Kick closes the connection of key, the close callback runs once the connection exits.
+func (s *Server) Kick(key string) error {
+	ch := s.GetBucket(key).Channel(key)
 	if ch == nil {
-		log.Warnf("channel not found for session %s", sessionID)
-		return
+		return ErrSessionNotFound
 	}
+	return ch.Kick()
+}
 
+func pushPayload(ops int32, msg gproto.Message) (*proto.Payload, error) {
+	data, err := gproto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
 	body, err := gproto.Marshal(&proto.Body{Ops: ops, Data: data})
 	if err != nil {
-		log.Errorf("marshal push body error: %v", err)
-		return
+		return nil, err
 	}
-
-	payload := &proto.Payload{
+	return &proto.Payload{
+		Op:    ops,
 		Place: proto.PlaceServer,
 		Type:  int32(proto.Push),
 		Body:  body,
-	}
-
-	if err := ch.Push(payload); err != nil {
-		log.Errorf("push failed for channel %s: %v", sessionID, err)
-	}
+	}, nil
 }
 
 // Operate processes an incoming payload
@@ -406,24 +436,26 @@ func (s *Server) Operate(ctx context.Context, p *proto.Payload) error {
 
 	case int32(proto.Request):
 		p.Type = int32(proto.Response)
+		var (
+			reply []byte
+			err   error
+		)
 		reqBody := &proto.Body{}
-		if err := gproto.Unmarshal(p.Body, reqBody); err != nil {
-			return status.Errorf(codes.InvalidArgument, "failed to unmarshal request body: %v", err)
-		}
-		md, ok := s.m.md[reqBody.Ops]
-		if !ok {
-			return status.Errorf(codes.Unimplemented, "Unimplemented Ops=%d.", reqBody.Ops)
+		if err = gproto.Unmarshal(p.Body, reqBody); err != nil {
+			err = kerrors.BadRequest("", fmt.Sprintf("failed to unmarshal request body: %v", err))
+		} else if md, ok := s.services.Methods[reqBody.Ops]; !ok {
+			err = kerrors.New(http.StatusNotImplemented, "", fmt.Sprintf("Unimplemented Ops=%d.", reqBody.Ops))
+		} else {
+			reply, err = md.Desc.Handler(md.Service.Server, ctx, reqBody.Data, s.interceptor)
 		}
-		reply, errCode := md.Handler(s.m.server, ctx, reqBody.Data, s.interceptor)
-		st, _ := status.FromError(errCode)
-		p.Code = int32(st.Code())
 		body := &proto.Body{
 			Ops:  reqBody.Ops,
 			Data: reply,
 		}
+		p.Code, body.Error = proto.FromError(err)
 		bodyData, _ := gproto.Marshal(body)
 		p.Body = bodyData
-		return errCode
+		return err
 
 	default:
 		log.Warnf("unknown payload.Type: %v", p.Type)
@@ -466,8 +498,8 @@ func (s *Server) unaryServerInterceptor() UnaryServerInterceptor {
 		reply, err := h(ctx, req)
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || strings.HasPrefix(err.Error(), "panic:") {
-				st, _ := status.FromError(err)
-				log.Errorf("[TCP] unary method=[%s] unexpected err. st.Code=%d(%v) st.Message=%v", info.FullMethod, st.Code(), st.Code(), err)
+				e := kerrors.FromError(err)
+				log.Errorf("[TCP] unary method=[%s] unexpected err. code=%d reason=%s message=%v", info.FullMethod, e.Code, e.Reason, err)
 			}
 			return nil, err
 		}
@@ -2,25 +2,30 @@ package tcp
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	kerrors "github.com/yola1107/kratos/v2/errors"
 	"github.com/yola1107/kratos/v2/log"
 	"github.com/yola1107/kratos/v2/metadata"
+	"github.com/yola1107/kratos/v2/transport/session"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bucket"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bufio"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/bytes"
 	"github.com/yola1107/kratos/v2/transport/tcp/internal/channel"
 	xtime "github.com/yola1107/kratos/v2/transport/tcp/internal/time"
 	"github.com/yola1107/kratos/v2/transport/tcp/proto"
-	"google.golang.org/grpc/status"
 )
 
 const (
 	maxInt = 1<<31 - 1
+
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
 )
 
 // StartTCP listen all tcp.bind and start accept connections.
@@ -34,40 +39,65 @@ func (s *Server) StartTCP(accept int) (err error) {
 
 func (s *Server) acceptTCP(lis net.Listener) {
 	var (
-		conn *net.TCPConn
-		err  error
-		r    int
+		conn      net.Conn
+		err       error
+		r         int
+		tempDelay time.Duration // how long to sleep on accept failure
 	)
 	for {
-		tcpLn, ok := lis.(*net.TCPListener)
-		if !ok {
-			log.Fatal("listener is not a *net.TCPListener")
-			return
-		}
-		if conn, err = tcpLn.AcceptTCP(); err != nil {
+		if conn, err = lis.Accept(); err != nil {
 			// if listener close then return
-			log.Errorf("listener.Accept(\"%s\") error(%v)", lis.Addr().String(), err)
-			return
-		}
-		if err = conn.SetKeepAlive(s.c.TCP.KeepAlive); err != nil {
-			log.Errorf("conn.SetKeepAlive() error(%v)", err)
-			return
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if tempDelay == 0 {
+				tempDelay = minAcceptDelay
+			} else {
+				tempDelay *= 2
+			}
+			if tempDelay > maxAcceptDelay {
+				tempDelay = maxAcceptDelay
+			}
+			log.Errorf("listener.Accept(\"%s\") error(%v); retrying in %v", lis.Addr().String(), err, tempDelay)
+			time.Sleep(tempDelay)
+			continue
 		}
-		if err = conn.SetReadBuffer(s.c.TCP.Rcvbuf); err != nil {
-			log.Errorf("conn.SetReadBuffer() error(%v)", err)
-			return
+		tempDelay = 0
+		if err = s.setupConn(conn); err != nil {
+			log.Errorf("setup conn \"%s\" error(%v)", conn.RemoteAddr().String(), err)
+			conn.Close()
+			continue
 		}
-		if err = conn.SetWriteBuffer(s.c.TCP.Sndbuf); err != nil {
-			log.Errorf("conn.SetWriteBuffer() error(%v)", err)
-			return
+		ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if err = s.limiter.acquire(ip); err != nil {
+			log.Warnf("reject conn \"%s\" error(%v)", conn.RemoteAddr().String(), err)
+			conn.Close()
+			continue
 		}
-		go s.serveTCP(conn, r)
+		go func(conn net.Conn, r int) {
+			defer s.limiter.release(ip)
+			s.serveTCP(conn, r)
+		}(conn, r)
 		if r++; r == maxInt {
 			r = 0
 		}
 	}
 }
 
+func (s *Server) setupConn(conn net.Conn) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if err := tcpConn.SetKeepAlive(s.c.TCP.KeepAlive); err != nil {
+		return err
+	}
+	if err := tcpConn.SetReadBuffer(s.c.TCP.Rcvbuf); err != nil {
+		return err
+	}
+	return tcpConn.SetWriteBuffer(s.c.TCP.Sndbuf)
+}
+
 func (s *Server) serveTCP(conn net.Conn, r int) {
 	var (
 		// timer
@@ -99,7 +129,6 @@ func (s *Server) serveTCP(conn net.Conn, r int) {
 	uid := uuid.New().String()
 	step := 0
 	trd = tr.Add(time.Duration(s.c.Protocol.HandshakeTimeout), func() {
-		s.disconnectChan <- uid
 		conn.Close()
 		log.Errorf("key: %s remoteIP: %s step: %d tcp handshake timeout", ch.Key, conn.RemoteAddr().String(), step)
 	})
@@ -110,6 +139,7 @@ func (s *Server) serveTCP(conn net.Conn, r int) {
 		if err = s.authTCP(conn, rr, p); err == nil {
 			err = wr.Flush()
 			ch.Key = uid
+			ch.Conn = conn
 			hb = time.Duration(s.c.Protocol.HandshakeTimeout)
 			b = s.GetBucket(ch.Key)
 			b.Put(ch)
@@ -133,11 +163,15 @@ func (s *Server) serveTCP(conn net.Conn, r int) {
 	step = 3
 	// hanshake ok start dispatch goroutine
 	go s.dispatchTCP(conn, wr, wp, wb, ch)
+	sess := newServerSession(ch)
+	s.sessions.Store(ch.Key, sess)
+	ctx = session.NewContext(ctx, sess)
+	s.onSessionOpen(sess)
 	for {
 		if p, err = ch.CliProto.Set(); err != nil {
 			break
 		}
-		if err = p.ReadTCP(rr); err != nil {
+		if err = proto.ReadFrame(s.codec, rr, p); err != nil {
 			break
 		}
 		// log.Infof("ReadTCP. p={op:%d place:%d type:%d seq:%d code:%d body:%+v}", p.Op, p.Place, p.Type, p.Seq, p.Code, p.Body)
@@ -147,8 +181,8 @@ func (s *Server) serveTCP(conn net.Conn, r int) {
 			step++
 		}
 		if err = s.Operate(ctx, p); err != nil {
-			st, _ := status.FromError(err)
-			log.Warnf("Operate err. st.Code=%d(%+v) st.Message=%v", st.Code(), st.Code(), st.Message())
+			e := kerrors.FromError(err)
+			log.Warnf("Operate err. code=%d reason=%s message=%v", e.Code, e.Reason, e.Message)
 			// break
 		}
 		ch.CliProto.SetAdv()
@@ -158,12 +192,36 @@ func (s *Server) serveTCP(conn net.Conn, r int) {
 		log.Errorf("key: %s server tcp failed error(%v)", ch.Key, err)
 	}
 	log.Infof("disconnect. key=%s step=%d", ch.Key, step)
-	s.disconnectChan <- uid
 	b.Del(ch)
 	tr.Del(trd)
 	rp.Put(rb)
 	conn.Close()
 	ch.Close()
+	sess.closed.Store(true)
+	s.sessions.Delete(ch.Key)
+	s.onSessionClose(sess)
+}
+
+func (s *Server) onSessionOpen(sess *ServerSession) {
+	for _, srv := range s.services.Services {
+		if fn := srv.OnOpen; fn != nil {
+			safeCall(func() { fn(sess.ID()) })
+		}
+	}
+	for _, fn := range s.openHooks {
+		safeCall(func() { fn(sess) })
+	}
+}
+
+func (s *Server) onSessionClose(sess *ServerSession) {
+	for _, srv := range s.services.Services {
+		if fn := srv.OnClose; fn != nil {
+			safeCall(func() { fn(sess.ID()) })
+		}
+	}
+	for _, fn := range s.closeHooks {
+		safeCall(func() { fn(sess) })
+	}
 }
 
 func (s *Server) dispatchTCP(conn net.Conn, wr *bufio.Writer, wp *bytes.Pool, wb *bytes.Buffer, ch *channel.Channel) {
@@ -183,16 +241,11 @@ func (s *Server) dispatchTCP(conn net.Conn, wr *bufio.Writer, wp *bytes.Pool, wb
 				if p, err = ch.CliProto.Get(); err != nil {
 					break
 				}
-				switch p.Type {
-				case int32(proto.Pong):
-					if err = p.WriteTCPHeart(wr); err != nil {
-						goto failed
-					}
-				default:
-					// log.Infof("DispatchTCP. p={op:%d place:%d type:%d seq:%d code:%d body:%+v}", p.Op, p.Place, p.Type, p.Seq, p.Code, p.Body)
-					if err = p.WriteTCP(wr); err != nil {
-						goto failed
-					}
+				if p.Type == int32(proto.Pong) {
+					p.Body = nil
+				}
+				if err = proto.WriteFrame(s.codec, wr, p); err != nil {
+					goto failed
 				}
 				// reset payload back to ring
 				p.Body = nil // avoid memory leak
@@ -200,7 +253,7 @@ func (s *Server) dispatchTCP(conn net.Conn, wr *bufio.Writer, wp *bytes.Pool, wb
 			}
 		default:
 			// server send
-			if err = p.WriteTCP(wr); err != nil {
+			if err = proto.WriteFrame(s.codec, wr, p); err != nil {
 				goto failed
 			}
 		}
@@ -230,7 +283,7 @@ func (s *Server) authTCP(conn net.Conn, rr *bufio.Reader, p *proto.Payload) (err
 	}
 	// reqBody := &proto.Body{}
 	// for {
-	// 	if err = p.ReadTCP(rr); err != nil {
+	// 	if err = proto.ReadFrame(s.codec, rr, p); err != nil {
 	// 		return
 	// 	}
 	// 	if p.Type == int32(proto.Request) {
@@ -0,0 +1,165 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	kerrors "github.com/yola1107/kratos/v2/errors"
+	"github.com/yola1107/kratos/v2/transport/tcp/proto"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testService interface{}
+
+type testServer struct{}
+
+var testServiceDesc = ServiceDesc{
+	ServiceName: "test.Service",
+	HandlerType: (*testService)(nil),
+	Methods: []MethodDesc{
+		{
+			Ops:        1,
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+				return data, nil
+			},
+		},
+		{
+			Ops:        3,
+			MethodName: "Fail",
+			Handler: func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error) {
+				return nil, kerrors.Forbidden("NOT_YOUR_TURN", "not your turn").WithMetadata(map[string]string{"seat": "2"})
+			},
+		},
+	},
+}
+
+func newTestServer(t *testing.T, onOpen, onClose SessionFunc) (*Server, string) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	srv.RegisterService(&testServiceDesc, &testServer{}, onOpen, onClose)
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return srv, u.Host
+}
+
+func TestServerPushAndKick(t *testing.T) {
+	opened := make(chan string, 1)
+	closed := make(chan string, 1)
+	srv, addr := newTestServer(t,
+		func(key string) { opened <- key },
+		func(key string) { closed <- key },
+	)
+	defer srv.Stop(context.Background())
+
+	pushed := make(chan string, 2)
+	c, err := NewClient(context.Background(),
+		WithEndpoint(addr),
+		WithRetryPolicy(time.Millisecond, 0),
+		WithPushHandler(map[int32]PushHandler{
+			7: func(data []byte) {
+				v := &wrapperspb.StringValue{}
+				_ = gproto.Unmarshal(data, v)
+				pushed <- v.Value
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var key string
+	select {
+	case key = <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("open callback not called")
+	}
+
+	if err = srv.Push(context.Background(), key, 7, wrapperspb.String("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Broadcast(context.Background(), 7, wrapperspb.String("all")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"one", "all"} {
+		select {
+		case v := <-pushed:
+			if v != want {
+				t.Errorf("expect %v, got %v", want, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("push %q not received", want)
+		}
+	}
+
+	if err = srv.Push(context.Background(), "missing", 7, wrapperspb.String("x")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expect %v, got %v", ErrSessionNotFound, err)
+	}
+	if err = srv.PushToBucket(context.Background(), srv.Buckets(), 7, wrapperspb.String("x")); err == nil {
+		t.Error("expect out of range error")
+	}
+
+	if err = srv.Kick(key); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-closed:
+		if v != key {
+			t.Errorf("expect %v, got %v", key, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close callback not called")
+	}
+}
+
+func TestServerRegisterDuplicate(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "duplicate service registration") {
+			t.Errorf("expect a duplicate registration panic, got %v", err)
+		}
+	}()
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+}
+
+func TestServerOperateError(t *testing.T) {
+	srv := NewServer()
+	if err := srv.register(&testServiceDesc, &testServer{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ops    int32
+		code   int32
+		reason string
+		md     map[string]string
+	}{
+		{ops: 3, code: 403, reason: "NOT_YOUR_TURN", md: map[string]string{"seat": "2"}},
+		{ops: 9, code: 501},
+	}
+	for _, tt := range tests {
+		data, _ := gproto.Marshal(&proto.Body{Ops: tt.ops})
+		p := &proto.Payload{Type: int32(proto.Request), Body: data}
+		if err := srv.Operate(context.Background(), p); err == nil {
+			t.Errorf("ops %d: expect error", tt.ops)
+		}
+		body := &proto.Body{}
+		if err := gproto.Unmarshal(p.Body, body); err != nil {
+			t.Fatal(err)
+		}
+		e := kerrors.FromError(proto.ToError(p.Code, body.Error))
+		if p.Type != int32(proto.Response) || e.Code != tt.code || e.Reason != tt.reason || e.Metadata["seat"] != tt.md["seat"] {
+			t.Errorf("ops %d: expect code %d reason %q, got type %d error %v", tt.ops, tt.code, tt.reason, p.Type, e)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yola1107/kratos/v2/transport/internal/ops"
+	"github.com/yola1107/kratos/v2/transport/session"
+)
+
+// SessionFunc is called with the channel key when a connection opens or closes.
+type SessionFunc func(key string)
+
+// MethodDesc describes an RPC method
+type MethodDesc struct {
+	Ops        int32
+	MethodName string
+	Handler    methodHandler
+}
+
+type methodHandler func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error)
+
+// ServiceDesc describes a service and its methods
+type ServiceDesc struct {
+	ServiceName string
+	// The pointer to the service interface. Used to check whether the user
+	// provided implementation satisfies the interface requirements.
+	HandlerType interface{}
+	Methods     []MethodDesc
+}
+
+type serviceRegistry = ops.Registry[MethodDesc, SessionFunc]
+
+func newServiceRegistry() *serviceRegistry {
+	return ops.NewRegistry[MethodDesc, SessionFunc](func(d *MethodDesc) (int32, string) { return d.Ops, d.MethodName })
+}
+
+// RegisterService registers a service with the server, several services may be
+// registered as long as their Ops are globally unique.
+// onOpen and onClose may be nil, they are called from the connection goroutine.
+// It panics with the registration error on a duplicate service or conflicting Ops.
+func (s *Server) RegisterService(sd *ServiceDesc, ss interface{}, onOpen, onClose SessionFunc) {
+	if err := s.register(sd, ss, onOpen, onClose); err != nil {
+		panic(fmt.Errorf("tcp: Server.RegisterService: %w", err))
+	}
+}
+
+// AddSessionHook adds hooks like the SessionHook option, it must be called
+// before the server starts, e.g. by the generated socket registrations.
+func (s *Server) AddSessionHook(onOpen, onClose session.Hook) {
+	if onOpen != nil {
+		s.openHooks = append(s.openHooks, onOpen)
+	}
+	if onClose != nil {
+		s.closeHooks = append(s.closeHooks, onClose)
+	}
+}
+
+func (s *Server) register(sd *ServiceDesc, ss interface{}, onOpen, onClose SessionFunc) error {
+	return s.services.Register(sd.ServiceName, sd.HandlerType, ss, sd.Methods, onOpen, onClose)
+}
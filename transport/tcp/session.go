@@ -0,0 +1,60 @@
+package tcp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/transport/session"
+	"github.com/yola1107/kratos/v2/transport/tcp/internal/channel"
+)
+
+var _ session.Session = (*ServerSession)(nil)
+
+// ServerSession is a client connection of the tcp server, its ID is the channel key.
+type ServerSession struct {
+	ch     *channel.Channel
+	attrs  sync.Map
+	closed atomic.Bool
+}
+
+func newServerSession(ch *channel.Channel) *ServerSession {
+	return &ServerSession{ch: ch}
+}
+
+func (s *ServerSession) ID() string           { return s.ch.Key }
+func (s *ServerSession) Closed() bool         { return s.closed.Load() }
+func (s *ServerSession) RemoteAddr() net.Addr { return s.ch.Conn.RemoteAddr() }
+
+// Get returns the attribute of key.
+func (s *ServerSession) Get(key string) (any, bool) { return s.attrs.Load(key) }
+
+// Set sets the attribute of key.
+func (s *ServerSession) Set(key string, value any) { s.attrs.Store(key, value) }
+
+// Delete deletes the attribute of key.
+func (s *ServerSession) Delete(key string) { s.attrs.Delete(key) }
+
+// Push sends msg to the client as a push of cmd, it's safe to call from any goroutine.
+func (s *ServerSession) Push(cmd int32, msg gproto.Message) error {
+	if s.Closed() {
+		return ErrSessionNotFound
+	}
+	p, err := pushPayload(cmd, msg)
+	if err != nil {
+		return err
+	}
+	return s.ch.Push(p)
+}
+
+// Close closes the connection, the close callbacks run once the connection exits.
+func (s *ServerSession) Close(reason string) error {
+	if s.Closed() {
+		return nil
+	}
+	log.Infof("[tcp] closing session: key=%s, reason=%s", s.ch.Key, reason)
+	return s.ch.Kick()
+}
@@ -0,0 +1,91 @@
+package tcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/yola1107/kratos/v2/transport/session"
+)
+
+func TestServerSessionHook(t *testing.T) {
+	opened := make(chan session.Session, 1)
+	closed := make(chan session.Session, 1)
+	srv := NewServer(Address("127.0.0.1:0"), SessionHook(
+		func(s session.Session) { opened <- s },
+		func(s session.Session) { closed <- s },
+	))
+	srv.RegisterService(&testServiceDesc, &testServer{}, nil, nil)
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop(context.Background())
+
+	pushed := make(chan string, 1)
+	c, err := NewClient(context.Background(),
+		WithEndpoint(u.Host),
+		WithRetryPolicy(time.Millisecond, 0),
+		WithPushHandler(map[int32]PushHandler{
+			7: func(data []byte) {
+				v := &wrapperspb.StringValue{}
+				_ = gproto.Unmarshal(data, v)
+				pushed <- v.Value
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var sess session.Session
+	select {
+	case sess = <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("open hook not called")
+	}
+	if s, ok := srv.Session(sess.ID()); !ok || s != sess {
+		t.Errorf("expect session %s registered", sess.ID())
+	}
+	if sess.RemoteAddr() == nil {
+		t.Error("expect remote addr")
+	}
+	sess.Set("uid", int64(1001))
+	if v, ok := sess.Get("uid"); !ok || v.(int64) != 1001 {
+		t.Errorf("expect uid 1001, got %v", v)
+	}
+
+	if err = sess.Push(7, wrapperspb.String("hi")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-pushed:
+		if v != "hi" {
+			t.Errorf("expect %v, got %v", "hi", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push not received")
+	}
+
+	if err = sess.Close("kicked"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-closed:
+		if s != sess || !s.Closed() {
+			t.Errorf("expect closed session %s, got %v", sess.ID(), s.ID())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close hook not called")
+	}
+	if _, ok := srv.Session(sess.ID()); ok {
+		t.Error("expect session removed")
+	}
+}
@@ -13,8 +13,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/yola1107/kratos/v2/internal/endpoint"
 	"github.com/yola1107/kratos/v2/library/xgo"
 	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/registry"
+	"github.com/yola1107/kratos/v2/selector"
+	"github.com/yola1107/kratos/v2/transport/internal/resolver"
 	"github.com/yola1107/kratos/v2/transport/websocket/proto"
 
 	"github.com/gorilla/websocket"
@@ -86,6 +90,26 @@ func WithRetryPolicy(delay time.Duration, maxAttempt int32) ClientOption {
 	}
 }
 
+// WithDiscovery with client discovery, used by discovery:///<service> endpoints.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) { o.discovery = d }
+}
+
+// WithNodeFilter with select filters.
+func WithNodeFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *clientOptions) { o.nodeFilters = filters }
+}
+
+// WithSelector with node selector builder (p2c, wrr, random), defaults to the global selector.
+func WithSelector(b selector.Builder) ClientOption {
+	return func(o *clientOptions) { o.selector = b }
+}
+
+// WithPath with the request path of discovered nodes, defaults to /.
+func WithPath(path string) ClientOption {
+	return func(o *clientOptions) { o.path = path }
+}
+
 // clientOptions is websocket client options
 type clientOptions struct {
 	ctx             context.Context
@@ -100,13 +124,20 @@ type clientOptions struct {
 	session         *SessionConfig
 	retryDelay      time.Duration
 	retryMaxAttempt int32
+	discovery       registry.Discovery
+	nodeFilters     []selector.NodeFilter
+	selector        selector.Builder
+	path            string
 }
 
 type Client struct {
 	opts       *clientOptions
 	url        *url.URL
+	selector   selector.Selector
+	r          *resolver.Resolver
 	seq        int32
-	reqPool    sync.Map // seq -> command(int32)
+	reqPool    sync.Map // seq -> command(int32) or chan *proto.Payload
+	pushes     sync.Map // command -> PushHandler
 	session    *Session
 	retryCount atomic.Int32
 }
@@ -128,6 +159,7 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		},
 		retryDelay:      3 * time.Second,
 		retryMaxAttempt: -1, // unlimited retry
+		path:            "/",
 	}
 
 	// 应用选项
@@ -146,9 +178,31 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		seq:     0,
 		reqPool: sync.Map{},
 	}
+	for command, handler := range options.pushHandler {
+		c.pushes.Store(command, handler)
+	}
+	if service, ok := resolver.ParseTarget(options.endpoint); ok {
+		if options.discovery == nil {
+			return nil, fmt.Errorf("[websocket client] discovery is required by endpoint %s", options.endpoint)
+		}
+		c.selector = resolver.NewSelector(options.selector)
+		r, err := resolver.New(ctx, options.discovery, service, endpoint.Scheme("ws", options.tlsConf != nil), c.selector, options.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("[websocket client] new resolver failed: %w", err)
+		}
+		c.r = r
+		// the watcher lives as long as the client context
+		go func() {
+			<-ctx.Done()
+			_ = r.Close()
+		}()
+	}
 
 	// 立即尝试连接
 	if err := c.Reconnect(); err != nil {
+		if c.r != nil {
+			_ = c.r.Close()
+		}
 		return nil, err
 	}
 
@@ -185,7 +239,7 @@ func (c *Client) Reconnect() error {
 	c.Close()
 
 	for attempt := int32(1); ; attempt++ {
-		conn, _, err := dialer.DialContext(c.opts.ctx, c.url.String(), nil)
+		conn, err := c.dial(&dialer)
 		if err == nil {
 			c.retryCount.Store(0)
 			c.session = NewSession(c, conn, c.opts.session)
@@ -207,6 +261,24 @@ func (c *Client) Reconnect() error {
 	}
 }
 
+// dial dials the endpoint, or a node picked by the selector for discovery endpoints.
+func (c *Client) dial(dialer *websocket.Dialer) (conn *websocket.Conn, err error) {
+	target := c.url.String()
+	if c.selector != nil {
+		var (
+			node selector.Node
+			done selector.DoneFunc
+		)
+		if node, done, err = c.selector.Select(c.opts.ctx, selector.WithNodeFilter(c.opts.nodeFilters...)); err != nil {
+			return nil, err
+		}
+		defer func() { done(c.opts.ctx, selector.DoneInfo{Err: err}) }()
+		target = (&url.URL{Scheme: node.Scheme(), Host: node.Address(), Path: c.opts.path}).String()
+	}
+	conn, _, err = dialer.DialContext(c.opts.ctx, target, nil)
+	return conn, err
+}
+
 // calculateBackoff computes exponential backoff delay
 func (c *Client) calculateBackoff(attempt int32) time.Duration {
 	backoff := float64(c.opts.retryDelay) * math.Pow(1.5, float64(attempt-1))
@@ -235,10 +307,46 @@ func (c *Client) OnSessionClose(sess *Session) {
 	}
 }
 
-// Request sends a request message
+// Request sends a request message, the response is delivered to the ResponseHandler of command.
 func (c *Client) Request(command int32, msg gproto.Message) error {
+	_, err := c.request(command, msg, command)
+	return err
+}
+
+// Call sends a request and waits for its response until ctx is done.
+func (c *Client) Call(ctx context.Context, command int32, req, reply gproto.Message) error {
+	if _, ok := ctx.Deadline(); !ok && c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+	done := make(chan *proto.Payload, 1)
+	seq, err := c.request(command, req, done)
+	if err != nil {
+		return err
+	}
+	select {
+	case p := <-done:
+		if p == nil {
+			return ErrClosedRequest
+		}
+		if err := proto.ToError(p.Code, p.Error); err != nil {
+			return err
+		}
+		if reply == nil {
+			return nil
+		}
+		return gproto.Unmarshal(p.Body, reply)
+	case <-ctx.Done():
+		c.reqPool.Delete(seq)
+		return ctx.Err()
+	}
+}
+
+// request stores pending (command or response channel) by seq and sends the payload.
+func (c *Client) request(command int32, msg gproto.Message, pending any) (int32, error) {
 	if !c.IsAlive() {
-		return ErrClosedRequest
+		return 0, ErrClosedRequest
 	}
 
 	seq := atomic.AddInt32(&c.seq, 1)
@@ -249,17 +357,21 @@ func (c *Client) Request(command int32, msg gproto.Message) error {
 
 	data, err := gproto.Marshal(msg)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	c.reqPool.Store(seq, command)
-	return c.session.SendPayload(&proto.Payload{
+	c.reqPool.Store(seq, pending)
+	if err = c.session.SendPayload(&proto.Payload{
 		Op:      proto.OpRequest,
 		Place:   proto.PlaceClient,
 		Seq:     seq,
 		Command: command,
 		Body:    data,
-	})
+	}); err != nil {
+		c.reqPool.Delete(seq)
+		return 0, err
+	}
+	return seq, nil
 }
 
 // DispatchMessage handles incoming messages
@@ -283,22 +395,35 @@ func (c *Client) DispatchMessage(sess *Session, data []byte) error {
 
 // handleResponse processes response messages
 func (c *Client) handleResponse(p *proto.Payload) {
-	cmdInterface, loaded := c.reqPool.LoadAndDelete(p.Seq)
+	pending, loaded := c.reqPool.LoadAndDelete(p.Seq)
 	if !loaded {
 		return
 	}
 
-	if command, ok := cmdInterface.(int32); ok {
-		if handler, exists := c.opts.responseHandler[command]; exists {
+	switch v := pending.(type) {
+	case int32:
+		if handler, exists := c.opts.responseHandler[v]; exists {
 			safeCall(func() { handler(p.Body, p.Code) })
 		}
+	case chan *proto.Payload:
+		v <- p
+	}
+}
+
+// OnPush sets the handler of the pushes of command, replacing the handler
+// of WithPushHandler. A nil handler removes it.
+func (c *Client) OnPush(command int32, handler PushHandler) {
+	if handler == nil {
+		c.pushes.Delete(command)
+		return
 	}
+	c.pushes.Store(command, handler)
 }
 
 // handlePush processes push messages
 func (c *Client) handlePush(p *proto.Payload) {
-	if handler, exists := c.opts.pushHandler[p.Command]; exists {
-		safeCall(func() { handler(p.Body) })
+	if v, exists := c.pushes.Load(p.Command); exists {
+		safeCall(func() { v.(PushHandler)(p.Body) })
 	}
 }
 
@@ -314,9 +439,14 @@ func (c *Client) Close(msg ...string) {
 		reason = "client closed: " + strings.Join(msg, "; ")
 	}
 	c.session = nil
-	s.Close(true, reason)
-	c.reqPool.Range(func(key, value any) bool {
-		c.reqPool.Delete(key)
+	s.close(true, reason)
+	c.reqPool.Range(func(key, _ any) bool {
+		// only the side that removes the request may complete it, DispatchMessage may race here
+		if value, loaded := c.reqPool.LoadAndDelete(key); loaded {
+			if done, ok := value.(chan *proto.Payload); ok {
+				done <- nil
+			}
+		}
 		return true
 	})
 }
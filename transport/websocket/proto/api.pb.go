@@ -29,6 +29,7 @@ type Payload struct {
 	Code          int32                  `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`       // 错误码
 	Command       int32                  `protobuf:"varint,5,opt,name=command,proto3" json:"command,omitempty"` // 命令编号 (如LoginReq=1001)
 	Body          []byte                 `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`        // 业务数据ProtoMessage（如 LoginReq/Resp/MatchPush 的二进制）
+	Error         *Error                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`      // 错误详情，code 非 0 时携带
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -105,18 +106,94 @@ func (x *Payload) GetBody() []byte {
 	return nil
 }
 
+func (x *Payload) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Error 响应的错误详情，与 code 一起还原为 errors.Error
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`                                                                               // 错误原因，如 TABLE_NOT_FOUND
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`                                                                             // 错误信息
+	Metadata      map[string]string      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 错误元数据
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_proto_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_proto_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Error) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Error) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 var File_proto_api_proto protoreflect.FileDescriptor
 
 const file_proto_api_proto_rawDesc = "" +
 	"\n" +
-	"\x0fproto/api.proto\x12\x0fwebsocket.proto\"\x83\x01\n" +
+	"\x0fproto/api.proto\x12\x0fwebsocket.proto\"\xb1\x01\n" +
 	"\aPayload\x12\x0e\n" +
 	"\x02op\x18\x01 \x01(\x05R\x02op\x12\x14\n" +
 	"\x05place\x18\x02 \x01(\x05R\x05place\x12\x10\n" +
 	"\x03seq\x18\x03 \x01(\x05R\x03seq\x12\x12\n" +
 	"\x04code\x18\x04 \x01(\x05R\x04code\x12\x18\n" +
 	"\acommand\x18\x05 \x01(\x05R\acommand\x12\x12\n" +
-	"\x04body\x18\x06 \x01(\fR\x04bodyB\x17Z\x15websocket/proto;protob\x06proto3"
+	"\x04body\x18\x06 \x01(\fR\x04body\x12,\n" +
+	"\x05error\x18\a \x01(\v2\x16.websocket.proto.ErrorR\x05error\"\xb8\x01\n" +
+	"\x05Error\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12@\n" +
+	"\bmetadata\x18\x03 \x03(\v2$.websocket.proto.Error.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x17Z\x15websocket/proto;protob\x06proto3"
 
 var (
 	file_proto_api_proto_rawDescOnce sync.Once
@@ -130,16 +207,20 @@ func file_proto_api_proto_rawDescGZIP() []byte {
 	return file_proto_api_proto_rawDescData
 }
 
-var file_proto_api_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_api_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_proto_api_proto_goTypes = []any{
 	(*Payload)(nil), // 0: websocket.proto.Payload
+	(*Error)(nil),   // 1: websocket.proto.Error
+	nil,             // 2: websocket.proto.Error.MetadataEntry
 }
 var file_proto_api_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: websocket.proto.Payload.error:type_name -> websocket.proto.Error
+	2, // 1: websocket.proto.Error.metadata:type_name -> websocket.proto.Error.MetadataEntry
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_api_proto_init() }
@@ -153,7 +234,7 @@ func file_proto_api_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_api_proto_rawDesc), len(file_proto_api_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -20,10 +20,10 @@ import (
 	"github.com/yola1107/kratos/v2/log"
 	"github.com/yola1107/kratos/v2/middleware"
 	"github.com/yola1107/kratos/v2/transport"
+	"github.com/yola1107/kratos/v2/transport/session"
 	"github.com/yola1107/kratos/v2/transport/websocket/proto"
 
 	"github.com/gorilla/websocket"
-	"google.golang.org/grpc/codes"
 	gproto "google.golang.org/protobuf/proto"
 )
 
@@ -77,6 +77,14 @@ func Middleware(m ...middleware.Middleware) ServerOption {
 	return func(o *Server) { o.middleware.Use(m...) }
 }
 
+// SessionHook adds hooks called with every session opened and closed,
+// after the hooks of the registered services. Either may be nil.
+func SessionHook(onOpen, onClose session.Hook) ServerOption {
+	return func(o *Server) {
+		o.AddSessionHook(onOpen, onClose)
+	}
+}
+
 // Server is a Websocket server wrapper.
 type Server struct {
 	*http.Server
@@ -95,7 +103,9 @@ type Server struct {
 	upgrader     *websocket.Upgrader      // WebSocket升级器
 	sessionMgr   *SessionManager          // 会话管理
 	unaryInts    []UnaryServerInterceptor // 拦截器链
-	m            *service                 // 注册的服务
+	services     *serviceRegistry         // 注册的服务，Ops -> 方法
+	openHooks    []session.Hook
+	closeHooks   []session.Hook
 }
 
 // NewServer creates a Websocket server by options.
@@ -120,6 +130,7 @@ func NewServer(opts ...ServerOption) *Server {
 			CheckOrigin:     func(r *http.Request) bool { return true },
 		},
 		sessionMgr: NewSessionManager(),
+		services:   newServiceRegistry(),
 	}
 
 	for _, o := range opts {
@@ -136,6 +147,15 @@ func NewServer(opts ...ServerOption) *Server {
 	return srv
 }
 
+// UseMiddleware uses a service middleware with selector.
+// selector examples:
+//   - '/*'
+//   - '/helloworld.v1.Greeter/*'
+//   - '/helloworld.v1.Greeter/SayHello'
+func (s *Server) UseMiddleware(selector string, m ...middleware.Middleware) {
+	s.middleware.Add(selector, m...)
+}
+
 func (s *Server) Use(handlers ...UnaryServerInterceptor) *Server {
 	if len(s.unaryInts)+len(handlers) > math.MaxInt8/2 {
 		panic("websocket: server use too many handlers")
@@ -231,14 +251,24 @@ func (s *Server) Stop(ctx context.Context) error {
 
 func (s *Server) OnSessionOpen(sess *Session) {
 	s.sessionMgr.Add(sess)
-	if s.m.connectFunc != nil {
-		s.m.connectFunc(sess)
+	for _, srv := range s.services.Services {
+		if fn := srv.OnOpen; fn != nil {
+			safeCall(func() { fn(sess) })
+		}
+	}
+	for _, fn := range s.openHooks {
+		safeCall(func() { fn(sess) })
 	}
 }
 
 func (s *Server) OnSessionClose(sess *Session) {
-	if s.m.disconnectFunc != nil {
-		s.m.disconnectFunc(sess)
+	for _, srv := range s.services.Services {
+		if fn := srv.OnClose; fn != nil {
+			safeCall(func() { fn(sess) })
+		}
+	}
+	for _, fn := range s.closeHooks {
+		safeCall(func() { fn(sess) })
 	}
 	s.sessionMgr.Delete(sess)
 }
@@ -252,6 +282,7 @@ func (s *Server) DispatchMessage(sess *Session, data []byte) error {
 
 	ctx := context.WithValue(s.baseCtx, CtxSessionKey, sess)
 	ctx = context.WithValue(ctx, CtxSessionIDKey, sess.id)
+	ctx = session.NewContext(ctx, sess)
 
 	switch p.Op {
 	case proto.OpPing:
@@ -266,21 +297,21 @@ func (s *Server) DispatchMessage(sess *Session, data []byte) error {
 func (s *Server) operate(ctx context.Context, sess *Session, p *proto.Payload) error {
 	p.Op, p.Place = proto.OpResponse, proto.PlaceServer
 
-	srv := s.m
-	md, ok := srv.md[p.Command]
+	md, ok := s.services.Methods[p.Command]
 	if !ok {
-		p.Code = int32(codes.Unimplemented)
+		p.Code, p.Error = proto.FromError(kerrors.Newf(http.StatusNotImplemented, "", "unimplemented command=%d", p.Command))
+		p.Body = nil
 		log.Warnf("[websocket] unimplemented command=%d, session=%s", p.Command, sess.ID())
 		return sess.SendPayload(p)
 	}
 
-	reply, err := md.Handler(srv.server, ctx, p.Body, s.interceptor)
+	reply, err := md.Desc.Handler(md.Service.Server, ctx, p.Body, s.interceptor)
 	if err != nil {
-		e := kerrors.FromError(err)
-		p.Code, p.Body = e.Code, nil
-		log.Errorf("[websocket] handler error command=%d, session=%s: %v", p.Command, sess.ID(), e.Message)
+		p.Code, p.Error = proto.FromError(err)
+		p.Body = nil
+		log.Errorf("[websocket] handler error command=%d, session=%s: %v", p.Command, sess.ID(), p.Error.Message)
 	} else {
-		p.Code, p.Body = 0, reply
+		p.Code, p.Error, p.Body = 0, nil, reply
 	}
 
 	return sess.SendPayload(p)
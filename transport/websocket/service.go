@@ -2,9 +2,10 @@ package websocket
 
 import (
 	"context"
-	"reflect"
+	"fmt"
 
-	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/transport/internal/ops"
+	"github.com/yola1107/kratos/v2/transport/session"
 )
 
 type ServiceDesc struct {
@@ -15,14 +16,6 @@ type ServiceDesc struct {
 	Methods     []MethodDesc
 }
 
-type service struct {
-	server interface{}
-	md     map[int32]*MethodDesc
-
-	connectFunc    func(*Session) // 连接建立回调
-	disconnectFunc func(*Session) // 连接关闭回调
-}
-
 type MethodDesc struct {
 	Ops        int32
 	MethodName string
@@ -31,25 +24,32 @@ type MethodDesc struct {
 
 type methodHandler func(srv interface{}, ctx context.Context, data []byte, interceptor UnaryServerInterceptor) ([]byte, error)
 
+type serviceRegistry = ops.Registry[MethodDesc, func(*Session)]
+
+func newServiceRegistry() *serviceRegistry {
+	return ops.NewRegistry[MethodDesc, func(*Session)](func(d *MethodDesc) (int32, string) { return d.Ops, d.MethodName })
+}
+
+// RegisterService registers a service with the server, several services may be
+// registered as long as their Ops are globally unique.
+// It panics with the registration error on a duplicate service or conflicting Ops.
 func (s *Server) RegisterService(sd *ServiceDesc, ss interface{}, onOpen, onClose func(session *Session)) {
-	ht := reflect.TypeOf(sd.HandlerType).Elem()
-	st := reflect.TypeOf(ss)
-	if !st.Implements(ht) {
-		log.Fatalf("websocket: Server.RegisterService found the handler of type %v that does not satisfy %v", st, ht)
-	}
-	if s.m != nil {
-		log.Fatalf("websocket: Server.RegisterService found duplicate service registration for %q", sd.ServiceName)
+	if err := s.register(sd, ss, onOpen, onClose); err != nil {
+		panic(fmt.Errorf("websocket: Server.RegisterService: %w", err))
 	}
-	srv := &service{
-		server: ss,
-		md:     make(map[int32]*MethodDesc),
+}
 
-		connectFunc:    onOpen,
-		disconnectFunc: onClose,
+// AddSessionHook adds hooks like the SessionHook option, it must be called
+// before the server starts, e.g. by the generated socket registrations.
+func (s *Server) AddSessionHook(onOpen, onClose session.Hook) {
+	if onOpen != nil {
+		s.openHooks = append(s.openHooks, onOpen)
 	}
-	for i := range sd.Methods {
-		d := &sd.Methods[i]
-		srv.md[d.Ops] = d
+	if onClose != nil {
+		s.closeHooks = append(s.closeHooks, onClose)
 	}
-	s.m = srv
+}
+
+func (s *Server) register(sd *ServiceDesc, ss interface{}, onOpen, onClose func(session *Session)) error {
+	return s.services.Register(sd.ServiceName, sd.HandlerType, ss, sd.Methods, onOpen, onClose)
 }
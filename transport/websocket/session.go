@@ -3,6 +3,7 @@ package websocket
 import (
 	"context"
 	"errors"
+	"net"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/yola1107/kratos/v2/library/xgo"
 	"github.com/yola1107/kratos/v2/log"
+	"github.com/yola1107/kratos/v2/transport/session"
 	"github.com/yola1107/kratos/v2/transport/websocket/proto"
 
 	"github.com/google/uuid"
@@ -40,6 +42,8 @@ type SessionConfig struct {
 	SendChanSize int
 }
 
+var _ session.Session = (*Session)(nil)
+
 type Session struct {
 	id        string
 	conn      *websocket.Conn
@@ -52,6 +56,7 @@ type Session struct {
 	closed    atomic.Bool
 	closeOnce sync.Once
 	connMu    sync.Mutex
+	attrs     sync.Map
 }
 
 func NewSession(h iHandler, conn *websocket.Conn, cfg *SessionConfig) *Session {
@@ -77,6 +82,16 @@ func (s *Session) ID() string            { return s.id }
 func (s *Session) Closed() bool          { return s.closed.Load() }
 func (s *Session) LastActive() time.Time { return s.lastAct.Load().(time.Time) }
 func (s *Session) GetRemoteIP() string   { return s.conn.RemoteAddr().String() }
+func (s *Session) RemoteAddr() net.Addr  { return s.conn.RemoteAddr() }
+
+// Get returns the attribute of key.
+func (s *Session) Get(key string) (any, bool) { return s.attrs.Load(key) }
+
+// Set sets the attribute of key.
+func (s *Session) Set(key string, value any) { s.attrs.Store(key, value) }
+
+// Delete deletes the attribute of key.
+func (s *Session) Delete(key string) { s.attrs.Delete(key) }
 
 func (s *Session) Send(data []byte) error {
 	if s.Closed() {
@@ -118,7 +133,7 @@ func (s *Session) Push(cmd int32, msg gproto.Message) error {
 
 func (s *Session) readLoop() {
 	defer xgo.RecoverFromError(nil)
-	defer s.Close(false)
+	defer s.close(false)
 
 	for !s.Closed() {
 		s.conn.SetReadDeadline(time.Now().Add(s.config.ReadDeadline))
@@ -148,7 +163,7 @@ func (s *Session) writeLoop() {
 	defer func() {
 		// 只有在非正常关闭时才调用 Close
 		if !s.Closed() {
-			s.Close(false, "writeLoop exit")
+			s.close(false, "writeLoop exit")
 		}
 	}()
 
@@ -185,13 +200,13 @@ func (s *Session) heartbeat() {
 			if s.Closed() || time.Since(s.LastActive()) > s.config.ReadDeadline {
 				if !s.Closed() {
 					log.Warnf("sessionID=%q heartbeat timeout", s.id)
-					s.Close(true, "Heartbeat Timeout")
+					s.close(true, "Heartbeat Timeout")
 				}
 				return
 			}
 			if err := s.writeMessage(websocket.BinaryMessage, pingData); err != nil && !isNetworkClosedError(err) {
 				log.Errorf("sessionID=%q heartbeat error: %v", s.id, err)
-				s.Close(false)
+				s.close(false)
 				return
 			}
 		}
@@ -224,7 +239,16 @@ func (s *Session) writeWithDeadline(fn func() error) error {
 	return fn()
 }
 
-func (s *Session) Close(force bool, msg ...string) bool {
+// Close closes the session with reason, the close callbacks run before it returns.
+//
+// Breaking change: it replaces Close(force bool, msg ...string) bool so that
+// Session implements session.Session. Replace Close(force, msg) with Close(msg).
+func (s *Session) Close(reason string) error {
+	s.close(true, reason)
+	return nil
+}
+
+func (s *Session) close(force bool, msg ...string) bool {
 	closed := false
 	s.closeOnce.Do(func() {
 		closed = true
@@ -96,7 +96,7 @@ func (m *SessionManager) BroadcastAsync(data []byte) {
 func (m *SessionManager) CloseAllSessions() {
 	m.sessions.Range(func(_, v interface{}) bool {
 		if session, ok := v.(*Session); ok {
-			session.Close(true, "server closed")
+			session.close(true, "server closed")
 		}
 		return true
 	})
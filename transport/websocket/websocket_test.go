@@ -6,6 +6,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	gproto "google.golang.org/protobuf/proto"
+
+	kerrors "github.com/yola1107/kratos/v2/errors"
+	"github.com/yola1107/kratos/v2/transport/session"
+	"github.com/yola1107/kratos/v2/transport/websocket/proto"
 )
 
 func TestClientCreation(t *testing.T) {
@@ -87,3 +92,49 @@ func TestCalculateBackoff(t *testing.T) {
 		assert.True(t, delay <= tt.maxTime, "delay should be <= maxTime")
 	}
 }
+
+func TestPayloadError(t *testing.T) {
+	code, e := proto.FromError(kerrors.NotFound("TABLE_NOT_FOUND", "table not found").WithMetadata(map[string]string{"table": "7"}))
+	data, err := gproto.Marshal(&proto.Payload{Op: proto.OpResponse, Code: code, Error: e})
+	assert.NoError(t, err)
+
+	p := &proto.Payload{}
+	assert.NoError(t, gproto.Unmarshal(data, p))
+	got := kerrors.FromError(proto.ToError(p.Code, p.Error))
+	assert.Equal(t, int32(404), got.Code)
+	assert.Equal(t, "TABLE_NOT_FOUND", got.Reason)
+	assert.Equal(t, "table not found", got.Message)
+	assert.Equal(t, "7", got.Metadata["table"])
+
+	assert.Equal(t, 500, kerrors.Code(proto.ToError(500, nil)))
+	assert.NoError(t, proto.ToError(0, nil))
+}
+
+func TestSessionHookPanic(t *testing.T) {
+	closed := false
+	srv := NewServer(
+		SessionHook(func(session.Session) { panic("open") }, func(session.Session) { panic("close") }),
+		SessionHook(nil, func(session.Session) { closed = true }),
+	)
+	sess := &Session{id: "test-session"}
+
+	assert.NotPanics(t, func() { srv.OnSessionOpen(sess) })
+	assert.Equal(t, int32(1), srv.sessionMgr.Len())
+	assert.NotPanics(t, func() { srv.OnSessionClose(sess) })
+	assert.True(t, closed, "expect the hooks after a panicking hook to run")
+	assert.Equal(t, int32(0), srv.sessionMgr.Len())
+}
+
+type testService interface{}
+
+type testServer struct{}
+
+func TestServerRegisterDuplicate(t *testing.T) {
+	desc := &ServiceDesc{ServiceName: "test.Service", HandlerType: (*testService)(nil)}
+	// NewServer handles its path on the default mux, once per test binary
+	srv := &Server{services: newServiceRegistry()}
+	srv.RegisterService(desc, &testServer{}, nil, nil)
+	assert.PanicsWithError(t, `websocket: Server.RegisterService: found duplicate service registration for "test.Service"`, func() {
+		srv.RegisterService(desc, &testServer{}, nil, nil)
+	})
+}
@@ -22,6 +22,8 @@ import (
 // GreeterGNETServer is the server API for Greeter service.
 type GreeterGNETServer interface {
 	GetLoop() work.Loop
+	OnSessionOpen(*gnet.Session)
+	OnSessionClose(*gnet.Session)
 	// SayHelloReq Sends a greeting
 	SayHelloReq(context.Context, *HelloRequest) (*HelloReply, error)
 	OnLoginReq(context.Context, *LoginReq) (*LoginRsp, error)
@@ -38,7 +40,7 @@ type GreeterGNETServer interface {
 }
 
 func RegisterGreeterGNETServer(s *gnet.Server, srv GreeterGNETServer) {
-	s.RegisterService(&Greeter_GNET_ServiceDesc, srv)
+	s.RegisterService(&Greeter_GNET_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Greeter_SayHelloReq_GNET_Handler(srv interface{}, ctx context.Context, data []byte, interceptor gnet.UnaryServerInterceptor) ([]byte, error) {
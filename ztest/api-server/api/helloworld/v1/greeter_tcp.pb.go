@@ -22,7 +22,8 @@ import (
 // GreeterTCPServer is the server API for Greeter service.
 type GreeterTCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 	SayHelloReq(context.Context, *HelloRequest) (*HelloReply, error)
 	OnLoginReq(context.Context, *LoginReq) (*LoginRsp, error)
 	OnLogoutReq(context.Context, *LogoutReq) (*LogoutRsp, error)
@@ -37,8 +38,7 @@ type GreeterTCPServer interface {
 }
 
 func RegisterGreeterTCPServer(s *tcp.Server, srv GreeterTCPServer) {
-	chanList := s.RegisterService(&Greeter_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&Greeter_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Greeter_SayHelloReq_TCP_Handler(srv interface{}, ctx context.Context, data []byte, interceptor tcp.UnaryServerInterceptor) ([]byte, error) {
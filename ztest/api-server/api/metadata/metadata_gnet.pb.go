@@ -22,6 +22,8 @@ import (
 // MetadataGNETServer is the server API for Metadata service.
 type MetadataGNETServer interface {
 	GetLoop() work.Loop
+	OnSessionOpen(*gnet.Session)
+	OnSessionClose(*gnet.Session)
 	// ListServices ListServices list the full name of all services.
 	ListServices(context.Context, *ListServicesRequest) (*ListServicesReply, error)
 	// GetServiceDesc GetServiceDesc get the full fileDescriptorSet of service.
@@ -29,7 +31,7 @@ type MetadataGNETServer interface {
 }
 
 func RegisterMetadataGNETServer(s *gnet.Server, srv MetadataGNETServer) {
-	s.RegisterService(&Metadata_GNET_ServiceDesc, srv)
+	s.RegisterService(&Metadata_GNET_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Metadata_ListServices_GNET_Handler(srv interface{}, ctx context.Context, data []byte, interceptor gnet.UnaryServerInterceptor) ([]byte, error) {
@@ -2,7 +2,7 @@ package player
 
 import (
 	"github.com/yola1107/kratos/v2/log"
-	"github.com/yola1107/kratos/v2/transport/websocket"
+	"github.com/yola1107/kratos/v2/transport/session"
 	v1 "github.com/yola1107/kratos/v2/ztest/api-server/api/helloworld/v1"
 	"github.com/yola1107/kratos/v2/ztest/api-server/internal/conf"
 	"google.golang.org/protobuf/proto"
@@ -10,7 +10,7 @@ import (
 
 type Player struct {
 	isRobot  bool
-	session  *websocket.Session
+	session  session.Session
 	gameData *GameData
 	baseData *BaseData // 私有，不暴露
 }
@@ -18,7 +18,7 @@ type Player struct {
 type Raw struct {
 	ID       int64
 	IsRobot  bool
-	Session  *websocket.Session
+	Session  session.Session
 	BaseData *BaseData
 }
 
@@ -51,19 +51,19 @@ func (p *Player) GetSessionID() string {
 	return p.session.ID()
 }
 
-func (p *Player) GetSession() *websocket.Session {
+func (p *Player) GetSession() session.Session {
 	return p.session
 }
 
-func (p *Player) UpdateSession(session *websocket.Session) {
-	p.session = session
+func (p *Player) UpdateSession(sess session.Session) {
+	p.session = sess
 }
 
 func (p *Player) GetIP() string {
 	if p.session == nil {
 		return ""
 	}
-	return p.session.GetRemoteIP()
+	return p.session.RemoteAddr().String()
 }
 
 func (p *Player) LogoutGame(code int32, msg string) {
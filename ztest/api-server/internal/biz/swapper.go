@@ -3,7 +3,7 @@ package biz
 import (
 	"context"
 
-	"github.com/yola1107/kratos/v2/transport/websocket"
+	"github.com/yola1107/kratos/v2/transport/session"
 	"github.com/yola1107/kratos/v2/ztest/api-server/internal/biz/player"
 	"github.com/yola1107/kratos/v2/ztest/api-server/internal/biz/table"
 	"github.com/yola1107/kratos/v2/ztest/api-server/pkg/codes"
@@ -41,10 +41,10 @@ func (uc *Usecase) Swapper(ctx context.Context) (r *SwapperInfo) {
 	}
 }
 
-func (uc *Usecase) GetSession(ctx context.Context) *websocket.Session {
-	session, ok := ctx.Value(websocket.CtxSessionKey).(*websocket.Session)
-	if !ok || session == nil {
+func (uc *Usecase) GetSession(ctx context.Context) session.Session {
+	sess, ok := session.FromContext(ctx)
+	if !ok {
 		return nil
 	}
-	return session
+	return sess
 }
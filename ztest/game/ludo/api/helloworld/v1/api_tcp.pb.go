@@ -22,7 +22,8 @@ import (
 // LudoTCPServer is the server API for Ludo service.
 type LudoTCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 	SayHelloReq(context.Context, *HelloRequest) (*HelloReply, error)
 	OnLoginReq(context.Context, *LoginReq) (*LoginRsp, error)
 	OnLogoutReq(context.Context, *LogoutReq) (*LogoutRsp, error)
@@ -37,8 +38,7 @@ type LudoTCPServer interface {
 }
 
 func RegisterLudoTCPServer(s *tcp.Server, srv LudoTCPServer) {
-	chanList := s.RegisterService(&Ludo_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&Ludo_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Ludo_SayHelloReq_TCP_Handler(srv interface{}, ctx context.Context, data []byte, interceptor tcp.UnaryServerInterceptor) ([]byte, error) {
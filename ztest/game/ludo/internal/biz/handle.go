@@ -8,7 +8,7 @@ import (
 	"github.com/yola1107/kratos/v2/library/work"
 	"github.com/yola1107/kratos/v2/library/xgo"
 	"github.com/yola1107/kratos/v2/log"
-	"github.com/yola1107/kratos/v2/transport/websocket"
+	"github.com/yola1107/kratos/v2/transport/session"
 	v1 "github.com/yola1107/kratos/v2/ztest/game/ludo/api/helloworld/v1"
 	"github.com/yola1107/kratos/v2/ztest/game/ludo/internal/biz/player"
 	"github.com/yola1107/kratos/v2/ztest/game/ludo/internal/biz/table"
@@ -188,12 +188,12 @@ func (uc *Usecase) createPlayer(raw *player.Raw) (*player.Player, error) {
 	return p, nil
 }
 
-func (uc *Usecase) Disconnect(session *websocket.Session) {
-	if session == nil {
+func (uc *Usecase) Disconnect(sess session.Session) {
+	if sess == nil {
 		return
 	}
 
-	p := uc.pm.GetBySessionID(session.ID())
+	p := uc.pm.GetBySessionID(sess.ID())
 	if p == nil {
 		return
 	}
@@ -22,7 +22,8 @@ import (
 // WhotTCPServer is the server API for Whot service.
 type WhotTCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 	OnChatReq(context.Context, *ChatReq) (*ChatRsp, error)
 	OnForwardReq(context.Context, *ForwardReq) (*ForwardRsp, error)
 	OnHostingReq(context.Context, *HostingReq) (*HostingRsp, error)
@@ -38,8 +39,7 @@ type WhotTCPServer interface {
 }
 
 func RegisterWhotTCPServer(s *tcp.Server, srv WhotTCPServer) {
-	chanList := s.RegisterService(&Whot_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&Whot_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Whot_SayHelloReq_TCP_Handler(srv interface{}, ctx context.Context, data []byte, interceptor tcp.UnaryServerInterceptor) ([]byte, error) {
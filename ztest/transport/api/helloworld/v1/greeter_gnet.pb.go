@@ -22,6 +22,8 @@ import (
 // GreeterGNETServer is the server API for Greeter service.
 type GreeterGNETServer interface {
 	GetLoop() work.Loop
+	OnSessionOpen(*gnet.Session)
+	OnSessionClose(*gnet.Session)
 	// SayHelloReq Sends a greeting
 	SayHelloReq(context.Context, *HelloRequest) (*HelloReply, error)
 	// SayHello2ReqSends a greeting by post
@@ -29,7 +31,7 @@ type GreeterGNETServer interface {
 }
 
 func RegisterGreeterGNETServer(s *gnet.Server, srv GreeterGNETServer) {
-	s.RegisterService(&Greeter_GNET_ServiceDesc, srv)
+	s.RegisterService(&Greeter_GNET_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Greeter_SayHelloReq_GNET_Handler(srv interface{}, ctx context.Context, data []byte, interceptor gnet.UnaryServerInterceptor) ([]byte, error) {
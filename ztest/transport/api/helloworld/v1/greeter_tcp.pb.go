@@ -22,14 +22,14 @@ import (
 // GreeterTCPServer is the server API for Greeter service.
 type GreeterTCPServer interface {
 	GetLoop() work.Loop
-	SetCometChan(cl *tcp.ChanList, cs *tcp.Server)
+	OnSessionOpen(key string)
+	OnSessionClose(key string)
 	SayHelloReq(context.Context, *HelloRequest) (*HelloReply, error)
 	SayHello2Req(context.Context, *Hello2Request) (*Hello2Reply, error)
 }
 
 func RegisterGreeterTCPServer(s *tcp.Server, srv GreeterTCPServer) {
-	chanList := s.RegisterService(&Greeter_TCP_ServiceDesc, srv)
-	srv.SetCometChan(chanList, s)
+	s.RegisterService(&Greeter_TCP_ServiceDesc, srv, srv.OnSessionOpen, srv.OnSessionClose)
 }
 
 func _Greeter_SayHelloReq_TCP_Handler(srv interface{}, ctx context.Context, data []byte, interceptor tcp.UnaryServerInterceptor) ([]byte, error) {
@@ -24,6 +24,7 @@ func main() {
 		gnet.WithEndpoint("127.0.0.1:3200"),
 		gnet.WithTimeout(5*time.Second),
 	)
+	defer client.Close()
 
 	// 测试 SayHelloReq (Ops: 1001)
 	log.Info("=== Testing SayHelloReq ===")
@@ -33,7 +34,7 @@ func main() {
 		}
 
 		var reply v1.HelloReply
-		err := client.Invoke(context.Background(), 1001, req, &reply)
+		err := client.Invoke(context.Background(), 1001, req, &reply, gnet.Operation(v1.Greeter_SayHelloReq_FullMethodName))
 		if err != nil {
 			log.Errorf("SayHelloReq failed: %v", err)
 			continue
@@ -52,7 +53,7 @@ func main() {
 		}
 
 		var reply v1.Hello2Reply
-		err := client.Invoke(context.Background(), 1003, req, &reply)
+		err := client.Invoke(context.Background(), 1003, req, &reply, gnet.Operation(v1.Greeter_SayHello2Req_FullMethodName))
 		if err != nil {
 			log.Errorf("SayHello2Req failed: %v", err)
 			continue
@@ -28,6 +28,14 @@ func (s *server) GetLoop() work.Loop {
 	return s.gnetLoop
 }
 
+func (s *server) OnSessionOpen(sess *gnet.Session) {
+	log.Infof("[gnet] session open: id=%s remote=%s", sess.ID(), sess.GetRemoteIP())
+}
+
+func (s *server) OnSessionClose(sess *gnet.Session) {
+	log.Infof("[gnet] session close: id=%s", sess.ID())
+}
+
 func (s *server) SayHelloReq(ctx context.Context, req *v1.HelloRequest) (*v1.HelloReply, error) {
 	log.Infof("[gnet] SayHelloReq received: %s", req.Name)
 	return &v1.HelloReply{
@@ -54,6 +62,7 @@ func main() {
 	gnetSrv := gnet.NewServer(
 		gnet.Address(":3200"),
 		gnet.Timeout(5*time.Second),
+		gnet.IdleTimeout(60*time.Second),
 		gnet.Middleware(
 			recovery.Recovery(),
 		),
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -27,19 +28,18 @@ func main() {
 
 	addr := "0.0.0.0:3101"
 
-	c, err := tcp.NewTcpClient(&tcp.ClientConfig{
-		Addr: addr,
-		PushHandlers: map[int32]tcp.PushMsgHandle{
-			int32(v1.GameCommand_SayHelloRsp):  func(data []byte) { log.Infof("PushMsgHandle(1002). data=%+v", data) },
-			int32(v1.GameCommand_SayHello2Rsp): func(data []byte) { log.Infof("PushMsgHandle(1004). data=%+v", unmarshalProtoMsg(data)) },
-		},
-		RespHandlers: map[int32]tcp.RespMsgHandle{
-			int32(v1.GameCommand_SayHelloReq):  func(data []byte, code int32) { log.Infof("RespHandlers(1001). code=%d data=%+v ", code, data) },
-			int32(v1.GameCommand_SayHello2Req): func(data []byte, code int32) { log.Infof("RespHandlers(1003). code=%d data=%+v ", code, data) },
-		},
-		DisconnectFunc: func() { log.Infof("disconect.") },
-		Token:          "",
-	})
+	c, err := tcp.NewClient(context.Background(),
+		tcp.WithEndpoint(addr),
+		tcp.WithPushHandler(map[int32]tcp.PushHandler{
+			int32(v1.GameCommand_SayHelloRsp):  func(data []byte) { log.Infof("PushHandler(1002). data=%+v", data) },
+			int32(v1.GameCommand_SayHello2Rsp): func(data []byte) { log.Infof("PushHandler(1004). data=%+v", unmarshalProtoMsg(data)) },
+		}),
+		tcp.WithResponseHandler(map[int32]tcp.ResponseHandler{
+			int32(v1.GameCommand_SayHelloReq):  func(data []byte, code int32) { log.Infof("ResponseHandler(1001). code=%d data=%+v ", code, data) },
+			int32(v1.GameCommand_SayHello2Req): func(data []byte, code int32) { log.Infof("ResponseHandler(1003). code=%d data=%+v ", code, data) },
+		}),
+		tcp.WithDisconnectFunc(func(*tcp.Session) { log.Infof("disconect.") }),
+	)
 	if err != nil {
 		panic(err)
 	}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	gproto "google.golang.org/protobuf/proto"
-
 	"github.com/yola1107/kratos/v2/library/log/zap"
 	"github.com/yola1107/kratos/v2/library/log/zap/conf"
 	"github.com/yola1107/kratos/v2/library/work"
@@ -33,13 +31,18 @@ type server struct {
 	v2.UnimplementedGreeterServer
 
 	tcpLoop work.Loop
-	session *tcp.ChanList
+	tcpSrv  *tcp.Server
 }
 
 var seed int64
 
-func (s *server) SetCometChan(cl *tcp.ChanList, cs *tcp.Server) { s.session = cl }
-func (s *server) GetLoop() work.Loop                            { return s.tcpLoop }
+func (s *server) GetLoop() work.Loop { return s.tcpLoop }
+func (s *server) OnSessionOpen(key string) {
+	log.Infof("session open. key=%s", key)
+}
+func (s *server) OnSessionClose(key string) {
+	log.Infof("session close. key=%s", key)
+}
 
 func (s *server) SayHelloReq(ctx context.Context, in *v2.HelloRequest) (*v2.HelloReply, error) {
 	// panic("tcp panic test")
@@ -67,11 +70,9 @@ func (s *server) testPush(ctx context.Context) {
 		mid = md.Get("mid")
 	}
 	for i := 0; i < 1; i++ {
-		bytes, _ := gproto.Marshal(&v2.Hello2Reply{Message: fmt.Sprintf("Reply_%d", seed)})
-		s.session.PushChan <- &tcp.PushData{
-			Mid:  mid,
-			Ops:  int32(v2.GameCommand_SayHello2Rsp),
-			Data: bytes,
+		msg := &v2.Hello2Reply{Message: fmt.Sprintf("Reply_%d", seed)}
+		if err := s.tcpSrv.Push(ctx, mid, int32(v2.GameCommand_SayHello2Rsp), msg); err != nil {
+			log.Errorf("push failed. mid=%s err=%v", mid, err)
 		}
 	}
 }
@@ -119,6 +120,7 @@ func main() {
 
 	v2.RegisterGreeterServer(grpcSrv, s)
 	v2.RegisterGreeterHTTPServer(httpSrv, s)
+	s.tcpSrv = tcpSrv
 	v2.RegisterGreeterTCPServer(tcpSrv, s)
 	app := kratos.New(
 		kratos.Name(Name),
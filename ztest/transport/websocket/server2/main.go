@@ -49,7 +49,7 @@ func (s *server) OnSessionClose(session *websocket.Session) {
 	// log.Infof("[ws] DisConnectFunc callback. key=%q", session.ID())
 	if session != nil {
 		if !session.Closed() {
-			session.Close(true)
+			_ = session.Close("closed by server")
 			log.Debugf("close session %s.", session.ID())
 		}
 		s.sessionsMap.Delete(session.ID())